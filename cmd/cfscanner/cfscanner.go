@@ -10,6 +10,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+	pkghttp "github.com/lilendian0x00/xray-knife/v10/pkg/http"
 	pkgscanner "github.com/lilendian0x00/xray-knife/v10/pkg/scanner"
 	"github.com/lilendian0x00/xray-knife/v10/utils"
 	"github.com/lilendian0x00/xray-knife/v10/utils/customlog"
@@ -18,6 +21,11 @@ import (
 
 var (
 	cliConfig pkgscanner.ScannerConfig
+
+	fragmentSpec   string
+	noises         []string
+	corePolicySpec string
+	speedtestSpec  string
 )
 
 var CFscannerCmd = &cobra.Command{
@@ -56,6 +64,25 @@ pass a file containing one subnet per line.`,
 			customlog.Printf(customlog.Info, "Scanning on custom port %d (not 443).\n", cliConfig.Port)
 		}
 
+		fragment, err := protocol.NewFragmentOptions(fragmentSpec, noises)
+		if err != nil {
+			customlog.Printf(customlog.Failure, "%v\n", err)
+			return
+		}
+		if fragment != nil && cliConfig.ConfigLink == "" {
+			customlog.Printf(customlog.Warning, "--fragment and --noise only apply together with --config.\n")
+		}
+		cliConfig.Fragment = fragment
+
+		if cliConfig.CorePolicy, err = core.LoadCorePolicy(corePolicySpec); err != nil {
+			customlog.Printf(customlog.Failure, "%v\n", err)
+			return
+		}
+		if cliConfig.SpeedtestBackend, err = pkghttp.ParseSpeedtestBackend(speedtestSpec); err != nil {
+			customlog.Printf(customlog.Failure, "%v\n", err)
+			return
+		}
+
 		if !cliConfig.Resume {
 			if err := os.Remove(cliConfig.OutputFile); err != nil && !os.IsNotExist(err) {
 				customlog.Printf(customlog.Failure, "Failed to clear previous results file %s: %v\n", cliConfig.OutputFile, err)
@@ -131,12 +158,19 @@ func init() {
 	CFscannerCmd.Flags().BoolVarP(&cliConfig.OnlySpeedtestResults, "only-speedtest", "k", false, "Only display results that have successful speedtest data")
 	CFscannerCmd.Flags().IntVarP(&cliConfig.DownloadMB, "download-mb", "d", 20, "Custom amount of data to download for speedtest (in MB)")
 	CFscannerCmd.Flags().IntVarP(&cliConfig.UploadMB, "upload-mb", "m", 10, "Custom amount of data to upload for speedtest (in MB)")
+	CFscannerCmd.Flags().IntVar(&cliConfig.SpeedtestDuration, "speedtest-duration", 0, "Transfer for this many seconds in each direction instead of --download-mb/--upload-mb (0 = fixed amount)")
+	CFscannerCmd.Flags().IntVar(&cliConfig.SpeedtestStreams, "speedtest-streams", 1, "Parallel connections per speed test; their throughput is added up")
+	CFscannerCmd.Flags().IntVar(&cliConfig.SpeedtestRampUp, "speedtest-rampup", 0, "Start of the transfer (ms) left out of the speed, while TCP ramps up (0 = a fifth of --speedtest-duration)")
+	CFscannerCmd.Flags().StringVar(&speedtestSpec, "speedtest-backend", "cloudflare", "Speed test backend: cloudflare[:server], librespeed:<url> or url:<download url>[,<upload url>]. Without --config it must be reachable on the scanned IPs")
 	CFscannerCmd.Flags().StringVarP(&cliConfig.ConfigLink, "config", "C", "", "Use a config link as a proxy to test IPs")
 	CFscannerCmd.Flags().BoolVarP(&cliConfig.InsecureTLS, "insecure", "E", false, "Allow insecure TLS connections for the proxy config")
 	CFscannerCmd.Flags().BoolVar(&cliConfig.Resume, "resume", false, "Resume scan from previous results (file or DB)")
 	CFscannerCmd.Flags().BoolVar(&cliConfig.SaveToDB, "save-db", false, "Save scan results to the database")
 	CFscannerCmd.Flags().IntVarP(&cliConfig.Port, "port", "P", 443, "TCP port to scan (Cloudflare also accepts 2053, 2083, 2087, 2096, 8443)")
 	CFscannerCmd.Flags().StringVar(&cliConfig.BindInterface, "bind", "", "Bind outbound dials to a specific OS interface (e.g. eth0). Linux: needs CAP_NET_RAW.")
+	CFscannerCmd.Flags().StringVar(&fragmentSpec, "fragment", "", "Fragment the TLS ClientHello of the --config proxy: packets,length,interval (e.g. tlshello,100-200,10-20) or \"default\"")
+	CFscannerCmd.Flags().StringVar(&corePolicySpec, "core-policy", "", "Pick xray or sing-box for the --config proxy per protocol/transport: rules like vless/ws=singbox[,fallback=off] or a file holding them")
+	CFscannerCmd.Flags().StringArrayVar(&noises, "noise", nil, "Send a noise packet before UDP traffic of the --config proxy (xray only): type:packet[:delay] (repeatable)")
 
 	_ = CFscannerCmd.MarkFlagRequired("subnets")
 }
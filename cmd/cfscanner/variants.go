@@ -0,0 +1,305 @@
+package cfscanner
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lilendian0x00/xray-knife/v10/database"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/convert"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+	pkghttp "github.com/lilendian0x00/xray-knife/v10/pkg/http"
+	pkgscanner "github.com/lilendian0x00/xray-knife/v10/pkg/scanner"
+	"github.com/lilendian0x00/xray-knife/v10/utils"
+	"github.com/lilendian0x00/xray-knife/v10/utils/customlog"
+)
+
+// variantsConfig holds the flags of the variants command.
+type variantsConfig struct {
+	configLink      string
+	configLinksFile string
+	ips             []string
+	top             int
+	noResolve       bool
+	test            bool
+	saveToDB        bool
+	threadCount     uint16
+	maxDelay        uint16
+	coreType        string
+	insecureTLS     bool
+	outputFile      string
+	verbose         bool
+}
+
+var variantsCfg variantsConfig
+
+// variantsCmd turns CDN-fronted configs into one variant per clean edge IP.
+var variantsCmd = &cobra.Command{
+	Use:   "variants",
+	Short: "Makes variants of CDN-fronted configs for the best scanned Cloudflare IPs",
+	Long: `Takes configs reaching their server through Cloudflare (ws, grpc, xhttp or
+httpupgrade with a host or SNI behind it) and makes one variant per clean edge IP,
+with the address replaced and the host/SNI kept. The IPs are the top results of
+previous 'cfscanner --save-db' runs, or the ones given with --ips.
+
+With --test the variants are tested right away; --save-db adds the ones that pass
+to the config library, tagged with their edge IP.
+
+Examples:
+  xray-knife cfscanner variants -c "vless://...@example.com:443?type=ws&security=tls..." --top 10
+  xray-knife cfscanner variants -f configs.txt --test --save-db -o clean.txt`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg := &variantsCfg
+		if cfg.saveToDB && !cfg.test {
+			return fmt.Errorf("--save-db only saves the variants that pass, it requires --test")
+		}
+
+		ips, err := variantIPs(cfg)
+		if err != nil {
+			return err
+		}
+
+		links, err := variantSourceLinks(cfg)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		var resolver *net.Resolver
+		if !cfg.noResolve {
+			resolver = net.DefaultResolver
+		}
+		c := core.NewAutomaticCore(false, cfg.insecureTLS)
+		var variants []pkgscanner.Variant
+		cdnConfigs := 0
+		for _, link := range links {
+			p, err := c.CreateProtocol(link)
+			if err == nil {
+				err = p.Parse()
+			}
+			if err != nil {
+				if cfg.verbose {
+					customlog.Printf(customlog.Warning, "Skipping %s: %v\n", link, err)
+				}
+				continue
+			}
+
+			lookupCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			behind := pkgscanner.IsBehindCloudflare(lookupCtx, resolver, p.ConvertToGeneralConfig())
+			cancel()
+			if !behind {
+				continue
+			}
+			cdnConfigs++
+
+			vs, err := pkgscanner.MakeVariants(c, p, ips)
+			if err != nil {
+				customlog.Printf(customlog.Warning, "Skipping %s: %v\n", link, err)
+				continue
+			}
+			variants = append(variants, vs...)
+		}
+		if len(variants) == 0 {
+			return fmt.Errorf("none of the %d configs is fronted by Cloudflare", len(links))
+		}
+		customlog.Printf(customlog.Info, "Made %d variants of %d CDN configs for %d edge IPs.\n", len(variants), cdnConfigs, len(ips))
+
+		if cfg.test {
+			if variants, err = testVariants(ctx, cfg, variants); err != nil {
+				return err
+			}
+			if cfg.saveToDB && len(variants) > 0 {
+				if err := saveVariants(c, variants); err != nil {
+					return err
+				}
+				customlog.Printf(customlog.Success, "Saved %d working variants to the database.\n", len(variants))
+			}
+		}
+
+		out := make([]string, 0, len(variants))
+		for _, v := range variants {
+			out = append(out, v.Link)
+		}
+		if cfg.outputFile != "" {
+			if err := utils.WriteIntoFile(cfg.outputFile, []byte(strings.Join(out, "\n")+"\n")); err != nil {
+				return fmt.Errorf("failed to save variants: %w", err)
+			}
+			customlog.Printf(customlog.Finished, "%d variants have been saved to %s\n", len(out), cfg.outputFile)
+			return nil
+		}
+		for _, link := range out {
+			fmt.Println(link)
+		}
+		return nil
+	},
+}
+
+// variantIPs returns the --ips, or the top clean IPs of previous scans.
+func variantIPs(cfg *variantsConfig) ([]string, error) {
+	if len(cfg.ips) > 0 {
+		ips := make([]string, 0, len(cfg.ips))
+		for _, ip := range cfg.ips {
+			ip = strings.TrimSpace(ip)
+			if net.ParseIP(ip) == nil {
+				return nil, fmt.Errorf("invalid IP %q in --ips", ip)
+			}
+			ips = append(ips, ip)
+		}
+		return ips, nil
+	}
+
+	if cfg.top <= 0 {
+		return nil, fmt.Errorf("--top must be at least 1")
+	}
+	// Failed IPs and ones without a latency sort last, so fetching the top
+	// rows is enough
+	results, err := database.GetCfScanHistory(cfg.top)
+	if err != nil {
+		return nil, err
+	}
+	var ips []string
+	for _, r := range results {
+		if !r.Error.Valid && r.LatencyMs.Valid {
+			ips = append(ips, r.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no clean IPs in the database: run 'xray-knife cfscanner --save-db' first or pass --ips")
+	}
+	return ips, nil
+}
+
+// variantSourceLinks returns the configs to make variants of: --config,
+// --file, or every config in the database.
+func variantSourceLinks(cfg *variantsConfig) ([]string, error) {
+	var links []string
+	switch {
+	case cfg.configLink != "":
+		links = []string{cfg.configLink}
+	case cfg.configLinksFile != "":
+		parsed, err := convert.ReadLinksFile(cfg.configLinksFile)
+		if err != nil {
+			return nil, err
+		}
+		links = parsed
+	default:
+		fromDB, err := database.GetConfigsFromDB(0, "", 0)
+		if err != nil {
+			return nil, err
+		}
+		links = fromDB
+	}
+
+	links, _ = pkghttp.DeduplicateLinks(links)
+	if len(links) == 0 {
+		return nil, fmt.Errorf("no config links provided or found")
+	}
+	return links, nil
+}
+
+// testVariants tests variants and returns the ones that pass, fastest first.
+func testVariants(ctx context.Context, cfg *variantsConfig, variants []pkgscanner.Variant) ([]pkgscanner.Variant, error) {
+	examiner, err := pkghttp.NewExaminer(pkghttp.Options{
+		Core:        cfg.coreType,
+		MaxDelay:    cfg.maxDelay,
+		Verbose:     cfg.verbose,
+		InsecureTLS: cfg.insecureTLS,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create examiner: %w", err)
+	}
+
+	byLink := make(map[string]pkgscanner.Variant, len(variants))
+	links := make([]string, 0, len(variants))
+	for _, v := range variants {
+		byLink[v.Link] = v
+		links = append(links, v.Link)
+	}
+
+	resultsChan := make(chan *pkghttp.Result, cfg.threadCount)
+	var passed pkghttp.ConfigResults
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for res := range resultsChan {
+			if res.Status == "passed" {
+				customlog.Printf(customlog.Success, "%-15s | %4dms | %s\n", byLink[res.ConfigLink].SourceIP, res.Delay, res.ConfigLink)
+				passed = append(passed, res)
+			}
+		}
+	}()
+
+	customlog.Printf(customlog.Processing, "Testing %d variants...\n", len(links))
+	pkghttp.NewTestManager(examiner, cfg.threadCount, cfg.verbose, nil).RunTests(ctx, links, resultsChan, nil)
+	close(resultsChan)
+	wg.Wait()
+
+	sort.Sort(passed)
+	winners := make([]pkgscanner.Variant, 0, len(passed))
+	for _, res := range passed {
+		winners = append(winners, byLink[res.ConfigLink])
+	}
+	customlog.Printf(customlog.Info, "%d of %d variants passed.\n", len(winners), len(variants))
+	return winners, nil
+}
+
+// saveVariants adds variants to the config library, tagged with their IP.
+func saveVariants(c core.Core, variants []pkgscanner.Variant) error {
+	now := time.Now().UTC()
+	configs := make([]database.SubscriptionConfig, 0, len(variants))
+	for _, v := range variants {
+		dbConf := database.SubscriptionConfig{
+			ConfigLink: v.Link,
+			SourceIP:   sql.NullString{String: v.SourceIP, Valid: true},
+			LastSeenAt: database.NullTime{Time: now, Valid: true},
+		}
+		if fp, err := core.LinkFingerprint(v.Link); err == nil {
+			dbConf.Fingerprint = sql.NullString{String: fp, Valid: true}
+		}
+		lint := core.LintLink(c, v.Link)
+		dbConf.LintStatus = sql.NullString{String: lint.Status, Valid: true}
+		dbConf.LintCodes = sql.NullString{String: protocol.LintCodes(lint.Diagnostics), Valid: len(lint.Diagnostics) > 0}
+		if p, err := c.CreateProtocol(v.Link); err == nil && p.Parse() == nil {
+			g := p.ConvertToGeneralConfig()
+			dbConf.Protocol = sql.NullString{String: g.Protocol, Valid: g.Protocol != ""}
+			dbConf.Remark = sql.NullString{String: g.Remark, Valid: g.Remark != ""}
+		}
+		configs = append(configs, dbConf)
+	}
+	if err := database.UpsertSubscriptionConfigs(configs); err != nil {
+		return fmt.Errorf("failed to save variants to database: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	flags := variantsCmd.Flags()
+	flags.StringVarP(&variantsCfg.configLink, "config", "c", "", "The config link to make variants of")
+	flags.StringVarP(&variantsCfg.configLinksFile, "file", "f", "", "Read config links from a file (default: all configs in the database)")
+	flags.StringSliceVar(&variantsCfg.ips, "ips", nil, "Edge IPs to use instead of the best scanned ones (e.g. \"104.16.1.1,104.17.2.2\")")
+	flags.IntVarP(&variantsCfg.top, "top", "n", 5, "Number of best scanned IPs to make variants for")
+	flags.BoolVar(&variantsCfg.noResolve, "no-resolve", false, "Don't resolve domain addresses to check they are behind Cloudflare; any ws/grpc/xhttp/httpupgrade config counts")
+	flags.BoolVar(&variantsCfg.test, "test", false, "Test the variants and only keep the ones that pass")
+	flags.BoolVar(&variantsCfg.saveToDB, "save-db", false, "Save the variants that pass to the database, tagged with their edge IP (requires --test)")
+	flags.Uint16VarP(&variantsCfg.threadCount, "thread", "t", 50, "Number of variants tested at once")
+	flags.Uint16VarP(&variantsCfg.maxDelay, "mdelay", "d", 5000, "Maximum allowed delay of a passing variant (ms)")
+	flags.StringVarP(&variantsCfg.coreType, "core", "z", "auto", "Core to test with (xray, sing-box, auto)")
+	flags.BoolVarP(&variantsCfg.insecureTLS, "insecure", "E", false, "Allow insecure TLS connections")
+	flags.StringVarP(&variantsCfg.outputFile, "out", "o", "", "Write the variants (only the passing ones with --test) to a file instead of STDOUT")
+	flags.BoolVarP(&variantsCfg.verbose, "verbose", "v", false, "Show skipped configs and test errors")
+	CFscannerCmd.AddCommand(variantsCmd)
+}
@@ -0,0 +1,185 @@
+package edit
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lilendian0x00/xray-knife/v10/database"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/convert"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+	"github.com/lilendian0x00/xray-knife/v10/utils"
+	"github.com/lilendian0x00/xray-knife/v10/utils/customlog"
+)
+
+// editCmdConfig holds the configuration for the edit command
+type editCmdConfig struct {
+	readFromSTDIN   bool
+	configLink      string
+	configLinksFile string
+	outputFile      string
+	edits           protocol.Edits
+}
+
+// EditCmd is the edit subcommand.
+var EditCmd = newEditCommand()
+
+func newEditCommand() *cobra.Command {
+	cfg := &editCmdConfig{}
+
+	cmd := &cobra.Command{
+		Use:   "edit",
+		Short: "Rewrite the address, port, SNI, host or remark of config links.",
+		Long: `Applies field overrides to config links and prints the regenerated share links.
+Parameters that aren't overridden are kept as they were; vmess links are
+re-encoded in the form they came in (base64 JSON or base64 address with
+peer/obfsParam parameters).
+
+The remark may be a template. Available placeholders:
+  {remark} {protocol} {address} {port} {sni} {host} {type} {tls}  of the original config
+  {index}                                                         position of the link, from 1
+  {country} {delay} {ip}                                          latest 'http' test result saved in the database
+
+Examples:
+  xray-knife edit -c "vless://..." --address 104.16.1.1 --port 2053
+  xray-knife edit -f configs.txt --remark "{country}-{delay}ms" -o renamed.txt`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cfg.edits.IsZero() {
+				return fmt.Errorf("nothing to edit: pass at least one of --address, --port, --sni, --host or --remark")
+			}
+
+			links, err := readLinks(cfg)
+			if err != nil {
+				return err
+			}
+			if len(links) == 0 {
+				return fmt.Errorf("no config links provided or found")
+			}
+
+			results, err := lookupResults(cfg.edits.Remark, links)
+			if err != nil {
+				return err
+			}
+
+			c := core.NewAutomaticCore(false, true)
+			edited := make([]string, 0, len(links))
+			for i, link := range links {
+				vars := map[string]string{"index": strconv.Itoa(i + 1)}
+				for k, v := range resultVars(results, link) {
+					vars[k] = v
+				}
+
+				p, err := c.CreateProtocol(link)
+				if err == nil {
+					err = p.Parse()
+				}
+				if err == nil {
+					p, err = core.EditProtocol(c, p, cfg.edits, vars)
+				}
+				if err != nil {
+					customlog.Printf(customlog.Failure, "Skipping link %d: %v\n", i+1, err)
+					continue
+				}
+				edited = append(edited, p.GetLink())
+			}
+
+			if cfg.outputFile != "" {
+				if err := utils.WriteIntoFile(cfg.outputFile, []byte(strings.Join(edited, "\n")+"\n")); err != nil {
+					return fmt.Errorf("failed to save edited configs: %w", err)
+				}
+				customlog.Printf(customlog.Finished, "%d edited configs have been saved to %s\n", len(edited), cfg.outputFile)
+				return nil
+			}
+			for _, link := range edited {
+				fmt.Println(link)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&cfg.readFromSTDIN, "stdin", "i", false, "Read config links from STDIN, one per line")
+	cmd.Flags().StringVarP(&cfg.configLink, "config", "c", "", "The config link")
+	cmd.Flags().StringVarP(&cfg.configLinksFile, "file", "f", "", "Read config links from a file (share links, base64, Clash YAML, sing-box/xray JSON or wg-quick .conf)")
+	cmd.Flags().StringVarP(&cfg.outputFile, "out", "o", "", "Write the edited links to a file instead of STDOUT")
+	cmd.Flags().StringVar(&cfg.edits.Address, "address", "", "New server address (IP or domain)")
+	cmd.Flags().StringVar(&cfg.edits.Port, "port", "", "New server port (hysteria2 also takes a port list like 443,20000-30000)")
+	cmd.Flags().StringVar(&cfg.edits.SNI, "sni", "", "New TLS server name")
+	cmd.Flags().StringVar(&cfg.edits.Host, "host", "", "New transport host header (vless, vmess, trojan)")
+	cmd.Flags().StringVar(&cfg.edits.Remark, "remark", "", "New remark, may contain placeholders like {country}-{delay}ms")
+	cmd.MarkFlagsMutuallyExclusive("stdin", "config", "file")
+	return cmd
+}
+
+func readLinks(cfg *editCmdConfig) ([]string, error) {
+	var links []string
+	switch {
+	case cfg.configLink != "":
+		links = append(links, cfg.configLink)
+	case cfg.configLinksFile != "":
+		parsedLinks, err := convert.ReadLinksFile(cfg.configLinksFile)
+		if err != nil {
+			return nil, err
+		}
+		links = parsedLinks
+	case cfg.readFromSTDIN:
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			links = append(links, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("error reading from stdin: %w", err)
+		}
+	}
+
+	trimmed := links[:0]
+	for _, link := range links {
+		if link = strings.TrimSpace(link); link != "" {
+			trimmed = append(trimmed, link)
+		}
+	}
+	return trimmed, nil
+}
+
+// lookupResults fetches the latest test results of links when the remark
+// template needs them.
+func lookupResults(remark string, links []string) (map[string]database.HttpTestResult, error) {
+	needed := false
+	for _, v := range []string{"{country}", "{delay}", "{ip}"} {
+		needed = needed || strings.Contains(strings.ToLower(remark), v)
+	}
+	if !needed {
+		return nil, nil
+	}
+	results, err := database.GetLatestHttpTestResults(links)
+	if err != nil {
+		return nil, err
+	}
+	if missing := len(links) - len(results); missing > 0 {
+		customlog.Printf(customlog.Warning, "%d of %d configs have no saved test result, their {country}, {delay} and {ip} are \"NA\". Run 'xray-knife http --save-db' first.\n", missing, len(links))
+	}
+	return results, nil
+}
+
+func resultVars(results map[string]database.HttpTestResult, link string) map[string]string {
+	vars := map[string]string{"country": "NA", "delay": "NA", "ip": "NA"}
+	r, ok := results[link]
+	if !ok {
+		return vars
+	}
+	if r.IPLocation.Valid && r.IPLocation.String != "" {
+		vars["country"] = r.IPLocation.String
+	}
+	if r.DelayMs > 0 {
+		vars["delay"] = strconv.FormatInt(r.DelayMs, 10)
+	}
+	if r.IPAddress.Valid && r.IPAddress.String != "" {
+		vars["ip"] = r.IPAddress.String
+	}
+	return vars
+}
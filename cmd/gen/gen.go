@@ -0,0 +1,121 @@
+package gen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+	"github.com/spf13/cobra"
+
+	pkggen "github.com/lilendian0x00/xray-knife/v10/pkg/gen"
+	"github.com/lilendian0x00/xray-knife/v10/utils"
+	"github.com/lilendian0x00/xray-knife/v10/utils/customlog"
+)
+
+// genCmdConfig holds the configuration for the gen command
+type genCmdConfig struct {
+	opts       pkggen.Options
+	outputFile string
+	noQR       bool
+}
+
+// GenCmd is the gen subcommand.
+var GenCmd = newGenCommand()
+
+func newGenCommand() *cobra.Command {
+	cfg := &genCmdConfig{}
+
+	cmd := &cobra.Command{
+		Use:   "gen",
+		Short: "Generate a server config and the matching client share links.",
+		Long: `Generates a complete xray-core or sing-box server config with fresh keys and
+prints the share links (and QR codes) clients connect with.
+
+Protocols:
+  vless-reality  VLESS over TCP with REALITY and XTLS Vision; a new x25519 key pair and short IDs
+  trojan-tls     Trojan over TLS
+  hysteria2      Hysteria2 (QUIC)
+  ss2022         Shadowsocks 2022 with a new server key and per-user keys
+
+trojan-tls and hysteria2 need a certificate for --sni. Without --cert/--key a
+self-signed one is generated next to the server config. trojan-tls links pin
+its SHA-256 (pcs), which only the xray core checks; hysteria2 links skip
+certificate verification.
+
+Examples:
+  xray-knife gen -p vless-reality -s 203.0.113.7 --sni www.microsoft.com -o config.json
+  xray-knife gen -z sing-box -p hysteria2 -s vpn.example.com --cert /etc/ssl/vpn.crt --key /etc/ssl/vpn.key
+  xray-knife gen -p ss2022 -s 203.0.113.7 --port 8388 -u 5 -o config.json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cfg.outputFile != "" {
+				dir, err := filepath.Abs(filepath.Dir(cfg.outputFile))
+				if err != nil {
+					return err
+				}
+				cfg.opts.CertDir = dir
+			}
+
+			res, err := pkggen.Generate(cfg.opts)
+			if err != nil {
+				return err
+			}
+
+			if res.Certificate != nil {
+				if err := os.WriteFile(res.CertFile, res.Certificate, 0644); err != nil {
+					return fmt.Errorf("failed to save certificate: %w", err)
+				}
+				if err := os.WriteFile(res.KeyFile, res.Key, 0600); err != nil {
+					return fmt.Errorf("failed to save certificate key: %w", err)
+				}
+				customlog.Printf(customlog.Info, "Generated a self-signed certificate: %s, %s\n", res.CertFile, res.KeyFile)
+			}
+
+			if cfg.outputFile != "" {
+				if err := utils.WriteIntoFile(cfg.outputFile, append(res.ServerConfig, '\n')); err != nil {
+					return fmt.Errorf("failed to save server config: %w", err)
+				}
+				customlog.Printf(customlog.Finished, "Server config has been saved to %s\n", cfg.outputFile)
+			} else {
+				fmt.Println(string(res.ServerConfig))
+			}
+
+			if res.PublicKey != "" {
+				customlog.Printf(customlog.Info, "REALITY public key: %s\n", res.PublicKey)
+			}
+			customlog.Printf(customlog.Success, "Client links:\n")
+			for _, link := range res.Links {
+				fmt.Println(link)
+				if cfg.noQR {
+					continue
+				}
+				qr, err := qrcode.New(link, qrcode.Medium)
+				if err != nil {
+					customlog.Printf(customlog.Warning, "Failed to make QR code: %v\n", err)
+					continue
+				}
+				fmt.Println(qr.ToSmallString(false))
+			}
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&cfg.opts.Core, "core", "z", "xray", "Core the server runs (xray, sing-box)")
+	flags.StringVarP(&cfg.opts.Protocol, "protocol", "p", pkggen.VlessReality, "Server protocol ("+strings.Join(pkggen.Protocols, ", ")+")")
+	flags.StringVarP(&cfg.opts.Server, "server", "s", "", "Public IP or domain clients dial (required)")
+	flags.IntVar(&cfg.opts.Port, "port", 443, "Port the server listens on")
+	flags.StringVar(&cfg.opts.Listen, "listen", "", "Address the server binds (default: all interfaces)")
+	flags.StringVar(&cfg.opts.SNI, "sni", "", "Site REALITY impersonates (default www.microsoft.com), or the certificate's server name (default --server)")
+	flags.StringVar(&cfg.opts.CertFile, "cert", "", "TLS certificate file on the server (trojan-tls, hysteria2); a self-signed one is generated when unset")
+	flags.StringVar(&cfg.opts.KeyFile, "key", "", "TLS key file on the server (trojan-tls, hysteria2)")
+	flags.StringVar(&cfg.opts.Method, "method", "2022-blake3-aes-128-gcm", "Shadowsocks 2022 method (2022-blake3-aes-128-gcm, 2022-blake3-aes-256-gcm)")
+	flags.IntVarP(&cfg.opts.Users, "users", "u", 1, "Number of users, each gets its own credentials and link")
+	flags.StringVar(&cfg.opts.Remark, "remark", "", "Remark of the client links (default: the protocol)")
+	flags.StringVarP(&cfg.outputFile, "out", "o", "", "Write the server config to a file instead of STDOUT")
+	flags.BoolVar(&cfg.noQR, "no-qr", false, "Don't print QR codes of the client links")
+	_ = cmd.MarkFlagRequired("server")
+	cmd.MarkFlagsRequiredTogether("cert", "key")
+	return cmd
+}
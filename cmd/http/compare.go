@@ -0,0 +1,182 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/schollz/progressbar/v3"
+
+	"github.com/lilendian0x00/xray-knife/v10/database"
+	pkghttp "github.com/lilendian0x00/xray-knife/v10/pkg/http"
+	"github.com/lilendian0x00/xray-knife/v10/utils"
+	"github.com/lilendian0x00/xray-knife/v10/utils/customlog"
+)
+
+// handleCompareCores tests links on both xray and sing-box and prints the
+// results side by side, disagreements first.
+func handleCompareCores(config *Config, links []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	comparer, err := pkghttp.NewCoreComparer(examinerOptions(config))
+	if err != nil {
+		return fmt.Errorf("failed to create examiners: %w", err)
+	}
+
+	links, dupsRemoved := pkghttp.DeduplicateLinks(links)
+	if dupsRemoved > 0 {
+		customlog.Printf(customlog.Info, "Removed %d duplicate config link(s).\n", dupsRemoved)
+	}
+
+	// Only links both cores can parse are worth comparing
+	supported := make([]string, 0, len(links))
+	for _, link := range links {
+		if err := comparer.Supports(link); err != nil {
+			if config.Verbose || len(links) == 1 {
+				customlog.Printf(customlog.Warning, "Skipping %s: %v\n", link, err)
+			}
+			continue
+		}
+		supported = append(supported, link)
+	}
+	if skipped := len(links) - len(supported); skipped > 0 {
+		customlog.Printf(customlog.Info, "Skipped %d config(s) only one core can handle.\n", skipped)
+	}
+	if len(supported) == 0 {
+		return fmt.Errorf("no configs both cores can handle")
+	}
+
+	printConfiguration(config, len(supported))
+
+	var runID int64
+	if config.SaveToDB {
+		optsJson, err := json.Marshal(struct {
+			pkghttp.Options
+			CompareCores bool `json:"compareCores"`
+		}{examinerOptions(config), true})
+		if err != nil {
+			return fmt.Errorf("failed to marshal test options to JSON: %w", err)
+		}
+		// Every link gets a result row per core
+		runID, err = database.CreateHttpTestRun(string(optsJson), 2*len(supported))
+		if err != nil {
+			return fmt.Errorf("failed to create database entry for test run: %w", err)
+		}
+		customlog.Printf(customlog.Info, "Created test run with ID: %d. Results will be saved to the database.\n", runID)
+	}
+
+	bar := progressbar.NewOptions(len(supported),
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionEnableColorCodes(true),
+		progressbar.OptionShowCount(),
+		progressbar.OptionShowIts(),
+		progressbar.OptionSetDescription("[cyan]Comparing cores (0 disagree)[reset]"),
+		progressbar.OptionSetTheme(progressbar.Theme{
+			Saucer:        "[green]=[reset]",
+			SaucerHead:    "[green]>[reset]",
+			SaucerPadding: " ",
+			BarStart:      "[",
+			BarEnd:        "]",
+		}),
+	)
+
+	resultsChan := make(chan pkghttp.CoreComparison, config.ThreadCount)
+	var comparisons []pkghttp.CoreComparison
+	var disagreeCount int32
+	var collectorWg sync.WaitGroup
+	collectorWg.Add(1)
+	go func() {
+		defer collectorWg.Done()
+		for cmp := range resultsChan {
+			if cmp.Disagree {
+				atomic.AddInt32(&disagreeCount, 1)
+			}
+			comparisons = append(comparisons, cmp)
+		}
+	}()
+
+	comparer.Run(ctx, supported, config.ThreadCount, resultsChan, func() {
+		bar.Describe(fmt.Sprintf("[cyan]Comparing cores (%d disagree)[reset]", atomic.LoadInt32(&disagreeCount)))
+		bar.Add(1)
+	})
+	close(resultsChan)
+	collectorWg.Wait()
+	bar.Finish()
+	fmt.Fprintln(os.Stderr)
+
+	sort.SliceStable(comparisons, func(i, j int) bool {
+		return comparisons[i].Disagree && !comparisons[j].Disagree
+	})
+	printComparisons(comparisons)
+
+	// Only CSV holds both sides of a comparison
+	if config.OutputFile != "" && config.OutputType == "csv" {
+		if err := pkghttp.SaveComparisonsCSV(config.OutputFile, comparisons); err != nil {
+			return err
+		}
+		customlog.Printf(customlog.Finished, "Comparison results have been saved to %s\n", config.OutputFile)
+	}
+	if runID > 0 {
+		if err := pkghttp.SaveComparisonsToDB(runID, comparisons); err != nil {
+			return err
+		}
+		customlog.Printf(customlog.Finished, "Results of both cores saved to the database (run %d).\n", runID)
+	}
+
+	customlog.Printf(customlog.Finished, "Compared %d configs: the cores disagree on %d.\n", len(comparisons), disagreeCount)
+	return nil
+}
+
+// printComparisons prints one line per link with each core's status, delay
+// and failure reason. Disagreements are marked with "!".
+func printComparisons(comparisons []pkghttp.CoreComparison) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "\tXRAY\tDELAY\tSING-BOX\tDELAY\tREASON\tLINK")
+	fmt.Fprintln(w, "\t----\t-----\t--------\t-----\t------\t----")
+	for _, cmp := range comparisons {
+		mark := ""
+		if cmp.Disagree {
+			mark = "!"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", mark,
+			cmp.Xray.Status, formatDelay(cmp.Xray.Delay),
+			cmp.Singbox.Status, formatDelay(cmp.Singbox.Delay),
+			comparisonReason(cmp), cmp.ConfigLink)
+	}
+	w.Flush()
+}
+
+func formatDelay(delay int64) string {
+	if delay < 0 {
+		return "N/A"
+	}
+	return strconv.FormatInt(delay, 10) + "ms"
+}
+
+// comparisonReason joins the failure reasons of both cores, naming the core
+// each came from.
+func comparisonReason(cmp pkghttp.CoreComparison) string {
+	var reason string
+	for _, res := range []pkghttp.Result{cmp.Xray, cmp.Singbox} {
+		if res.Reason == "" || res.Status == "passed" {
+			continue
+		}
+		if reason != "" {
+			reason += "; "
+		}
+		reason += res.Core + ": " + utils.Truncate(res.Reason, 60)
+	}
+	if reason == "" {
+		return "-"
+	}
+	return reason
+}
@@ -19,8 +19,11 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/lilendian0x00/xray-knife/v10/database"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/convert"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/export"
 	pkghttp "github.com/lilendian0x00/xray-knife/v10/pkg/http"
-	"github.com/lilendian0x00/xray-knife/v10/utils"
 	"github.com/lilendian0x00/xray-knife/v10/utils/customlog"
 )
 
@@ -53,13 +56,32 @@ type Config struct {
 	SaveToDB            bool
 	Speedtest           bool
 	GetIPInfo           bool
+	UDPTest             bool
+	UDPResolver         string
+	UDPHTTP3URL         string
 	SpeedtestAmount     uint64
 	MaximumAllowedDelay uint16
 	Timeout             uint16
 	Retries             uint16
+	Samples             uint16
 	Ping                bool
 	PingInterval        uint16
 	BindInterface       string
+	FragmentSpec        string
+	Noises              []string
+	Fragment            *protocol.FragmentOptions
+	MuxSpec             string
+	Mux                 *protocol.MuxOptions
+	CorePolicySpec      string
+	CorePolicy          *core.CorePolicy
+	CompareCores        bool
+	ProfileSpec         string
+	Profile             *pkghttp.Profile
+	SpeedtestSpec       string
+	SpeedtestBackend    pkghttp.SpeedtestBackend
+	SpeedtestDuration   uint16
+	SpeedtestStreams    uint8
+	SpeedtestRampUp     uint16
 }
 
 func validateConfig(cfg *Config) error {
@@ -69,13 +91,48 @@ func validateConfig(cfg *Config) error {
 	}
 
 	if cfg.OutputFile != "" {
-		validOutputTypes := map[string]bool{"csv": true, "txt": true}
+		validOutputTypes := map[string]bool{"csv": true, "txt": true, "xray": true, "singbox": true, "clash": true}
 		if !validOutputTypes[cfg.OutputType] {
-			return fmt.Errorf("bad output format. Allowed formats: txt, csv")
+			return fmt.Errorf("bad output format. Allowed formats: txt, csv, xray, singbox, clash")
 		}
+		base := strings.TrimSuffix(cfg.OutputFile, filepath.Ext(cfg.OutputFile))
 		if cfg.OutputType == "csv" {
-			base := strings.TrimSuffix(cfg.OutputFile, filepath.Ext(cfg.OutputFile))
 			cfg.OutputFile = base + ".csv"
+		} else if pkghttp.IsExportOutputType(cfg.OutputType) {
+			cfg.OutputFile = base + export.Format(cfg.OutputType).Ext()
+		}
+	}
+
+	fragment, err := protocol.NewFragmentOptions(cfg.FragmentSpec, cfg.Noises)
+	if err != nil {
+		return err
+	}
+	cfg.Fragment = fragment
+
+	if cfg.Mux, err = protocol.ParseMuxSpec(cfg.MuxSpec); err != nil {
+		return err
+	}
+
+	if cfg.CorePolicy, err = core.LoadCorePolicy(cfg.CorePolicySpec); err != nil {
+		return err
+	}
+	if cfg.CorePolicy != nil && cfg.CoreType != "auto" && !cfg.CompareCores {
+		customlog.Printf(customlog.Warning, "--core-policy only applies to the auto core.\n")
+	}
+
+	if cfg.Profile, err = pkghttp.LoadProfile(cfg.ProfileSpec); err != nil {
+		return err
+	}
+	if cfg.SpeedtestBackend, err = pkghttp.ParseSpeedtestBackend(cfg.SpeedtestSpec); err != nil {
+		return err
+	}
+
+	if cfg.CompareCores {
+		if cfg.Ping {
+			return fmt.Errorf("--compare-cores cannot be used with --ping")
+		}
+		if cfg.CoreType != "auto" || cfg.CorePolicy != nil {
+			customlog.Printf(customlog.Warning, "--core and --core-policy are ignored with --compare-cores.\n")
 		}
 	}
 
@@ -90,6 +147,17 @@ func validateConfig(cfg *Config) error {
 			customlog.Printf(customlog.Warning, "--speedtest is disabled in ping mode.\n")
 			cfg.Speedtest = false
 		}
+		if cfg.Profile != nil {
+			customlog.Printf(customlog.Warning, "--profile is ignored in ping mode, pinging --url.\n")
+		}
+		if cfg.UDPTest {
+			customlog.Printf(customlog.Warning, "--udp is disabled in ping mode.\n")
+			cfg.UDPTest = false
+		}
+		if cfg.Samples > 1 {
+			customlog.Printf(customlog.Warning, "--samples is disabled in ping mode.\n")
+			cfg.Samples = 0
+		}
 	}
 	return nil
 }
@@ -108,25 +176,6 @@ Use --from-db to test configs from the database library.`,
 				return err
 			}
 
-			examiner, err := pkghttp.NewExaminer(pkghttp.Options{
-				Core:                   config.CoreType,
-				MaxDelay:               config.MaximumAllowedDelay,
-				Timeout:                config.Timeout,
-				Retries:                uint8(config.Retries),
-				Verbose:                config.Verbose,
-				ShowBody:               config.ShowBody,
-				InsecureTLS:            config.InsecureTLS,
-				DoSpeedtest:            config.Speedtest,
-				DoIPInfo:               config.GetIPInfo,
-				TestEndpoint:           config.DestURL,
-				TestEndpointHttpMethod: config.HTTPMethod,
-				SpeedtestKbAmount:      config.SpeedtestAmount,
-				BindInterface:          config.BindInterface,
-			})
-			if err != nil {
-				return fmt.Errorf("failed to create examiner: %w", err)
-			}
-
 			// Determine source of configs for batch testing
 			var links []string
 			if config.FromDB {
@@ -142,16 +191,15 @@ Use --from-db to test configs from the database library.`,
 				}
 				customlog.Printf(customlog.Success, "Found %d config links to test.\n", len(links))
 			} else if config.ConfigLinksFile != "" {
-				links = utils.ParseFileByNewline(config.ConfigLinksFile)
-			}
-
-			// If we have links for a batch test, run it.
-			if len(links) > 0 {
-				return handleMultipleConfigs(examiner, config, links)
+				var err error
+				links, err = convert.ReadLinksFile(config.ConfigLinksFile)
+				if err != nil {
+					return err
+				}
 			}
 
 			// Handle single config modes (ping or one-shot test from flag/stdin).
-			if config.ConfigLink == "" {
+			if len(links) == 0 && config.ConfigLink == "" {
 				customlog.Printf(customlog.Info, "Please enter a config link and press Enter:\n")
 				reader := bufio.NewReader(os.Stdin)
 				text, err := reader.ReadString('\n')
@@ -164,6 +212,23 @@ Use --from-db to test configs from the database library.`,
 				}
 			}
 
+			if config.CompareCores {
+				if len(links) == 0 {
+					links = []string{config.ConfigLink}
+				}
+				return handleCompareCores(config, links)
+			}
+
+			examiner, err := pkghttp.NewExaminer(examinerOptions(config))
+			if err != nil {
+				return fmt.Errorf("failed to create examiner: %w", err)
+			}
+
+			// If we have links for a batch test, run it.
+			if len(links) > 0 {
+				return handleMultipleConfigs(examiner, config, links)
+			}
+
 			if config.Ping {
 				return handlePingMode(examiner, config)
 			} else {
@@ -177,6 +242,37 @@ Use --from-db to test configs from the database library.`,
 	return cmd
 }
 
+// examinerOptions maps the command flags to the examiner options.
+func examinerOptions(config *Config) pkghttp.Options {
+	return pkghttp.Options{
+		Core:                   config.CoreType,
+		MaxDelay:               config.MaximumAllowedDelay,
+		Timeout:                config.Timeout,
+		Retries:                uint8(config.Retries),
+		Samples:                config.Samples,
+		Verbose:                config.Verbose,
+		ShowBody:               config.ShowBody,
+		InsecureTLS:            config.InsecureTLS,
+		DoSpeedtest:            config.Speedtest,
+		DoIPInfo:               config.GetIPInfo,
+		DoUDPTest:              config.UDPTest,
+		UDPResolver:            config.UDPResolver,
+		UDPHTTP3URL:            config.UDPHTTP3URL,
+		TestEndpoint:           config.DestURL,
+		TestEndpointHttpMethod: config.HTTPMethod,
+		SpeedtestKbAmount:      config.SpeedtestAmount,
+		SpeedtestBackend:       config.SpeedtestBackend,
+		SpeedtestDuration:      config.SpeedtestDuration,
+		SpeedtestStreams:       config.SpeedtestStreams,
+		SpeedtestRampUp:        config.SpeedtestRampUp,
+		BindInterface:          config.BindInterface,
+		Fragment:               config.Fragment,
+		Mux:                    config.Mux,
+		CorePolicy:             config.CorePolicy,
+		Profile:                config.Profile,
+	}
+}
+
 // handlePingMode runs a continuous ping loop until the user hits Ctrl+C.
 func handlePingMode(examiner *pkghttp.Examiner, config *Config) error {
 	pinger, err := examiner.Core.CreateProtocol(config.ConfigLink)
@@ -264,21 +360,7 @@ func handleMultipleConfigs(examiner *pkghttp.Examiner, config *Config, links []s
 	printConfiguration(config, len(links))
 
 	// Create a test run entry in the database
-	opts := pkghttp.Options{
-		Core:                   config.CoreType,
-		MaxDelay:               config.MaximumAllowedDelay,
-		Timeout:                config.Timeout,
-		Retries:                uint8(config.Retries),
-		Verbose:                config.Verbose,
-		ShowBody:               config.ShowBody,
-		InsecureTLS:            config.InsecureTLS,
-		DoSpeedtest:            config.Speedtest,
-		DoIPInfo:               config.GetIPInfo,
-		TestEndpoint:           config.DestURL,
-		TestEndpointHttpMethod: config.HTTPMethod,
-		SpeedtestKbAmount:      config.SpeedtestAmount,
-		BindInterface:          config.BindInterface,
-	}
+	opts := examinerOptions(config)
 	optsJson, err := json.Marshal(opts)
 	if err != nil {
 		return fmt.Errorf("failed to marshal test options to JSON: %w", err)
@@ -377,7 +459,8 @@ func handleMultipleConfigs(examiner *pkghttp.Examiner, config *Config, links []s
 	fmt.Fprintln(os.Stderr)
 
 	// If sorted output was requested, rewrite the file sorted
-	if config.SortedByRealDelay && config.OutputFile != "" {
+	// Client config formats are only written here, once all results are in.
+	if config.OutputFile != "" && (config.SortedByRealDelay || pkghttp.IsExportOutputType(config.OutputType)) {
 		processor.RewriteFileSorted(results)
 	}
 
@@ -388,6 +471,7 @@ func handleMultipleConfigs(examiner *pkghttp.Examiner, config *Config, links []s
 func handleSingleConfig(examiner *pkghttp.Examiner, config *Config) {
 	examiner.Verbose = true
 	res, err := examiner.ExamineConfig(context.Background(), config.ConfigLink)
+	printTargets(res.Targets)
 	if err != nil {
 		customlog.Printf(customlog.Failure, "%v\n", err)
 		return
@@ -397,10 +481,28 @@ func handleSingleConfig(examiner *pkghttp.Examiner, config *Config) {
 		customlog.Printf(customlog.Failure, "%s: %s\n", res.Status, res.Reason)
 	}
 
+	if res.Core != "" && config.CoreType == "auto" {
+		customlog.Printf(customlog.Info, "Core: %s\n", res.Core)
+	}
+	switch res.UDP {
+	case pkghttp.UDPOk:
+		customlog.Printf(customlog.Success, "UDP: %s\n", res.UDP)
+	case pkghttp.UDPFail, pkghttp.UDPUnsupported:
+		customlog.Printf(customlog.Failure, "UDP: %s\n", res.UDP)
+	}
 	if res.Delay >= 0 {
 		customlog.Printf(customlog.Success, "Real Delay: %dms\n\n", res.Delay)
 	}
-	if config.Speedtest {
+	if res.Samples > 1 {
+		customlog.Printf(customlog.Info, "%d samples: min %dms, p50 %dms, p95 %dms, jitter %dms, loss %.1f%%\n",
+			res.Samples, res.DelayMin, res.DelayP50, res.DelayP95, res.Jitter, res.Loss)
+	}
+	if config.Speedtest && config.SpeedtestDuration > 0 {
+		customlog.Printf(customlog.Success, "Download for %ds - Speed: %f mbps\n",
+			config.SpeedtestDuration, res.DownloadSpeed)
+		customlog.Printf(customlog.Success, "Upload for %ds - Speed: %f mbps\n",
+			config.SpeedtestDuration, res.UploadSpeed)
+	} else if config.Speedtest {
 		customlog.Printf(customlog.Success, "Downloaded %dKB - Speed: %f mbps\n",
 			config.SpeedtestAmount, res.DownloadSpeed)
 		customlog.Printf(customlog.Success, "Uploaded %dKB - Speed: %f mbps\n",
@@ -408,17 +510,56 @@ func handleSingleConfig(examiner *pkghttp.Examiner, config *Config) {
 	}
 }
 
+// printTargets prints the outcome of each target of the test profile.
+func printTargets(targets []pkghttp.TargetResult) {
+	for _, t := range targets {
+		if t.Passed {
+			customlog.Printf(customlog.Success, "%s: %d in %dms\n", t.Name, t.Code, t.Delay)
+		} else {
+			customlog.Printf(customlog.Failure, "%s: %s\n", t.Name, t.Reason)
+		}
+	}
+}
+
 // printConfiguration prints the current configuration
 func printConfiguration(config *Config, totalConfigs int) {
+	testTarget, testTargetLabel := config.DestURL, "Test url"
+	if config.Profile != nil {
+		testTarget, testTargetLabel = config.Profile.String(), "Test profile"
+	}
 	fmt.Printf("%s: %d\n%s: %d\n%s: %dms\n%s: %t\n%s: %s\n%s: %t\n%s: %t\n",
 		color.RedString("Total configs"), totalConfigs,
 		color.RedString("Thread count"), config.ThreadCount,
 		color.RedString("Maximum delay"), config.MaximumAllowedDelay,
 		color.RedString("Speed test"), config.Speedtest,
-		color.RedString("Test url"), config.DestURL,
+		color.RedString(testTargetLabel), testTarget,
 		color.RedString("IP info"), config.GetIPInfo,
 		color.RedString("Insecure TLS"), config.InsecureTLS,
 	)
+	if config.Speedtest {
+		fmt.Printf("%s: %s\n", color.RedString("Speed test backend"), config.SpeedtestSpec)
+		if config.SpeedtestDuration > 0 {
+			fmt.Printf("%s: %ds, %d stream(s)\n", color.RedString("Speed test duration"), config.SpeedtestDuration, max(config.SpeedtestStreams, 1))
+		}
+	}
+	if config.Samples > 1 {
+		fmt.Printf("%s: %d\n", color.RedString("Delay samples"), config.Samples)
+	}
+	if config.UDPTest {
+		fmt.Printf("%s: %s\n", color.RedString("UDP resolver"), config.UDPResolver)
+		if config.UDPHTTP3URL != "" {
+			fmt.Printf("%s: %s\n", color.RedString("HTTP/3 url"), config.UDPHTTP3URL)
+		}
+	}
+	if config.Fragment != nil {
+		fmt.Printf("%s: %s\n", color.RedString("Fragment"), config.Fragment)
+	}
+	if config.Mux != nil {
+		fmt.Printf("%s: %s\n", color.RedString("Mux"), config.Mux)
+	}
+	if config.CorePolicy != nil {
+		fmt.Printf("%s: %s\n", color.RedString("Core policy"), config.CorePolicy)
+	}
 	if config.OutputFile != "" {
 		fmt.Printf("%s: %s\n", color.RedString("Output file"), config.OutputFile)
 	}
@@ -430,30 +571,44 @@ func addFlags(cmd *cobra.Command, config *Config) {
 
 	// Input flags
 	flags.StringVarP(&config.ConfigLink, "config", "c", "", "The xray config link")
-	flags.StringVarP(&config.ConfigLinksFile, "file", "f", "", "Read config links from a file")
+	flags.StringVarP(&config.ConfigLinksFile, "file", "f", "", "Read config links from a file (share links, base64, Clash YAML, sing-box/xray JSON or wg-quick .conf)")
 
 	// Core flags
 	flags.Uint16VarP(&config.ThreadCount, "thread", "t", 50, "Number of threads")
 	flags.StringVarP(&config.CoreType, "core", "z", "auto", "Core type (auto, singbox, xray)")
 	flags.StringVarP(&config.DestURL, "url", "u", "https://cloudflare.com/cdn-cgi/trace", "The url to test config")
 	flags.StringVarP(&config.HTTPMethod, "method", "m", "GET", "Http method")
+	flags.StringVar(&config.ProfileSpec, "profile", "", "Test against several targets instead of --url: a built-in profile (default, google, essentials) or a JSON profile file")
 	flags.BoolVarP(&config.ShowBody, "body", "b", false, "Show response body")
 	flags.Uint16VarP(&config.MaximumAllowedDelay, "mdelay", "d", 5000, "Maximum allowed delay (ms)")
 	flags.BoolVarP(&config.InsecureTLS, "insecure", "e", false, "Insecure tls connection (fake SNI)")
 	flags.Uint16Var(&config.Timeout, "timeout", 0, "HTTP client timeout in ms (0 = use mdelay value)")
 	flags.Uint16Var(&config.Retries, "retries", 0, "Number of retries for failed proxy tests")
+	flags.Uint16Var(&config.Samples, "samples", 0, "Send this many requests through each config and rank by their median, jitter and loss instead of a single delay")
 
 	// Speedtest flags
-	flags.BoolVarP(&config.Speedtest, "speedtest", "p", false, "Speed test with speed.cloudflare.com")
-	flags.Uint64VarP(&config.SpeedtestAmount, "amount", "a", 10000, "Download and upload amount (KB)")
+	flags.BoolVarP(&config.Speedtest, "speedtest", "p", false, "Speed test the configs (see --speedtest-backend)")
+	flags.StringVar(&config.SpeedtestSpec, "speedtest-backend", "cloudflare", "Speed test backend: cloudflare[:server] (e.g. a speedtest-server URL), librespeed:<url> or url:<download url>[,<upload url>]")
+	flags.Uint64VarP(&config.SpeedtestAmount, "amount", "a", 10000, "Download and upload amount (KB); with --speedtest-duration, the size of each request")
+	flags.Uint16Var(&config.SpeedtestDuration, "speedtest-duration", 0, "Transfer for this many seconds in each direction instead of --amount (0 = fixed amount)")
+	flags.Uint8Var(&config.SpeedtestStreams, "speedtest-streams", 1, "Parallel connections per speed test; their throughput is added up")
+	flags.Uint16Var(&config.SpeedtestRampUp, "speedtest-rampup", 0, "Start of the transfer (ms) left out of the speed, while TCP ramps up (0 = a fifth of --speedtest-duration)")
 
 	flags.BoolVarP(&config.GetIPInfo, "rip", "r", true, "Receive real IP (csv)")
+	flags.BoolVar(&config.UDPTest, "udp", false, "Check UDP goes through the configs with a DNS query (udp column: ok, fail or unsupported)")
+	flags.StringVar(&config.UDPResolver, "udp-resolver", pkghttp.DefaultUDPResolver, "DNS server (ip[:port]) queried by --udp")
+	flags.StringVar(&config.UDPHTTP3URL, "udp-h3", "", "Also make an HTTP/3 request to this URL with --udp (e.g. https://cloudflare.com/cdn-cgi/trace)")
 	flags.BoolVarP(&config.Verbose, "verbose", "v", false, "Verbose")
 
+	flags.BoolVar(&config.CompareCores, "compare-cores", false, "Test every config on both xray and sing-box and flag the ones only one core gets through")
 	flags.BoolVar(&config.Ping, "ping", false, "Enable continuous HTTP ping mode for a single config")
 	flags.Uint16Var(&config.PingInterval, "interval", 1000, "Interval between pings in milliseconds (ms)")
 
 	flags.StringVar(&config.BindInterface, "bind", "", "Bind outbound dials to a specific OS interface (e.g. eth0). Linux: needs CAP_NET_RAW.")
+	flags.StringVar(&config.FragmentSpec, "fragment", "", "Fragment the TLS ClientHello: packets,length,interval (e.g. tlshello,100-200,10-20) or \"default\"")
+	flags.StringVar(&config.MuxSpec, "mux", "", "Override the links' multiplexing, e.g. to compare runs with and without it: off, on or smux/yamux/h2mux, plus optional settings (e.g. on,concurrency=8)")
+	flags.StringVar(&config.CorePolicySpec, "core-policy", "", "Pick the auto core per protocol/transport: rules like vless/ws=singbox,trojan=xray[,fallback=off] or a file holding them (text or JSON)")
+	flags.StringArrayVar(&config.Noises, "noise", nil, "Send a noise packet before UDP traffic (xray only): type:packet[:delay], e.g. rand:10-20:10-16 (repeatable)")
 
 	// DB flags
 	flags.BoolVar(&config.FromDB, "from-db", false, "Test configs from the database")
@@ -463,7 +618,7 @@ func addFlags(cmd *cobra.Command, config *Config) {
 
 	// Output Flags
 	flags.StringVarP(&config.OutputFile, "out", "o", "valid.txt", "Output file for valid/all config links")
-	flags.StringVarP(&config.OutputType, "type", "x", "txt", "Output type for file (csv, txt)")
+	flags.StringVarP(&config.OutputType, "type", "x", "txt", "Output type for file (csv, txt, or an xray/singbox/clash client config of the passed configs)")
 	flags.BoolVarP(&config.SortedByRealDelay, "sort", "s", true, "Sort config links by their delay (fast to slow) in file output")
 	flags.BoolVar(&config.SaveToDB, "save-db", false, "Save test results to the database")
 
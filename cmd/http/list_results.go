@@ -28,8 +28,8 @@ var listResultsCmd = &cobra.Command{
 		}
 
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-		fmt.Fprintln(w, "STATUS\tDELAY\tDOWNLOAD\tUPLOAD\tLOCATION\tLINK")
-		fmt.Fprintln(w, "------\t-----\t--------\t------\t--------\t----")
+		fmt.Fprintln(w, "STATUS\tCORE\tDELAY\tP50/P95\tJITTER\tLOSS\tDOWNLOAD\tUPLOAD\tUDP\tLOCATION\tLINK")
+		fmt.Fprintln(w, "------\t----\t-----\t-------\t------\t----\t--------\t------\t---\t--------\t----")
 
 		for _, res := range results {
 			delay := "N/A"
@@ -37,6 +37,13 @@ var listResultsCmd = &cobra.Command{
 				delay = strconv.FormatInt(res.DelayMs, 10) + "ms"
 			}
 
+			p50p95, jitter, loss := "-", "-", "-"
+			if res.Samples > 1 {
+				p50p95 = fmt.Sprintf("%d/%dms", res.DelayP50Ms, res.DelayP95Ms)
+				jitter = strconv.FormatInt(res.JitterMs, 10) + "ms"
+				loss = fmt.Sprintf("%.0f%%", res.LossPct)
+			}
+
 			download := "N/A"
 			if res.DownloadMbps > 0 {
 				download = fmt.Sprintf("%.2f Mbps", res.DownloadMbps)
@@ -52,7 +59,17 @@ var listResultsCmd = &cobra.Command{
 				location = res.IPLocation.String
 			}
 
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", res.Status, delay, download, upload, location, res.ConfigLink)
+			coreName := "-"
+			if res.Core.Valid {
+				coreName = res.Core.String
+			}
+
+			udp := "-"
+			if res.UDP.Valid {
+				udp = res.UDP.String
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", res.Status, coreName, delay, p50p95, jitter, loss, download, upload, udp, location, res.ConfigLink)
 		}
 
 		return w.Flush()
@@ -49,6 +49,12 @@ func runApp(cmd *cobra.Command, args []string) error {
 	if err := validateChainFlags(&appCmdCh, pf.coreType); err != nil {
 		return err
 	}
+	if err := validateOutboundNetFlags(&appCmdOn); err != nil {
+		return err
+	}
+	if err := validateRotationFlags(&appCmdRot); err != nil {
+		return err
+	}
 	links, err := resolveLinks(&pf)
 	if err != nil {
 		return err
@@ -41,6 +41,12 @@ func runInbound(cmd *cobra.Command, args []string) error {
 	if err := validateChainFlags(&inboundCmdRot.ch, pf.coreType); err != nil {
 		return err
 	}
+	if err := validateOutboundNetFlags(&inboundCmdRot.on); err != nil {
+		return err
+	}
+	if err := validateRotationFlags(&inboundCmdRot.rot); err != nil {
+		return err
+	}
 	links, err := resolveLinks(&pf)
 	if err != nil {
 		return err
@@ -33,7 +33,7 @@ those are provided, from the local subscription database (populate with
 		return []string{"xray", "sing-box"}, cobra.ShellCompDirectiveNoFileComp
 	})
 	flags.StringVarP(&pf.configLink, "config", "c", "", "The single xray/sing-box config link to use")
-	flags.StringVarP(&pf.configFile, "file", "f", "", "Read config links from a file")
+	flags.StringVarP(&pf.configFile, "file", "f", "", "Read config links from a file (share links, base64, Clash YAML, sing-box/xray JSON or wg-quick .conf)")
 	flags.BoolVarP(&pf.readFromSTDIN, "stdin", "i", false, "Read config link(s) from STDIN")
 	flags.StringVarP(&pf.listenAddr, "addr", "a", "127.0.0.1", "Listen ip address for the proxy server")
 	flags.StringVarP(&pf.listenPort, "port", "p", "9999", "Listen port number for the proxy server")
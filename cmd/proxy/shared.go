@@ -9,8 +9,10 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/lilendian0x00/xray-knife/v10/pkg/convert"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+	pkghttp "github.com/lilendian0x00/xray-knife/v10/pkg/http"
 	pkgproxy "github.com/lilendian0x00/xray-knife/v10/pkg/proxy"
-	"github.com/lilendian0x00/xray-knife/v10/utils"
 	"github.com/lilendian0x00/xray-knife/v10/utils/customlog"
 
 	"github.com/spf13/cobra"
@@ -42,6 +44,11 @@ type rotationFlags struct {
 	drainTimeout        uint16
 	blacklistStrikes    uint16
 	blacklistDuration   uint32
+	testProfileSpec     string
+	testSamples         uint16
+
+	// testProfile is loaded from testProfileSpec by validateRotationFlags.
+	testProfile *pkghttp.Profile
 }
 
 // chainFlags carries the multi-hop chaining knobs.
@@ -55,11 +62,19 @@ type chainFlags struct {
 }
 
 // outboundNetFlags carries flags that shape outbound dials (interface
-// pinning + DNS resolver inside the tunnel).
+// pinning, TLS fragmentation, mux + DNS resolver inside the tunnel).
 type outboundNetFlags struct {
 	bindInterface string
+	fragmentSpec  string
+	noises        []string
+	muxSpec       string
 	dns           string
 	dnsType       string
+
+	// fragment and mux are parsed from the flags above by
+	// validateOutboundNetFlags.
+	fragment *protocol.FragmentOptions
+	mux      *protocol.MuxOptions
 }
 
 // inboundCfg holds inbound-protocol flag values shared between
@@ -111,6 +126,18 @@ func addRotationFlags(cmd *cobra.Command, r *rotationFlags) {
 	flags.Uint16Var(&r.drainTimeout, "drain", 0, "Seconds to keep the current outbound serving before switching during rotation (0=switch immediately)")
 	flags.Uint16Var(&r.blacklistStrikes, "blacklist-strikes", 3, "Failures before blacklisting a config (0=disabled)")
 	flags.Uint32Var(&r.blacklistDuration, "blacklist-duration", 600, "Seconds to blacklist a failed config")
+	flags.StringVar(&r.testProfileSpec, "test-profile", "", "Test configs against several targets: a built-in profile (default, google, essentials) or a JSON profile file")
+	flags.Uint16Var(&r.testSamples, "test-samples", 0, "Requests sent through each config during rotation, ranking them by median, jitter and loss (0 or 1 = single request)")
+}
+
+// validateRotationFlags loads the --test-profile value.
+func validateRotationFlags(r *rotationFlags) error {
+	profile, err := pkghttp.LoadProfile(r.testProfileSpec)
+	if err != nil {
+		return err
+	}
+	r.testProfile = profile
+	return nil
 }
 
 func addChainFlags(cmd *cobra.Command, c *chainFlags) {
@@ -130,6 +157,9 @@ func addChainFlags(cmd *cobra.Command, c *chainFlags) {
 func addOutboundNetFlags(cmd *cobra.Command, o *outboundNetFlags) {
 	flags := cmd.Flags()
 	flags.StringVar(&o.bindInterface, "bind", "", "Bind outbound dials to a specific OS interface (e.g. eth0). Linux: needs CAP_NET_RAW.")
+	flags.StringVar(&o.fragmentSpec, "fragment", "", "Fragment the TLS ClientHello: packets,length,interval (e.g. tlshello,100-200,10-20) or \"default\"")
+	flags.StringArrayVar(&o.noises, "noise", nil, "Send a noise packet before UDP traffic (xray only): type:packet[:delay], e.g. rand:10-20:10-16 (repeatable)")
+	flags.StringVar(&o.muxSpec, "mux", "", "Override the links' multiplexing: off, on or smux/yamux/h2mux, plus optional settings (e.g. on,concurrency=8,xudp=16)")
 	flags.StringVar(&o.dns, "dns", "1.1.1.1", "DNS resolver used inside the app/tun-mode tunnel (ip, ip:port, or https://host/path for --dns-type=https)")
 	flags.StringVar(&o.dnsType, "dns-type", "udp", "DNS transport for the app/tun-mode tunnel: udp, tcp, tls, https")
 	cmd.RegisterFlagCompletionFunc("dns-type", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -165,13 +195,27 @@ func validateChainFlags(c *chainFlags, coreType string) error {
 	return nil
 }
 
+// validateOutboundNetFlags parses the --fragment / --noise / --mux values.
+func validateOutboundNetFlags(o *outboundNetFlags) error {
+	fragment, err := protocol.NewFragmentOptions(o.fragmentSpec, o.noises)
+	if err != nil {
+		return err
+	}
+	mux, err := protocol.ParseMuxSpec(o.muxSpec)
+	if err != nil {
+		return err
+	}
+	o.fragment, o.mux = fragment, mux
+	return nil
+}
+
 // resolveLinks reads config links from the persistent --config / --file /
 // --stdin flags (mutual exclusion is enforced by cobra on the parent).
 // If none are set, returns nil — pkg/proxy then falls back to the DB pool.
 func resolveLinks(p *parentFlags) ([]string, error) {
 	switch {
 	case p.configFile != "":
-		return utils.ParseFileByNewline(p.configFile), nil
+		return convert.ReadLinksFile(p.configFile)
 	case p.configLink != "":
 		return []string{p.configLink}, nil
 	case p.readFromSTDIN:
@@ -229,6 +273,8 @@ func buildPkgConfig(
 		cfg.DrainTimeout = rot.drainTimeout
 		cfg.BlacklistStrikes = rot.blacklistStrikes
 		cfg.BlacklistDuration = rot.blacklistDuration
+		cfg.TestProfile = rot.testProfile
+		cfg.TestSamples = rot.testSamples
 	}
 	if ch != nil {
 		cfg.Chain = ch.chain
@@ -240,6 +286,8 @@ func buildPkgConfig(
 	}
 	if on != nil {
 		cfg.BindInterface = on.bindInterface
+		cfg.Fragment = on.fragment
+		cfg.Mux = on.mux
 		cfg.DNS = on.dns
 		cfg.DNSType = on.dnsType
 	}
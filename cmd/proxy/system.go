@@ -45,6 +45,12 @@ func runSystem(cmd *cobra.Command, args []string) error {
 	if err := validateChainFlags(&systemCmdRot.ch, pf.coreType); err != nil {
 		return err
 	}
+	if err := validateOutboundNetFlags(&systemCmdRot.on); err != nil {
+		return err
+	}
+	if err := validateRotationFlags(&systemCmdRot.rot); err != nil {
+		return err
+	}
 	links, err := resolveLinks(&pf)
 	if err != nil {
 		return err
@@ -60,6 +60,12 @@ func runTun(cmd *cobra.Command, args []string) error {
 	if err := validateChainFlags(&tunCmdCh, pf.coreType); err != nil {
 		return err
 	}
+	if err := validateOutboundNetFlags(&tunCmdOn); err != nil {
+		return err
+	}
+	if err := validateRotationFlags(&tunCmdRot); err != nil {
+		return err
+	}
 	links, err := resolveLinks(&pf)
 	if err != nil {
 		return err
@@ -6,11 +6,15 @@ import (
 	"path/filepath"
 
 	"github.com/lilendian0x00/xray-knife/v10/cmd/cfscanner"
+	"github.com/lilendian0x00/xray-knife/v10/cmd/edit"
 	xkexec "github.com/lilendian0x00/xray-knife/v10/cmd/exec"
+	"github.com/lilendian0x00/xray-knife/v10/cmd/gen"
 	"github.com/lilendian0x00/xray-knife/v10/cmd/http"
 	"github.com/lilendian0x00/xray-knife/v10/cmd/net"
 	"github.com/lilendian0x00/xray-knife/v10/cmd/parse"
 	"github.com/lilendian0x00/xray-knife/v10/cmd/proxy"
+	"github.com/lilendian0x00/xray-knife/v10/cmd/selftest"
+	"github.com/lilendian0x00/xray-knife/v10/cmd/speedtestserver"
 	"github.com/lilendian0x00/xray-knife/v10/cmd/subs"
 	"github.com/lilendian0x00/xray-knife/v10/cmd/webui"
 	"github.com/lilendian0x00/xray-knife/v10/database"
@@ -35,6 +39,7 @@ func Execute() {
 
 func addSubcommandPalettes() {
 	rootCmd.AddCommand(parse.ParseCmd)
+	rootCmd.AddCommand(edit.EditCmd)
 	rootCmd.AddCommand(subs.SubsCmd)
 	rootCmd.AddCommand(http.HttpCmd)
 	rootCmd.AddCommand(net.NetCmd)
@@ -42,6 +47,9 @@ func addSubcommandPalettes() {
 	rootCmd.AddCommand(proxy.ProxyCmd)
 	rootCmd.AddCommand(webui.WebUICmd)
 	rootCmd.AddCommand(xkexec.ExecCmd)
+	rootCmd.AddCommand(gen.GenCmd)
+	rootCmd.AddCommand(selftest.SelftestCmd)
+	rootCmd.AddCommand(speedtestserver.SpeedtestServerCmd)
 }
 
 // Set up the application's configuration and initialize the database.
@@ -0,0 +1,195 @@
+package selftest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core"
+	pkgselftest "github.com/lilendian0x00/xray-knife/v10/pkg/selftest"
+	"github.com/lilendian0x00/xray-knife/v10/utils"
+	"github.com/lilendian0x00/xray-knife/v10/utils/customlog"
+)
+
+// selftestCmdConfig holds the configuration for the selftest command
+type selftestCmdConfig struct {
+	serverCore string
+	clientCore string
+	protocols  []string
+	timeout    uint16
+	verbose    bool
+}
+
+// SelftestCmd is the selftest subcommand.
+var SelftestCmd = newSelftestCommand()
+
+func newSelftestCommand() *cobra.Command {
+	cfg := &selftestCmdConfig{}
+
+	cmd := &cobra.Command{
+		Use:   "selftest",
+		Short: "Check every protocol end to end against local servers.",
+		Long: `Starts a local server on 127.0.0.1 for each protocol and transport the xray-core
+and sing-box inbound builders serve, then fetches a local HTTP target through it
+with the client side of both cores. Nothing leaves the machine.
+
+A client is "unsupported" when its core has no client for the protocol or
+transport (sing-box has no xhttp, xray no hysteria2); failed clients and servers
+that don't start fail the test. TLS clients pin the generated certificate on
+xray and skip its verification on sing-box, which can't check a pin.
+
+Examples:
+  xray-knife selftest
+  xray-knife selftest --server-core sing-box --protocol vless,trojan -v`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cases, err := selectCases(cfg)
+			if err != nil {
+				return err
+			}
+			clients, err := coreNames(cfg.clientCore)
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			customlog.Printf(customlog.Processing, "Running %d self-test cases...\n", len(cases))
+			results, err := pkgselftest.Run(ctx, cases, pkgselftest.Options{
+				Clients: clients,
+				Timeout: time.Duration(cfg.timeout) * time.Millisecond,
+				Verbose: cfg.verbose,
+			}, func(res pkgselftest.Result) {
+				if !res.Passed() {
+					customlog.Printf(customlog.Failure, "%s on %s failed\n", res.Case, res.Case.Core)
+				}
+			})
+			if err != nil {
+				return err
+			}
+
+			printMatrix(results, clients, cfg.verbose)
+
+			failed := 0
+			for _, res := range results {
+				if !res.Passed() {
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d self-test cases failed", failed, len(results))
+			}
+			customlog.Printf(customlog.Finished, "All %d self-test cases passed.\n", len(results))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&cfg.serverCore, "server-core", "all", "Core running the servers (xray, sing-box, all)")
+	cmd.Flags().StringVar(&cfg.clientCore, "client-core", "all", "Core connecting to the servers (xray, sing-box, all)")
+	cmd.Flags().StringSliceVarP(&cfg.protocols, "protocol", "p", nil, "Only test these protocols (vless, vmess, trojan, ss, socks, hysteria2)")
+	cmd.Flags().Uint16VarP(&cfg.timeout, "timeout", "t", 5000, "Timeout of each request (ms)")
+	cmd.Flags().BoolVarP(&cfg.verbose, "verbose", "v", false, "Show the core logs and the links of the servers")
+	return cmd
+}
+
+func selectCases(cfg *selftestCmdConfig) ([]pkgselftest.Case, error) {
+	servers, err := coreNames(cfg.serverCore)
+	if err != nil {
+		return nil, err
+	}
+	var cases []pkgselftest.Case
+	for _, c := range pkgselftest.Cases() {
+		if !contains(servers, c.Core) {
+			continue
+		}
+		if len(cfg.protocols) > 0 && !contains(cfg.protocols, c.Protocol) {
+			continue
+		}
+		cases = append(cases, c)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("no self-test case matches the filters")
+	}
+	return cases, nil
+}
+
+// coreNames maps a --*-core flag value to core names.
+func coreNames(flag string) ([]string, error) {
+	switch strings.ToLower(flag) {
+	case "all", "":
+		return []string{core.XrayCoreName, core.SingboxCoreName}, nil
+	case "xray":
+		return []string{core.XrayCoreName}, nil
+	case "sing-box", "singbox":
+		return []string{core.SingboxCoreName}, nil
+	}
+	return nil, fmt.Errorf("unknown core %q (want xray, sing-box or all)", flag)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
+
+// printMatrix prints one line per case with the outcome of each client
+// core. Failed cases are marked with "!".
+func printMatrix(results []pkgselftest.Result, clients []string, verbose bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	header := "\tSERVER\tCASE"
+	for _, c := range clients {
+		header += "\t" + strings.ToUpper(displayName(c))
+	}
+	header += "\tREASON"
+	if verbose {
+		header += "\tLINK"
+	}
+	fmt.Fprintln(w, header)
+
+	for _, res := range results {
+		mark := ""
+		if !res.Passed() {
+			mark = "!"
+		}
+		line := fmt.Sprintf("%s\t%s\t%s", mark, displayName(res.Case.Core), res.Case)
+		var reasons []string
+		if res.ServerErr != nil {
+			for range clients {
+				line += "\t-"
+			}
+			reasons = append(reasons, "server: "+res.ServerErr.Error())
+		}
+		for _, c := range res.Clients {
+			switch c.Status {
+			case pkgselftest.StatusPassed:
+				line += fmt.Sprintf("\tpassed %dms", c.Delay.Milliseconds())
+			default:
+				line += "\t" + c.Status
+				reasons = append(reasons, displayName(c.Core)+": "+utils.Truncate(c.Err.Error(), 60))
+			}
+		}
+		line += "\t" + strings.Join(reasons, "; ")
+		if verbose {
+			line += "\t" + res.Link
+		}
+		fmt.Fprintln(w, line)
+	}
+	w.Flush()
+}
+
+func displayName(coreName string) string {
+	if coreName == core.SingboxCoreName {
+		return "sing-box"
+	}
+	return coreName
+}
@@ -0,0 +1,90 @@
+package speedtestserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	pkghttp "github.com/lilendian0x00/xray-knife/v10/pkg/http"
+	"github.com/lilendian0x00/xray-knife/v10/utils/customlog"
+)
+
+// speedtestServerCmdConfig holds the configuration for the speedtest-server command
+type speedtestServerCmdConfig struct {
+	listen   string
+	maxMB    uint32
+	certFile string
+	keyFile  string
+}
+
+// SpeedtestServerCmd is the speedtest-server subcommand.
+var SpeedtestServerCmd = newSpeedtestServerCommand()
+
+func newSpeedtestServerCommand() *cobra.Command {
+	cfg := &speedtestServerCmdConfig{}
+
+	cmd := &cobra.Command{
+		Use:   "speedtest-server",
+		Short: "Serve speed tests for the http and cfscanner commands.",
+		Long: `Serves the download and upload endpoints of speed.cloudflare.com (/__down and
+/__up) and of LibreSpeed (/backend/garbage.php and /backend/empty.php), so configs
+can be speed tested against a server of your own.
+
+Examples:
+  xray-knife speedtest-server --listen :8080
+  xray-knife http -f configs.txt -p --speedtest-backend cloudflare:http://203.0.113.7:8080
+  xray-knife speedtest-server --listen :8443 --cert server.crt --key server.key`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (cfg.certFile == "") != (cfg.keyFile == "") {
+				return fmt.Errorf("--cert and --key must be used together")
+			}
+
+			srv := &http.Server{
+				Addr:              cfg.listen,
+				Handler:           pkghttp.NewSpeedtestHandler(int64(cfg.maxMB) << 20),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+				srv.Shutdown(shutdownCtx)
+			}()
+
+			scheme := "http"
+			if cfg.certFile != "" {
+				scheme = "https"
+			}
+			customlog.Printf(customlog.Success, "Speed test server listening on %s://%s\n", scheme, cfg.listen)
+
+			var err error
+			if cfg.certFile != "" {
+				err = srv.ListenAndServeTLS(cfg.certFile, cfg.keyFile)
+			} else {
+				err = srv.ListenAndServe()
+			}
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&cfg.listen, "listen", "l", ":8080", "Address to listen on")
+	flags.Uint32Var(&cfg.maxMB, "max-mb", 1024, "Largest download served (MB)")
+	flags.StringVar(&cfg.certFile, "cert", "", "TLS certificate file (serves https)")
+	flags.StringVar(&cfg.keyFile, "key", "", "TLS key file")
+
+	return cmd
+}
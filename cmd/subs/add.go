@@ -24,7 +24,8 @@ The subscription can later be fetched with 'subs fetch --id <ID>'.
 
 Examples:
   xray-knife subs add --url "https://example.com/sub"
-  xray-knife subs add --url "https://example.com/sub" --remark "My VPN" --user-agent "clash"`,
+  xray-knife subs add --url "https://example.com/sub" --remark "My VPN" --user-agent "clash"
+  xray-knife subs add --url "ssconf://example.com/key.json#Outline" --remark "Outline"`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Validate URL before storing
 		if _, err := url.ParseRequestURI(addURL); err != nil {
@@ -41,7 +42,7 @@ Examples:
 }
 
 func init() {
-	AddCmd.Flags().StringVarP(&addURL, "url", "u", "", "URL of the subscription")
+	AddCmd.Flags().StringVarP(&addURL, "url", "u", "", "URL of the subscription (http(s):// or an Outline ssconf:// key)")
 	AddCmd.Flags().StringVarP(&addRemark, "remark", "r", "", "A memorable name for the subscription")
 	AddCmd.Flags().StringVarP(&addUserAgent, "user-agent", "a", "", "Custom User-Agent for fetching the subscription")
 	AddCmd.MarkFlagRequired("url")
@@ -0,0 +1,77 @@
+package subs
+
+import (
+	"fmt"
+
+	"github.com/lilendian0x00/xray-knife/v10/database"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core"
+	"github.com/lilendian0x00/xray-knife/v10/utils/customlog"
+	"github.com/spf13/cobra"
+)
+
+var dedupDryRun bool
+
+// DedupCmd collapses stored configs that point at the same server.
+var DedupCmd = &cobra.Command{
+	Use:   "dedup",
+	Short: "Removes configs that point at the same server from the DB",
+	Long: `Collapses duplicate configs stored in the database. Two configs are duplicates
+when they share protocol, address, port, credentials and transport, even if their
+remarks, parameter order or vmess JSON layout differ. Of each group, the most
+recently seen config is kept.
+
+Configs fetched by older versions get their fingerprint computed first.
+
+Examples:
+  xray-knife subs dedup --dry-run
+  xray-knife subs dedup`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Newest first, so the first config of each fingerprint is the one to keep
+		configs, err := database.ListSubscriptionConfigs(0, "", 0)
+		if err != nil {
+			return err
+		}
+
+		backfill := make(map[int64]string)
+		kept := make(map[string]bool)
+		var duplicates []int64
+		for _, c := range configs {
+			fp := c.Fingerprint.String
+			if !c.Fingerprint.Valid {
+				if fp, err = core.LinkFingerprint(c.ConfigLink); err != nil {
+					continue
+				}
+				backfill[c.ID] = fp
+			}
+			if kept[fp] {
+				duplicates = append(duplicates, c.ID)
+				delete(backfill, c.ID)
+				continue
+			}
+			kept[fp] = true
+		}
+
+		if dedupDryRun {
+			customlog.Printf(customlog.Processing, "%d of %d configs are duplicates and would be removed.\n", len(duplicates), len(configs))
+			return nil
+		}
+
+		if len(backfill) > 0 {
+			if err := database.SetSubscriptionConfigFingerprints(backfill); err != nil {
+				return fmt.Errorf("failed to store fingerprints: %w", err)
+			}
+		}
+		if len(duplicates) > 0 {
+			if err := database.DeleteSubscriptionConfigs(duplicates); err != nil {
+				return fmt.Errorf("failed to remove duplicates: %w", err)
+			}
+		}
+
+		customlog.Printf(customlog.Success, "Removed %d duplicate configs, %d unique configs left.\n", len(duplicates), len(configs)-len(duplicates))
+		return nil
+	},
+}
+
+func init() {
+	DedupCmd.Flags().BoolVar(&dedupDryRun, "dry-run", false, "Only report how many configs would be removed")
+}
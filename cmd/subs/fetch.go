@@ -3,6 +3,7 @@ package subs
 import (
 	"database/sql"
 	"fmt"
+	"os"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -10,7 +11,9 @@ import (
 
 	"github.com/alitto/pond/v2"
 	"github.com/lilendian0x00/xray-knife/v10/database"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/convert"
 	"github.com/lilendian0x00/xray-knife/v10/pkg/core"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
 	"github.com/lilendian0x00/xray-knife/v10/utils"
 	"github.com/lilendian0x00/xray-knife/v10/utils/customlog"
 
@@ -55,9 +58,11 @@ Supports multiple input modes:
   --url <URL>    One-off fetch from a URL (configs saved to DB but not linked to a subscription).
   --all          Fetch from all enabled subscriptions in the DB.
   --file <PATH>  Read subscription URLs from a file (one per line) and fetch each concurrently.
+                 A sing-box/xray-core JSON or wg-quick .conf file is imported directly instead.
 
 Use --workers to control concurrency for --file and --all modes (default: 3).
-Fetched configs are parsed, deduplicated, and upserted into the local database.
+Fetched configs are parsed, deduplicated, linted (see 'parse --lint') and upserted
+into the local database.
 Optionally write the fetched configs to a file with --out.
 
 Examples:
@@ -65,7 +70,8 @@ Examples:
   xray-knife subs fetch --url "https://example.com/sub"
   xray-knife subs fetch --all
   xray-knife subs fetch --file urls.txt --workers 5
-  xray-knife subs fetch --file urls.txt --out configs.txt`,
+  xray-knife subs fetch --file urls.txt --out configs.txt
+  xray-knife subs fetch --file sing-box.json`,
 		RunE:         fc.runCommand,
 		PreRunE:      fc.validateFlags,
 		SilenceUsage: true,
@@ -82,7 +88,7 @@ func (fc *FetchCommand) addFlags(cmd *cobra.Command) {
 	flags.StringVarP(&fc.config.OutputFile, "out", "o", "configs.txt", "Output file for fetched configs (default: configs.txt).")
 	flags.StringVarP(&fc.config.Proxy, "proxy", "p", "", "Proxy to use for fetching the subscription")
 	flags.BoolVar(&fc.config.FetchAll, "all", false, "Fetch from all enabled subscriptions in the DB")
-	flags.StringVarP(&fc.config.FileInput, "file", "f", "", "File containing subscription URLs (one per line)")
+	flags.StringVarP(&fc.config.FileInput, "file", "f", "", "File containing subscription URLs (one per line), or a sing-box/xray JSON or wg-quick .conf to import")
 	flags.IntVarP(&fc.config.Workers, "workers", "w", 3, "Number of concurrent workers for --file and --all modes")
 
 	cmd.MarkFlagsMutuallyExclusive("id", "url", "all", "file")
@@ -258,6 +264,16 @@ func (fc *FetchCommand) fetchAllSubscriptions() error {
 
 // fetchFromFile handles --file mode with concurrency via pond
 func (fc *FetchCommand) fetchFromFile() error {
+	body, err := os.ReadFile(fc.config.FileInput)
+	if err != nil {
+		return fmt.Errorf("failed to read %q: %w", fc.config.FileInput, err)
+	}
+	switch format := convert.DetectFormat(body); format {
+	case convert.FormatSingboxJSON, convert.FormatXrayJSON, convert.FormatWgQuick:
+		customlog.Printf(customlog.Processing, "%q is a %s config, importing it...\n", fc.config.FileInput, format)
+		return fc.importConfigFile()
+	}
+
 	urls := utils.ParseFileByNewline(fc.config.FileInput)
 	if len(urls) == 0 {
 		return fmt.Errorf("no URLs found in file %q", fc.config.FileInput)
@@ -342,6 +358,34 @@ func (fc *FetchCommand) fetchFromFile() error {
 	return nil
 }
 
+// importConfigFile saves the configs of a sing-box/xray JSON or wg-quick file.
+// Like --url, the configs are not linked to a subscription.
+func (fc *FetchCommand) importConfigFile() error {
+	rawLinks, err := convert.ReadLinksFile(fc.config.FileInput)
+	if err != nil {
+		return fmt.Errorf("failed to read configs from %q: %w", fc.config.FileInput, err)
+	}
+
+	dbConfigs := fc.parseLinks(rawLinks, sql.NullInt64{Valid: false})
+	if len(dbConfigs) == 0 {
+		customlog.Printf(customlog.Warning, "No valid configs found.\n")
+		return nil
+	}
+
+	if err := database.UpsertSubscriptionConfigs(dbConfigs); err != nil {
+		return fmt.Errorf("failed to save configurations to database: %w", err)
+	}
+	customlog.Printf(customlog.Success, "Imported %d configs, saved/updated %d configs in the database.\n", len(rawLinks), len(dbConfigs))
+
+	if fc.config.OutputFile != "" {
+		if err := fc.saveConfigsToFile(dbConfigs); err != nil {
+			return fmt.Errorf("failed to save configurations to file: %w", err)
+		}
+		customlog.Printf(customlog.Success, "%d configs have been written into %q\n", len(dbConfigs), fc.config.OutputFile)
+	}
+	return nil
+}
+
 // doFetch is the shared logic for single-URL fetch (used by fetchSingle)
 func (fc *FetchCommand) doFetch(sub *Subscription, subscriptionID sql.NullInt64) error {
 	rawLinks, err := sub.FetchAll()
@@ -376,10 +420,12 @@ func (fc *FetchCommand) doFetch(sub *Subscription, subscriptionID sql.NullInt64)
 	return nil
 }
 
-// parseLinks accepts the subscriptionID to correctly populate the struct
+// parseLinks accepts the subscriptionID to correctly populate the struct.
+// Links pointing at a server already seen in this batch are dropped.
 func (fc *FetchCommand) parseLinks(rawLinks []string, subID sql.NullInt64) []database.SubscriptionConfig {
 	var dbConfigs []database.SubscriptionConfig
 	now := time.Now().UTC()
+	seen := make(map[string]bool)
 
 	for _, link := range rawLinks {
 		trimmedLink := strings.TrimSpace(link)
@@ -393,6 +439,18 @@ func (fc *FetchCommand) parseLinks(rawLinks []string, subID sql.NullInt64) []dat
 			LastSeenAt:     database.NullTime{Time: now, Valid: true},
 		}
 
+		if fp, err := core.LinkFingerprint(trimmedLink); err == nil {
+			if seen[fp] {
+				continue
+			}
+			seen[fp] = true
+			dbConf.Fingerprint = sql.NullString{String: fp, Valid: true}
+		}
+
+		lint := core.LintLink(fc.core, trimmedLink)
+		dbConf.LintStatus = sql.NullString{String: lint.Status, Valid: true}
+		dbConf.LintCodes = sql.NullString{String: protocol.LintCodes(lint.Diagnostics), Valid: len(lint.Diagnostics) > 0}
+
 		// Parse protocol info with panic recovery — malformed links must not crash the program
 		func() {
 			defer func() {
@@ -38,8 +38,8 @@ Examples:
 		}
 
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-		fmt.Fprintln(w, "ID\tSUB ID\tPROTOCOL\tREMARK\tLAST SEEN")
-		fmt.Fprintln(w, "--\t------\t--------\t------\t---------")
+		fmt.Fprintln(w, "ID\tSUB ID\tPROTOCOL\tREMARK\tLINT\tLAST SEEN")
+		fmt.Fprintln(w, "--\t------\t--------\t------\t----\t---------")
 
 		for _, c := range configs {
 			subID := "N/A"
@@ -57,12 +57,20 @@ Examples:
 				remark = c.Remark.String
 			}
 
+			lint := "N/A"
+			if c.LintStatus.Valid {
+				lint = c.LintStatus.String
+				if c.LintCodes.Valid && c.LintCodes.String != "" {
+					lint += " (" + c.LintCodes.String + ")"
+				}
+			}
+
 			lastSeen := "N/A"
 			if c.LastSeenAt.Valid {
 				lastSeen = c.LastSeenAt.Time.Format("2006-01-02 15:04")
 			}
 
-			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", c.ID, subID, protocol, remark, lastSeen)
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", c.ID, subID, protocol, remark, lint, lastSeen)
 		}
 
 		return w.Flush()
@@ -18,7 +18,8 @@ Examples:
   xray-knife subs show
   xray-knife subs fetch --id 1
   xray-knife subs fetch --all
-  xray-knife subs list-configs --id 1`,
+  xray-knife subs list-configs --id 1
+  xray-knife subs dedup`,
 }
 
 func addSubcommandPalettes() {
@@ -28,6 +29,7 @@ func addSubcommandPalettes() {
 	SubsCmd.AddCommand(RmCmd)
 	SubsCmd.AddCommand(UpdateCmd)
 	SubsCmd.AddCommand(ListConfigsCmd)
+	SubsCmd.AddCommand(DedupCmd)
 }
 
 func init() {
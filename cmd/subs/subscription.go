@@ -5,9 +5,9 @@ import (
 	"io"
 	"log"
 	"net/url"
-	"strings"
 
-	"github.com/lilendian0x00/xray-knife/v10/utils"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/convert"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core"
 	"github.com/lilendian0x00/xray-knife/v10/utils/customlog"
 
 	"github.com/imroc/req/v3"
@@ -32,6 +32,13 @@ func (s *Subscription) FetchAll() ([]string, error) {
 		s.Method = "GET"
 	}
 
+	// Outline dynamic access keys point at an HTTPS document.
+	var keyName string
+	if u.Scheme == convert.SSConfScheme {
+		keyName = u.Fragment
+		u = convert.SSConfFetchURL(u)
+	}
+
 	client := req.C().ImpersonateChrome()
 
 	r := client.R()
@@ -58,36 +65,33 @@ func (s *Subscription) FetchAll() ([]string, error) {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	var links []string
-	decoded, err := utils.Base64Decode(string(body))
-	if err != nil {
-		// Probably It's not base64 encoded!, let's try parsing without decoding
-		customlog.Printf(customlog.Processing, "Couldn't decode the body! let's try parsing without decoding...\n")
-		links = strings.Split(string(body), "\n")
-	} else {
-		// Configs are separated by newline char
-		links = strings.Split(string(decoded), "\n")
+	if format := convert.DetectFormat(body); format != convert.FormatBase64 {
+		customlog.Printf(customlog.Processing, "Subscription body is in %s format\n", format)
 	}
 
-	// Filter out empty and whitespace-only lines
-	var filtered []string
-	for _, l := range links {
-		if trimmed := strings.TrimSpace(l); trimmed != "" {
-			filtered = append(filtered, trimmed)
-		}
+	links, err := convert.ExtractLinks(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract configs from subscription: %w", err)
 	}
 
-	s.ConfigLinks = filtered
-	return filtered, nil
+	links = convert.NameUnnamedLinks(links, keyName)
+
+	s.ConfigLinks = links
+	return links, nil
 }
 
 func (s *Subscription) RemoveDuplicate(verbose bool) {
-	// Remove duplicates using hashmap (hashed keys)
+	// Remove duplicates using hashmap keyed by the config fingerprint,
+	// so the same server under another remark is dropped too
 	allKeys := make(map[string]bool)
 	var list []string
 	for _, item := range s.ConfigLinks {
-		if _, value := allKeys[item]; !value {
-			allKeys[item] = true
+		key, err := core.LinkFingerprint(item)
+		if err != nil {
+			key = item
+		}
+		if _, value := allKeys[key]; !value {
+			allKeys[key] = true
 			list = append(list, item)
 		}
 	}
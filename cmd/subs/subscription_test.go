@@ -160,3 +160,55 @@ func TestRemoveDuplicate_NoDuplicates(t *testing.T) {
 		t.Fatalf("expected 3 links, got %d", len(s.ConfigLinks))
 	}
 }
+
+func TestRemoveDuplicate_SameServerDifferentRemark(t *testing.T) {
+	s := Subscription{
+		ConfigLinks: []string{
+			"vless://uuid@example.com:443?security=tls&type=ws&path=%2Fws&sni=example.com#first",
+			"vless://uuid@example.com:443?sni=example.com&path=%2Fws&type=ws&security=tls#second",
+			"vless://uuid@example.com:443?security=tls&type=ws&path=%2Fother&sni=example.com#third",
+		},
+	}
+	s.RemoveDuplicate(false)
+
+	if len(s.ConfigLinks) != 2 {
+		t.Fatalf("expected 2 unique links, got %d: %v", len(s.ConfigLinks), s.ConfigLinks)
+	}
+	if !strings.HasSuffix(s.ConfigLinks[0], "#first") || !strings.HasSuffix(s.ConfigLinks[1], "#third") {
+		t.Errorf("unexpected links kept: %v", s.ConfigLinks)
+	}
+}
+
+func TestFetchAll_ClashYAML(t *testing.T) {
+	body := `proxies:
+  - name: tj
+    type: trojan
+    server: tj.example.com
+    port: 443
+    password: secret
+  - name: ss
+    type: ss
+    server: ss.example.com
+    port: 8388
+    cipher: aes-128-gcm
+    password: pass
+`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	s := Subscription{Url: server.URL}
+	links, err := s.FetchAll()
+	if err != nil {
+		t.Fatalf("FetchAll error: %v", err)
+	}
+
+	if len(links) != 2 {
+		t.Fatalf("expected 2 links, got %d: %v", len(links), links)
+	}
+	if !strings.HasPrefix(links[0], "trojan://") || !strings.HasPrefix(links[1], "ss://") {
+		t.Errorf("unexpected links: %v", links)
+	}
+}
@@ -0,0 +1,2 @@
+DROP INDEX idx_subscription_configs_fingerprint;
+ALTER TABLE subscription_configs DROP COLUMN fingerprint;
@@ -0,0 +1,2 @@
+ALTER TABLE subscription_configs ADD COLUMN fingerprint TEXT;
+CREATE INDEX idx_subscription_configs_fingerprint ON subscription_configs(fingerprint);
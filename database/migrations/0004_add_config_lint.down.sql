@@ -0,0 +1,2 @@
+ALTER TABLE subscription_configs DROP COLUMN lint_codes;
+ALTER TABLE subscription_configs DROP COLUMN lint_status;
@@ -0,0 +1,2 @@
+ALTER TABLE subscription_configs ADD COLUMN lint_status TEXT;
+ALTER TABLE subscription_configs ADD COLUMN lint_codes TEXT;
@@ -0,0 +1 @@
+ALTER TABLE subscription_configs ADD COLUMN source_ip TEXT;
@@ -0,0 +1 @@
+ALTER TABLE http_test_results ADD COLUMN udp TEXT;
@@ -0,0 +1,6 @@
+ALTER TABLE http_test_results DROP COLUMN samples;
+ALTER TABLE http_test_results DROP COLUMN delay_min_ms;
+ALTER TABLE http_test_results DROP COLUMN delay_p50_ms;
+ALTER TABLE http_test_results DROP COLUMN delay_p95_ms;
+ALTER TABLE http_test_results DROP COLUMN jitter_ms;
+ALTER TABLE http_test_results DROP COLUMN loss_pct;
@@ -0,0 +1,6 @@
+ALTER TABLE http_test_results ADD COLUMN samples INTEGER DEFAULT 0;
+ALTER TABLE http_test_results ADD COLUMN delay_min_ms INTEGER DEFAULT 0;
+ALTER TABLE http_test_results ADD COLUMN delay_p50_ms INTEGER DEFAULT 0;
+ALTER TABLE http_test_results ADD COLUMN delay_p95_ms INTEGER DEFAULT 0;
+ALTER TABLE http_test_results ADD COLUMN jitter_ms INTEGER DEFAULT 0;
+ALTER TABLE http_test_results ADD COLUMN loss_pct REAL DEFAULT 0;
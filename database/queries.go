@@ -27,6 +27,10 @@ type SubscriptionConfig struct {
 	ConfigLink     string         `db:"config_link"`
 	Protocol       sql.NullString `db:"protocol"`
 	Remark         sql.NullString `db:"remark"`
+	Fingerprint    sql.NullString `db:"fingerprint"`
+	LintStatus     sql.NullString `db:"lint_status"` // ok, warning or error
+	LintCodes      sql.NullString `db:"lint_codes"`  // comma separated lint codes
+	SourceIP       sql.NullString `db:"source_ip"`   // edge IP a clean-IP variant was made for
 	AddedAt        time.Time      `db:"added_at"`
 	LastSeenAt     NullTime       `db:"last_seen_at"`
 }
@@ -52,6 +56,14 @@ type HttpTestResult struct {
 	IPLocation    sql.NullString `db:"ip_location"`
 	TTFBMs        int64          `db:"ttfb_ms"`
 	ConnectTimeMs int64          `db:"connect_time_ms"`
+	Core          sql.NullString `db:"core"`    // xray or singbox
+	UDP           sql.NullString `db:"udp"`     // ok, fail or unsupported
+	Samples       int            `db:"samples"` // requests sent in sampling mode, 0 otherwise
+	DelayMinMs    int64          `db:"delay_min_ms"`
+	DelayP50Ms    int64          `db:"delay_p50_ms"`
+	DelayP95Ms    int64          `db:"delay_p95_ms"`
+	JitterMs      int64          `db:"jitter_ms"`
+	LossPct       float64        `db:"loss_pct"`
 }
 
 type CfScanResult struct {
@@ -175,7 +187,7 @@ func UpdateSubscription(id int64, urlVal, remark, userAgent *string, enabled *bo
 }
 
 func ListSubscriptionConfigs(subID int64, protocol string, limit int) ([]SubscriptionConfig, error) {
-	query := `SELECT id, subscription_id, config_link, protocol, remark, added_at, last_seen_at FROM subscription_configs WHERE 1=1`
+	query := `SELECT id, subscription_id, config_link, protocol, remark, fingerprint, lint_status, lint_codes, source_ip, added_at, last_seen_at FROM subscription_configs WHERE 1=1`
 	args := []interface{}{}
 
 	if subID > 0 {
@@ -221,6 +233,10 @@ func CountSubscriptionConfigs(subID int64) (int, error) {
 
 // Subscription Configs
 
+// UpsertSubscriptionConfigs inserts configs or refreshes the rows they already have.
+// A config whose fingerprint is already stored under a different link is treated as
+// the same server: only the existing row's last_seen_at is updated, and it keeps the
+// subscription it was first seen in.
 func UpsertSubscriptionConfigs(configs []SubscriptionConfig) error {
 	tx, err := DB.BeginTxx(context.Background(), nil)
 	if err != nil {
@@ -229,20 +245,45 @@ func UpsertSubscriptionConfigs(configs []SubscriptionConfig) error {
 	defer tx.Rollback()
 
 	stmt, err := tx.PrepareNamedContext(context.Background(), `
-		INSERT INTO subscription_configs (subscription_id, config_link, protocol, remark, last_seen_at) 
-		VALUES (:subscription_id, :config_link, :protocol, :remark, :last_seen_at)
+		INSERT INTO subscription_configs (subscription_id, config_link, protocol, remark, fingerprint, lint_status, lint_codes, source_ip, last_seen_at) 
+		VALUES (:subscription_id, :config_link, :protocol, :remark, :fingerprint, :lint_status, :lint_codes, :source_ip, :last_seen_at)
 		ON CONFLICT(config_link) DO UPDATE SET 
 			last_seen_at = excluded.last_seen_at,
 			subscription_id = COALESCE(excluded.subscription_id, subscription_configs.subscription_id),
 			remark = excluded.remark,
-			protocol = excluded.protocol
+			protocol = excluded.protocol,
+			fingerprint = COALESCE(excluded.fingerprint, subscription_configs.fingerprint),
+			lint_status = excluded.lint_status,
+			lint_codes = excluded.lint_codes,
+			source_ip = COALESCE(excluded.source_ip, subscription_configs.source_ip)
 	`)
 	if err != nil {
 		return fmt.Errorf("could not prepare named statement: %w", err)
 	}
 	defer stmt.Close()
 
+	touchStmt, err := tx.PrepareNamedContext(context.Background(), `
+		UPDATE subscription_configs SET 
+			last_seen_at = :last_seen_at,
+			subscription_id = COALESCE(subscription_id, :subscription_id)
+		WHERE fingerprint = :fingerprint AND config_link != :config_link
+			AND NOT EXISTS (SELECT 1 FROM subscription_configs WHERE config_link = :config_link)
+	`)
+	if err != nil {
+		return fmt.Errorf("could not prepare named statement: %w", err)
+	}
+	defer touchStmt.Close()
+
 	for _, config := range configs {
+		if config.Fingerprint.Valid {
+			res, err := touchStmt.ExecContext(context.Background(), config)
+			if err != nil {
+				return fmt.Errorf("failed to update duplicates of config %s: %w", config.ConfigLink, err)
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				continue
+			}
+		}
 		if _, err := stmt.ExecContext(context.Background(), config); err != nil {
 			return fmt.Errorf("failed to execute upsert for config %s: %w", config.ConfigLink, err)
 		}
@@ -251,6 +292,52 @@ func UpsertSubscriptionConfigs(configs []SubscriptionConfig) error {
 	return tx.Commit()
 }
 
+// SetSubscriptionConfigFingerprints stores the fingerprints of existing configs, keyed by config ID.
+func SetSubscriptionConfigFingerprints(fingerprints map[int64]string) error {
+	tx, err := DB.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PreparexContext(context.Background(), `UPDATE subscription_configs SET fingerprint = ? WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("could not prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for id, fp := range fingerprints {
+		if _, err := stmt.ExecContext(context.Background(), fp, id); err != nil {
+			return fmt.Errorf("failed to set fingerprint of config %d: %w", id, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteSubscriptionConfigs removes the configs with the given IDs.
+func DeleteSubscriptionConfigs(ids []int64) error {
+	tx, err := DB.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return fmt.Errorf("could not begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PreparexContext(context.Background(), `DELETE FROM subscription_configs WHERE id = ?`)
+	if err != nil {
+		return fmt.Errorf("could not prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, id := range ids {
+		if _, err := stmt.ExecContext(context.Background(), id); err != nil {
+			return fmt.Errorf("failed to delete config %d: %w", id, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
 func GetConfigsFromDB(subID int64, protocol string, limit int) ([]string, error) {
 	query := `SELECT config_link FROM subscription_configs WHERE 1=1`
 	args := []interface{}{}
@@ -318,8 +405,8 @@ func InsertHttpTestResultsBatch(runID int64, results []HttpTestResult) error {
 	defer tx.Rollback()
 
 	stmt, err := tx.PrepareNamedContext(context.Background(), `
-        INSERT INTO http_test_results (run_id, config_link, status, reason, delay_ms, download_mbps, upload_mbps, ip_address, ip_location, ttfb_ms, connect_time_ms)
-        VALUES (:run_id, :config_link, :status, :reason, :delay_ms, :download_mbps, :upload_mbps, :ip_address, :ip_location, :ttfb_ms, :connect_time_ms)
+        INSERT INTO http_test_results (run_id, config_link, status, reason, delay_ms, download_mbps, upload_mbps, ip_address, ip_location, ttfb_ms, connect_time_ms, core, udp, samples, delay_min_ms, delay_p50_ms, delay_p95_ms, jitter_ms, loss_pct)
+        VALUES (:run_id, :config_link, :status, :reason, :delay_ms, :download_mbps, :upload_mbps, :ip_address, :ip_location, :ttfb_ms, :connect_time_ms, :core, :udp, :samples, :delay_min_ms, :delay_p50_ms, :delay_p95_ms, :jitter_ms, :loss_pct)
     `)
 	if err != nil {
 		return fmt.Errorf("could not prepare named statement for http_test_results: %w", err)
@@ -355,6 +442,35 @@ func GetHttpTestHistory(limit int) ([]HttpTestResult, error) {
 	return results, nil
 }
 
+// GetLatestHttpTestResults returns the newest test result of each of links
+// that has one, keyed by link.
+func GetLatestHttpTestResults(links []string) (map[string]HttpTestResult, error) {
+	stmt, err := DB.PreparexContext(context.Background(), `
+        SELECT r.* FROM http_test_results r
+        JOIN http_test_runs t ON t.id = r.run_id
+        WHERE r.config_link = ?
+        ORDER BY t.start_time DESC, r.id DESC
+        LIMIT 1
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("could not prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	results := make(map[string]HttpTestResult, len(links))
+	for _, link := range links {
+		var r HttpTestResult
+		if err := stmt.GetContext(context.Background(), &r, link); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return nil, fmt.Errorf("could not get test result of %s: %w", link, err)
+		}
+		results[link] = r
+	}
+	return results, nil
+}
+
 // CF Scanner //
 
 func UpsertCfScanResultsBatch(results []CfScanResult) error {
@@ -413,6 +529,7 @@ func GetCfScanHistory(limit int) ([]CfScanResult, error) {
 		SELECT * FROM cf_scan_results
 		ORDER BY
 			CASE WHEN error IS NULL THEN 0 ELSE 1 END,
+			latency_ms IS NULL,
 			latency_ms ASC,
 			download_mbps DESC
 		LIMIT ?
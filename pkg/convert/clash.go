@@ -0,0 +1,551 @@
+package convert
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ClashProxy is a single entry of a Clash/Mihomo `proxies:` list. The schema
+// differs per proxy type, so entries are kept as generic maps.
+type ClashProxy map[string]any
+
+type clashConfig struct {
+	Proxies []ClashProxy `yaml:"proxies"`
+}
+
+// ClashToLinks parses a Clash/Mihomo YAML document and converts every proxy
+// it can into a share link. Entries of unsupported types are skipped; an
+// error is only returned when nothing could be converted.
+func ClashToLinks(body []byte) ([]string, error) {
+	var cfg clashConfig
+	if err := yaml.Unmarshal(body, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid clash yaml: %w", err)
+	}
+
+	var links []string
+	var errs []error
+	for i, p := range cfg.Proxies {
+		link, err := ClashProxyToLink(p)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("proxy %d (%s): %w", i, p.str("name"), err))
+			continue
+		}
+		links = append(links, link)
+	}
+
+	if len(links) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return links, nil
+}
+
+// ClashProxyToLink converts one Clash proxy entry into its share link.
+func ClashProxyToLink(p ClashProxy) (string, error) {
+	// WireGuard proxies may list their servers under `peers` only
+	if (p.str("server") == "" || p.str("port") == "") && len(p.subList("peers")) == 0 {
+		return "", errors.New("missing server or port")
+	}
+
+	switch p.str("type") {
+	case "vmess":
+		return clashVmess(p)
+	case "vless":
+		return clashVless(p)
+	case "trojan":
+		return clashTrojan(p)
+	case "ss":
+		return clashShadowsocks(p)
+	case "hysteria2", "hy2":
+		return clashHysteria2(p)
+	case "tuic":
+		return clashTuic(p)
+	case "wireguard":
+		return clashWireguard(p)
+	case "socks5":
+		return clashSocks(p)
+	default:
+		return "", fmt.Errorf("unsupported proxy type %q", p.str("type"))
+	}
+}
+
+func clashVmess(p ClashProxy) (string, error) {
+	network := p.str("network")
+	if network == "" {
+		network = "tcp"
+	}
+	host, path := p.transportHostPath(network)
+
+	v := map[string]string{
+		"v":    "2",
+		"ps":   p.str("name"),
+		"add":  p.str("server"),
+		"port": p.str("port"),
+		"id":   p.str("uuid"),
+		"aid":  p.str("alterId"),
+		"scy":  p.str("cipher"),
+		"net":  network,
+		"type": "none",
+		"host": host,
+		"path": path,
+		"sni":  p.serverName(),
+		"alpn": strings.Join(p.strList("alpn"), ","),
+		"fp":   p.str("client-fingerprint"),
+	}
+	if v["aid"] == "" {
+		v["aid"] = "0"
+	}
+	if v["scy"] == "" {
+		v["scy"] = "auto"
+	}
+	if network == "grpc" {
+		v["path"] = p.sub("grpc-opts").str("grpc-service-name")
+	}
+	if network == "http" {
+		v["net"] = "tcp"
+		v["type"] = "http"
+	}
+	if p.bool("tls") {
+		v["tls"] = "tls"
+	}
+	if p.bool("skip-cert-verify") {
+		v["allowinsecure"] = "1"
+	}
+
+	// Drop empty values so the JSON looks like what other clients export.
+	for k, val := range v {
+		if val == "" {
+			delete(v, k)
+		}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return protocol.VmessIdentifier + "://" + base64.StdEncoding.EncodeToString(b), nil
+}
+
+func clashVless(p ClashProxy) (string, error) {
+	params := url.Values{}
+	params.Set("encryption", "none")
+	addParam(params, "flow", p.str("flow"))
+
+	security := "none"
+	if p.bool("tls") {
+		security = "tls"
+	}
+	if reality := p.sub("reality-opts"); len(reality) > 0 {
+		security = "reality"
+		addParam(params, "pbk", reality.str("public-key"))
+		addParam(params, "sid", reality.str("short-id"))
+	}
+	params.Set("security", security)
+	if security != "none" {
+		p.addTLSParams(params)
+	}
+	p.addTransportParams(params)
+	p.addMuxParams(params)
+
+	return buildLink(protocol.VlessIdentifier, url.User(p.str("uuid")), p, params), nil
+}
+
+func clashTrojan(p ClashProxy) (string, error) {
+	params := url.Values{}
+	security := "tls"
+	if reality := p.sub("reality-opts"); len(reality) > 0 {
+		security = "reality"
+		addParam(params, "pbk", reality.str("public-key"))
+		addParam(params, "sid", reality.str("short-id"))
+	}
+	params.Set("security", security)
+	p.addTLSParams(params)
+	p.addTransportParams(params)
+	p.addMuxParams(params)
+
+	return buildLink(protocol.TrojanIdentifier, url.User(p.str("password")), p, params), nil
+}
+
+func clashShadowsocks(p ClashProxy) (string, error) {
+	cipher, password := p.str("cipher"), p.str("password")
+	if cipher == "" {
+		return "", errors.New("missing cipher")
+	}
+	creds := base64.RawURLEncoding.EncodeToString([]byte(cipher + ":" + password))
+	link := fmt.Sprintf("%s://%s@%s", protocol.ShadowsocksIdentifier, creds, net.JoinHostPort(p.str("server"), p.str("port")))
+
+	query := url.Values{}
+	if plugin := p.str("plugin"); plugin != "" {
+		opts := p.sub("plugin-opts")
+		var parts []string
+		switch plugin {
+		case "obfs", "obfs-local", "simple-obfs":
+			plugin = protocol.PluginObfsLocal
+			parts = appendOpt(parts, "obfs", opts.str("mode"))
+			parts = appendOpt(parts, "obfs-host", opts.str("host"))
+		case protocol.PluginV2rayPlugin:
+			if opts.bool("tls") {
+				parts = append(parts, "tls")
+			}
+			parts = appendOpt(parts, "mode", opts.str("mode"))
+			parts = appendOpt(parts, "host", opts.str("host"))
+			parts = appendOpt(parts, "path", opts.str("path"))
+			if _, ok := opts["mux"]; ok {
+				mux := "0"
+				if opts.bool("mux") {
+					mux = "1"
+				}
+				parts = append(parts, "mux="+mux)
+			}
+		case protocol.PluginShadowTLS:
+			parts = appendOpt(parts, "host", opts.str("host"))
+			parts = appendOpt(parts, "password", opts.str("password"))
+			parts = appendOpt(parts, "version", opts.str("version"))
+		default:
+			return "", fmt.Errorf("unsupported shadowsocks plugin %q", plugin)
+		}
+		query.Set("plugin", protocol.JoinSIP003Plugin(plugin, strings.Join(parts, ";")))
+	}
+	p.addMuxParams(query)
+	if len(query) > 0 {
+		link += "/?" + query.Encode()
+	}
+
+	if name := p.str("name"); name != "" {
+		link += "#" + url.PathEscape(name)
+	}
+	return link, nil
+}
+
+func clashHysteria2(p ClashProxy) (string, error) {
+	params := url.Values{}
+	addParam(params, "sni", p.serverName())
+	addParam(params, "alpn", strings.Join(p.strList("alpn"), ","))
+	addParam(params, "obfs", p.str("obfs"))
+	addParam(params, "obfs-password", p.str("obfs-password"))
+	addParam(params, "pinSHA256", p.str("fingerprint"))
+	addParam(params, "mport", p.str("ports"))
+	addParam(params, "hop_interval", p.str("hop-interval"))
+	addParam(params, "up", p.str("up"))
+	addParam(params, "down", p.str("down"))
+	if p.bool("skip-cert-verify") {
+		params.Set("insecure", "1")
+	}
+
+	return buildLink(protocol.Hysteria2Identifier, url.User(p.str("password")), p, params), nil
+}
+
+func clashTuic(p ClashProxy) (string, error) {
+	if p.str("uuid") == "" {
+		return "", errors.New("only TUIC v5 (uuid + password) is supported")
+	}
+	params := url.Values{}
+	addParam(params, "congestion_control", p.str("congestion-controller"))
+	addParam(params, "udp_relay_mode", p.str("udp-relay-mode"))
+	addParam(params, "alpn", strings.Join(p.strList("alpn"), ","))
+	addParam(params, "sni", p.serverName())
+	if p.bool("disable-sni") {
+		params.Set("disable_sni", "1")
+	}
+	if p.bool("skip-cert-verify") {
+		params.Set("allow_insecure", "1")
+	}
+
+	return buildLink(protocol.TuicIdentifier, url.UserPassword(p.str("uuid"), p.str("password")), p, params), nil
+}
+
+func clashWireguard(p ClashProxy) (string, error) {
+	// Mihomo allows the peer settings either inline or in a `peers` list.
+	peers := p.subList("peers")
+	if len(peers) == 0 {
+		peers = []ClashProxy{p}
+	} else if peers[0].str("server") == "" {
+		peers[0]["server"], peers[0]["port"] = p["server"], p["port"]
+	}
+
+	var addresses []string
+	for _, key := range []string{"ip", "ipv6"} {
+		if ip := p.str(key); ip != "" {
+			if !strings.Contains(ip, "/") {
+				if strings.Contains(ip, ":") {
+					ip += "/128"
+				} else {
+					ip += "/32"
+				}
+			}
+			addresses = append(addresses, ip)
+		}
+	}
+
+	first := peers[0]
+	params := url.Values{}
+	addParam(params, "publickey", first.str("public-key"))
+	addParam(params, "presharedkey", firstNonEmpty(first.str("pre-shared-key"), p.str("pre-shared-key")))
+	addParam(params, "address", strings.Join(addresses, ","))
+	addParam(params, "allowedips", clashAllowedIPs(first, p))
+	addParam(params, "reserved", strings.Join(firstNonEmptyList(first.strList("reserved"), p.strList("reserved")), ","))
+	addParam(params, "keepalive", p.str("persistent-keepalive"))
+	addParam(params, "workers", p.str("workers"))
+	addParam(params, "mtu", p.str("mtu"))
+	for _, peer := range peers[1:] {
+		if peer.str("server") == "" {
+			continue
+		}
+		ka, _ := strconv.ParseInt(p.str("persistent-keepalive"), 10, 32)
+		params.Add(protocol.WireguardPeerParam, protocol.WireguardPeer{
+			PublicKey:    peer.str("public-key"),
+			PreSharedKey: peer.str("pre-shared-key"),
+			Endpoint:     net.JoinHostPort(peer.str("server"), peer.str("port")),
+			AllowedIPs:   clashAllowedIPs(peer, p),
+			Reserved:     strings.Join(firstNonEmptyList(peer.strList("reserved"), p.strList("reserved")), ","),
+			KeepAlive:    int32(ka),
+		}.String())
+	}
+
+	endpoint := ClashProxy{"server": first["server"], "port": first["port"], "name": p["name"]}
+	return buildLink(protocol.WireguardIdentifier, url.User(p.str("private-key")), endpoint, params), nil
+}
+
+// clashAllowedIPs returns the peer's allowed IPs unless they route everything,
+// which is what the cores do without them.
+func clashAllowedIPs(peer, p ClashProxy) string {
+	allowed := strings.Join(firstNonEmptyList(peer.strList("allowed-ips"), p.strList("allowed-ips")), ",")
+	if routesEverything(allowed) {
+		return ""
+	}
+	return allowed
+}
+
+func clashSocks(p ClashProxy) (string, error) {
+	link := protocol.SocksIdentifier + "://"
+	if user := p.str("username"); user != "" {
+		link += base64.RawURLEncoding.EncodeToString([]byte(user+":"+p.str("password"))) + "@"
+	}
+	link += net.JoinHostPort(p.str("server"), p.str("port"))
+	if name := p.str("name"); name != "" {
+		link += "#" + url.PathEscape(name)
+	}
+	return link, nil
+}
+
+// buildLink assembles scheme://user@server:port?params#name.
+func buildLink(scheme string, user *url.Userinfo, p ClashProxy, params url.Values) string {
+	u := url.URL{
+		Scheme:   scheme,
+		User:     user,
+		Host:     net.JoinHostPort(p.str("server"), p.str("port")),
+		RawQuery: params.Encode(),
+		Fragment: p.str("name"),
+	}
+	return u.String()
+}
+
+// addMuxParams maps mihomo's smux block onto the mux link parameters.
+func (p ClashProxy) addMuxParams(params url.Values) {
+	smux := p.sub("smux")
+	if len(smux) == 0 {
+		return
+	}
+	mux := &protocol.MuxOptions{
+		Enabled:     smux.bool("enabled"),
+		Concurrency: atoi(smux.str("max-streams")),
+		Padding:     smux.bool("padding"),
+	}
+	switch proto := smux.str("protocol"); proto {
+	case "smux", "yamux", "h2mux":
+		mux.Protocol = proto
+	}
+	mux.AddParams(params)
+}
+
+func (p ClashProxy) serverName() string {
+	return firstNonEmpty(p.str("servername"), p.str("sni"))
+}
+
+// addTLSParams adds the share-link TLS parameters (sni, alpn, fp, allowInsecure).
+func (p ClashProxy) addTLSParams(params url.Values) {
+	addParam(params, "sni", p.serverName())
+	addParam(params, "alpn", strings.Join(p.strList("alpn"), ","))
+	addParam(params, "fp", p.str("client-fingerprint"))
+	if p.bool("skip-cert-verify") {
+		params.Set("allowInsecure", "1")
+	}
+}
+
+// addTransportParams adds the share-link transport parameters (type, host, path, ...).
+func (p ClashProxy) addTransportParams(params url.Values) {
+	network := p.str("network")
+	switch network {
+	case "", "tcp":
+		params.Set("type", "tcp")
+	case "http":
+		params.Set("type", "tcp")
+		params.Set("headerType", "http")
+	case "grpc":
+		params.Set("type", "grpc")
+		addParam(params, "serviceName", p.sub("grpc-opts").str("grpc-service-name"))
+	case "xhttp":
+		params.Set("type", "xhttp")
+		addParam(params, "mode", p.sub("xhttp-opts").str("mode"))
+	default:
+		params.Set("type", network)
+	}
+
+	host, path := p.transportHostPath(network)
+	addParam(params, "host", host)
+	addParam(params, "path", path)
+}
+
+// transportHostPath extracts the Host header and path from the transport options.
+func (p ClashProxy) transportHostPath(network string) (host, path string) {
+	switch network {
+	case "ws":
+		opts := p.sub("ws-opts")
+		host = opts.sub("headers").str("Host")
+		if host == "" {
+			host = opts.sub("headers").str("host")
+		}
+		path = opts.str("path")
+	case "httpupgrade":
+		opts := p.sub("http-upgrade-opts")
+		if len(opts) == 0 {
+			opts = p.sub("ws-opts")
+		}
+		host = opts.sub("headers").str("Host")
+		path = opts.str("path")
+	case "xhttp":
+		opts := p.sub("xhttp-opts")
+		host = opts.str("host")
+		path = opts.str("path")
+	case "h2":
+		opts := p.sub("h2-opts")
+		host = strings.Join(opts.strList("host"), ",")
+		path = opts.str("path")
+	case "http":
+		opts := p.sub("http-opts")
+		host = strings.Join(opts.sub("headers").strList("Host"), ",")
+		path = strings.Join(opts.strList("path"), ",")
+	}
+	return host, path
+}
+
+func (p ClashProxy) str(key string) string {
+	switch v := p[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		// e.g. `reserved: [1, 2, 3]` or a single-element host list
+		return strings.Join(ClashProxy{"v": v}.strList("v"), ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (p ClashProxy) bool(key string) bool {
+	switch v := p[key].(type) {
+	case bool:
+		return v
+	case string:
+		b, _ := strconv.ParseBool(v)
+		return b
+	case int:
+		return v != 0
+	default:
+		return false
+	}
+}
+
+func (p ClashProxy) strList(key string) []string {
+	switch v := p[key].(type) {
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s := (ClashProxy{"v": item}).str("v"); s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	case nil:
+		return nil
+	default:
+		if s := p.str(key); s != "" {
+			return strings.Split(s, ",")
+		}
+		return nil
+	}
+}
+
+func (p ClashProxy) sub(key string) ClashProxy {
+	return asClashProxy(p[key])
+}
+
+func (p ClashProxy) subList(key string) []ClashProxy {
+	items, _ := p[key].([]any)
+	var out []ClashProxy
+	for _, item := range items {
+		if m := asClashProxy(item); len(m) > 0 {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// asClashProxy accepts nested maps in either form; yaml.v3 decodes them
+// into the parent's map type, encoding/json into map[string]any.
+func asClashProxy(v any) ClashProxy {
+	switch m := v.(type) {
+	case ClashProxy:
+		return m
+	case map[string]any:
+		return m
+	default:
+		return ClashProxy{}
+	}
+}
+
+func addParam(params url.Values, key, value string) {
+	if value != "" {
+		params.Set(key, value)
+	}
+}
+
+func appendOpt(parts []string, key, value string) []string {
+	if value == "" {
+		return parts
+	}
+	return append(parts, key+"="+value)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func firstNonEmptyList(lists ...[]string) []string {
+	for _, l := range lists {
+		if len(l) > 0 {
+			return l
+		}
+	}
+	return nil
+}
@@ -0,0 +1,533 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+	"github.com/lilendian0x00/xray-knife/v10/utils"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Names of the proxy groups written by LinksToClash.
+const (
+	ClashSelectGroup  = "PROXY"
+	ClashURLTestGroup = "auto"
+)
+
+// clashTestURL is the health-check URL of the generated url-test group.
+const clashTestURL = "https://www.gstatic.com/generate_204"
+
+// LinksToClash renders share links as a ready-to-use Clash/Mihomo config: a
+// mixed inbound, the proxies, a url-test group and a selector that routes
+// everything. Links that can't be expressed are returned in skipped.
+func LinksToClash(links []string) (out []byte, skipped []error, err error) {
+	var proxies []ClashProxy
+	var names []string
+	taken := make(map[string]bool)
+	for _, link := range links {
+		p, err := LinkToClashProxy(link)
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("%s: %w", link, err))
+			continue
+		}
+		name := UniqueName(p.str("name"), len(proxies)+1, taken)
+		p["name"] = name
+		proxies = append(proxies, p)
+		names = append(names, name)
+	}
+	if len(proxies) == 0 {
+		return nil, skipped, errors.New("no config could be converted to a clash proxy")
+	}
+
+	cfg := map[string]any{
+		"mixed-port": 7890,
+		"allow-lan":  false,
+		"mode":       "rule",
+		"log-level":  "info",
+		"proxies":    proxies,
+		"proxy-groups": []map[string]any{
+			{"name": ClashSelectGroup, "type": "select", "proxies": append([]string{ClashURLTestGroup}, names...)},
+			{"name": ClashURLTestGroup, "type": "url-test", "proxies": names, "url": clashTestURL, "interval": 300},
+		},
+		"rules": []string{"MATCH," + ClashSelectGroup},
+	}
+	out, err = yaml.Marshal(cfg)
+	return out, skipped, err
+}
+
+// UniqueName returns name, or "proxy-<index>" when it is empty, suffixed with
+// a counter if it was already taken. The result is recorded in taken.
+func UniqueName(name string, index int, taken map[string]bool) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = fmt.Sprintf("proxy-%d", index)
+	}
+	unique := name
+	for n := 2; taken[unique]; n++ {
+		unique = fmt.Sprintf("%s %d", name, n)
+	}
+	taken[unique] = true
+	return unique
+}
+
+// LinkToClashProxy converts a share link into a Clash proxy entry. It is the
+// inverse of ClashProxyToLink.
+func LinkToClashProxy(link string) (ClashProxy, error) {
+	link = strings.TrimSpace(link)
+	scheme, _, ok := strings.Cut(link, "://")
+	if !ok {
+		return nil, errors.New("not a share link")
+	}
+
+	switch scheme {
+	case protocol.VmessIdentifier:
+		return vmessToClash(link)
+	case protocol.VlessIdentifier, protocol.TrojanIdentifier:
+		return vlessTrojanToClash(link)
+	case protocol.ShadowsocksIdentifier:
+		return shadowsocksToClash(link)
+	case protocol.Hysteria2Identifier, "hy2":
+		return hysteria2ToClash(link)
+	case protocol.TuicIdentifier:
+		return tuicToClash(link)
+	case protocol.WireguardIdentifier:
+		return wireguardToClash(link)
+	case protocol.SocksIdentifier:
+		return socksToClash(link)
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q", scheme)
+	}
+}
+
+func vmessToClash(link string) (ClashProxy, error) {
+	decoded, err := utils.Base64Decode(strings.TrimPrefix(link, protocol.VmessIdentifier+"://"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid vmess link: %w", err)
+	}
+	var v ClashProxy
+	if err := json.Unmarshal(decoded, &v); err != nil {
+		return nil, fmt.Errorf("invalid vmess json: %w", err)
+	}
+
+	p := ClashProxy{
+		"type":    "vmess",
+		"name":    v.str("ps"),
+		"server":  v.str("add"),
+		"uuid":    v.str("id"),
+		"alterId": atoi(firstNonEmpty(v.str("aid"), "0")),
+		"cipher":  firstNonEmpty(v.str("scy"), "auto"),
+		"udp":     true,
+	}
+	if err := setPort(p, v.str("port")); err != nil {
+		return nil, err
+	}
+	if v.str("tls") == "tls" {
+		p["tls"] = true
+		setNonEmpty(p, "servername", v.str("sni"))
+		setNonEmpty(p, "client-fingerprint", v.str("fp"))
+		setList(p, "alpn", v.str("alpn"))
+		if v.bool("allowinsecure") || v.str("allowinsecure") == "1" {
+			p["skip-cert-verify"] = true
+		}
+	}
+	network := v.str("net")
+	if network == "tcp" && v.str("type") == "http" {
+		network = "http"
+	}
+	if err := setClashTransport(p, network, v.str("host"), v.str("path"), v.str("path"), ""); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func vlessTrojanToClash(link string) (ClashProxy, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+
+	p := ClashProxy{"type": u.Scheme, "name": u.Fragment, "server": u.Hostname(), "udp": true}
+	if err := setPort(p, u.Port()); err != nil {
+		return nil, err
+	}
+	if u.Scheme == protocol.VlessIdentifier {
+		p["uuid"] = u.User.Username()
+		setNonEmpty(p, "flow", q.Get("flow"))
+	} else {
+		p["password"] = u.User.Username()
+	}
+
+	security := q.Get("security")
+	if u.Scheme == protocol.TrojanIdentifier && security == "" {
+		security = "tls"
+	}
+	if security == "tls" || security == "reality" {
+		if u.Scheme == protocol.VlessIdentifier {
+			p["tls"] = true
+		}
+		sniKey := "servername"
+		if u.Scheme == protocol.TrojanIdentifier {
+			sniKey = "sni"
+		}
+		setNonEmpty(p, sniKey, q.Get("sni"))
+		setNonEmpty(p, "client-fingerprint", q.Get("fp"))
+		setList(p, "alpn", q.Get("alpn"))
+		if isTrue(firstNonEmpty(q.Get("allowInsecure"), q.Get("insecure"))) {
+			p["skip-cert-verify"] = true
+		}
+	}
+	if security == "reality" {
+		p["reality-opts"] = ClashProxy{"public-key": q.Get("pbk"), "short-id": q.Get("sid")}
+	}
+
+	network := q.Get("type")
+	if (network == "tcp" || network == "raw") && q.Get("headerType") == "http" {
+		network = "http"
+	}
+	if err := setClashTransport(p, network, q.Get("host"), q.Get("path"), q.Get("serviceName"), q.Get("mode")); err != nil {
+		return nil, err
+	}
+	setClashSmux(p, q)
+	return p, nil
+}
+
+func shadowsocksToClash(link string) (ClashProxy, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+
+	// SIP002 allows base64(method:password) or a percent-encoded method:password.
+	method, password := u.User.Username(), ""
+	if pw, ok := u.User.Password(); ok {
+		password = pw
+	} else if decoded, err := utils.Base64Decode(method); err == nil {
+		method, password, _ = strings.Cut(string(decoded), ":")
+	}
+
+	p := ClashProxy{
+		"type":     "ss",
+		"name":     u.Fragment,
+		"server":   u.Hostname(),
+		"cipher":   method,
+		"password": password,
+		"udp":      true,
+	}
+	if err := setPort(p, u.Port()); err != nil {
+		return nil, err
+	}
+	if plugin := u.Query().Get("plugin"); plugin != "" {
+		name, opts := protocol.SplitSIP003Plugin(plugin)
+		clashName, pluginOpts := sip003ToClashPlugin(name, opts)
+		if clashName == protocol.PluginObfsLocal {
+			clashName = "obfs"
+		}
+		p["plugin"] = clashName
+		p["plugin-opts"] = pluginOpts
+	}
+	setClashSmux(p, u.Query())
+	return p, nil
+}
+
+func hysteria2ToClash(link string) (ClashProxy, error) {
+	link, ports := cutPortList(link)
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+
+	password := u.User.Username()
+	if pw, ok := u.User.Password(); ok {
+		password += ":" + pw
+	}
+	p := ClashProxy{"type": "hysteria2", "name": u.Fragment, "server": u.Hostname(), "password": password}
+	if err := setPort(p, u.Port()); err != nil {
+		return nil, err
+	}
+	setNonEmpty(p, "ports", firstNonEmpty(q.Get("mport"), ports))
+	setNonEmpty(p, "hop-interval", q.Get("hop_interval"))
+	setNonEmpty(p, "sni", q.Get("sni"))
+	setList(p, "alpn", q.Get("alpn"))
+	setNonEmpty(p, "obfs", q.Get("obfs"))
+	setNonEmpty(p, "obfs-password", q.Get("obfs-password"))
+	setNonEmpty(p, "fingerprint", q.Get("pinSHA256"))
+	setNonEmpty(p, "up", q.Get("up"))
+	setNonEmpty(p, "down", q.Get("down"))
+	if isTrue(q.Get("insecure")) {
+		p["skip-cert-verify"] = true
+	}
+	return p, nil
+}
+
+func tuicToClash(link string) (ClashProxy, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+
+	password, _ := u.User.Password()
+	p := ClashProxy{
+		"type":     "tuic",
+		"name":     u.Fragment,
+		"server":   u.Hostname(),
+		"uuid":     u.User.Username(),
+		"password": password,
+	}
+	if err := setPort(p, u.Port()); err != nil {
+		return nil, err
+	}
+	setNonEmpty(p, "congestion-controller", firstNonEmpty(q.Get("congestion_control"), q.Get("congestion_controller")))
+	setNonEmpty(p, "udp-relay-mode", q.Get("udp_relay_mode"))
+	setNonEmpty(p, "sni", q.Get("sni"))
+	setList(p, "alpn", q.Get("alpn"))
+	if isTrue(q.Get("disable_sni")) {
+		p["disable-sni"] = true
+	}
+	if isTrue(firstNonEmpty(q.Get("allow_insecure"), q.Get("insecure"))) {
+		p["skip-cert-verify"] = true
+	}
+	return p, nil
+}
+
+func wireguardToClash(link string) (ClashProxy, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+
+	p := ClashProxy{
+		"type":        "wireguard",
+		"name":        u.Fragment,
+		"server":      u.Hostname(),
+		"private-key": u.User.Username(),
+		"public-key":  q.Get("publickey"),
+		"udp":         true,
+	}
+	if err := setPort(p, u.Port()); err != nil {
+		return nil, err
+	}
+	v4, v6 := splitAddressFamilies(strings.Split(q.Get("address"), ","))
+	setNonEmpty(p, "ip", strings.Split(v4, "/")[0])
+	setNonEmpty(p, "ipv6", strings.Split(v6, "/")[0])
+	setNonEmpty(p, "pre-shared-key", q.Get("presharedkey"))
+	if mtu := q.Get("mtu"); mtu != "" {
+		p["mtu"] = atoi(mtu)
+	}
+	if reserved := q.Get("reserved"); reserved != "" {
+		p["reserved"] = clashReserved(reserved)
+	}
+	setList(p, "allowed-ips", q.Get("allowedips"))
+	if ka := q.Get("keepalive"); ka != "" {
+		p["persistent-keepalive"] = atoi(ka)
+	}
+	if workers := q.Get("workers"); workers != "" {
+		p["workers"] = atoi(workers)
+	}
+
+	// Additional peers move every peer into Mihomo's `peers` list
+	if extra := q[protocol.WireguardPeerParam]; len(extra) > 0 {
+		first := ClashProxy{"server": p["server"], "port": p["port"], "public-key": p["public-key"]}
+		for _, key := range []string{"pre-shared-key", "reserved", "allowed-ips"} {
+			if v, ok := p[key]; ok {
+				first[key] = v
+			}
+		}
+		peers := []any{first}
+		for _, raw := range extra {
+			peer, err := protocol.ParseWireguardPeer(raw)
+			if err != nil {
+				return nil, err
+			}
+			host, port, err := net.SplitHostPort(peer.Endpoint)
+			if err != nil {
+				return nil, fmt.Errorf("invalid peer endpoint %q: %w", peer.Endpoint, err)
+			}
+			entry := ClashProxy{"server": host, "port": atoi(port), "public-key": peer.PublicKey}
+			setNonEmpty(entry, "pre-shared-key", peer.PreSharedKey)
+			setList(entry, "allowed-ips", peer.AllowedIPs)
+			if peer.Reserved != "" {
+				entry["reserved"] = clashReserved(peer.Reserved)
+			}
+			peers = append(peers, entry)
+		}
+		p["peers"] = peers
+	}
+	return p, nil
+}
+
+func clashReserved(reserved string) []any {
+	var values []any
+	for _, r := range strings.Split(reserved, ",") {
+		values = append(values, atoi(strings.TrimSpace(r)))
+	}
+	return values
+}
+
+func socksToClash(link string) (ClashProxy, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	p := ClashProxy{"type": "socks5", "name": u.Fragment, "server": u.Hostname(), "udp": true}
+	if err := setPort(p, u.Port()); err != nil {
+		return nil, err
+	}
+	if user := u.User.Username(); user != "" {
+		if decoded, err := utils.Base64Decode(user); err == nil {
+			user, pass, _ := strings.Cut(string(decoded), ":")
+			p["username"], p["password"] = user, pass
+		}
+	}
+	return p, nil
+}
+
+// setClashTransport writes the Clash network and its *-opts block.
+func setClashTransport(p ClashProxy, network, host, path, serviceName, mode string) error {
+	switch network {
+	case "", "tcp", "raw":
+		return nil
+	case "ws":
+		p["ws-opts"] = clashOpts("path", path, "headers", hostHeader(host))
+	case "httpupgrade":
+		p["ws-opts"] = clashOpts("path", path, "headers", hostHeader(host), "v2ray-http-upgrade", true)
+		network = "ws"
+	case "grpc":
+		p["grpc-opts"] = ClashProxy{"grpc-service-name": serviceName}
+	case "h2":
+		p["h2-opts"] = clashOpts("host", splitList(host), "path", path)
+	case "http":
+		p["http-opts"] = clashOpts("path", splitList(firstNonEmpty(path, "/")), "headers", ClashProxy{"Host": splitList(host)})
+	case "xhttp", "splithttp":
+		p["xhttp-opts"] = clashOpts("path", path, "host", host, "mode", mode)
+		network = "xhttp"
+	default:
+		return fmt.Errorf("transport %q is not supported by clash", network)
+	}
+	p["network"] = network
+	return nil
+}
+
+// clashOpts builds an options block from key/value pairs, leaving out empty values.
+// setClashSmux maps the mux link parameters onto mihomo's smux block.
+// Malformed or disabled mux hints are left out.
+func setClashSmux(p ClashProxy, q url.Values) {
+	mux, err := protocol.ParseMuxParams(q)
+	if err != nil || mux == nil || !mux.Enabled {
+		return
+	}
+	smux := clashOpts("enabled", true, "protocol", mux.Protocol)
+	if mux.Concurrency != 0 {
+		smux["max-streams"] = mux.Concurrency
+	}
+	if mux.Padding {
+		smux["padding"] = true
+	}
+	p["smux"] = smux
+}
+
+func clashOpts(kv ...any) ClashProxy {
+	out := ClashProxy{}
+	for i := 0; i+1 < len(kv); i += 2 {
+		switch v := kv[i+1].(type) {
+		case string:
+			if v == "" {
+				continue
+			}
+		case []any:
+			if len(v) == 0 {
+				continue
+			}
+		case ClashProxy:
+			if len(v) == 0 {
+				continue
+			}
+		}
+		out[kv[i].(string)] = kv[i+1]
+	}
+	return out
+}
+
+func hostHeader(host string) ClashProxy {
+	if host == "" {
+		return ClashProxy{}
+	}
+	return ClashProxy{"Host": host}
+}
+
+// cutPortList removes a Hysteria2 multi-port list ("443,20000-30000") from
+// the link's authority so it can go through url.Parse.
+func cutPortList(link string) (string, string) {
+	scheme, rest, _ := strings.Cut(link, "://")
+	authEnd := strings.IndexAny(rest, "/?#")
+	if authEnd < 0 {
+		authEnd = len(rest)
+	}
+	authority := rest[:authEnd]
+	colon := strings.LastIndex(authority, ":")
+	if colon < 0 || !strings.ContainsAny(authority[colon+1:], ",-") {
+		return link, ""
+	}
+	ports := authority[colon+1:]
+	first := strings.FieldsFunc(ports, func(r rune) bool { return r == ',' || r == '-' })[0]
+	return scheme + "://" + authority[:colon+1] + first + rest[authEnd:], ports
+}
+
+func setPort(p ClashProxy, port string) error {
+	n, err := strconv.Atoi(port)
+	if err != nil || n <= 0 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	if p.str("server") == "" {
+		return errors.New("missing server")
+	}
+	p["port"] = n
+	return nil
+}
+
+func setNonEmpty(p ClashProxy, key, value string) {
+	if value != "" {
+		p[key] = value
+	}
+}
+
+func setList(p ClashProxy, key, value string) {
+	if list := splitList(value); len(list) > 0 {
+		p[key] = list
+	}
+}
+
+func splitList(value string) []any {
+	return stringsToAny(splitStrings(value))
+}
+
+// splitStrings splits a comma separated value into its trimmed, non-empty parts.
+func splitStrings(value string) []string {
+	var out []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func isTrue(value string) bool {
+	b, _ := strconv.ParseBool(value)
+	return b
+}
+
+func atoi(value string) int {
+	n, _ := strconv.Atoi(value)
+	return n
+}
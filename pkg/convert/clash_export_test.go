@@ -0,0 +1,107 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestLinkToClashProxy_RoundTrip(t *testing.T) {
+	links, err := ClashToLinks([]byte(clashSample))
+	if err != nil {
+		t.Fatalf("ClashToLinks() error = %v", err)
+	}
+	for _, link := range links {
+		p, err := LinkToClashProxy(link)
+		if err != nil {
+			t.Errorf("LinkToClashProxy(%q) error = %v", link, err)
+			continue
+		}
+		back, err := ClashProxyToLink(p)
+		if err != nil {
+			t.Errorf("ClashProxyToLink(%v) error = %v", p, err)
+			continue
+		}
+		if p.str("type") == "vmess" {
+			// vmess JSON key order is not stable; compare the decoded proxies instead.
+			again, _ := LinkToClashProxy(back)
+			if again.str("uuid") != p.str("uuid") || again.str("network") != p.str("network") {
+				t.Errorf("vmess round trip mismatch: %v vs %v", p, again)
+			}
+			continue
+		}
+		if back != link {
+			t.Errorf("round trip mismatch:\n got %s\nwant %s", back, link)
+		}
+	}
+}
+
+func TestLinksToClash(t *testing.T) {
+	links := []string{
+		"trojan://secret@tj.example.com:443?sni=tj.example.com&type=ws&path=%2Fws&host=cdn.example.com#same",
+		"hysteria2://pw@hy.example.com:443,20000-30000/?sni=hy.example.com#same",
+		"vless://uuid@h:1?type=kcp#bad-transport",
+		"unknown://x",
+	}
+	out, skipped, err := LinksToClash(links)
+	if err != nil {
+		t.Fatalf("LinksToClash() error = %v", err)
+	}
+	if len(skipped) != 2 || !strings.Contains(skipped[0].Error(), "kcp") || !strings.Contains(skipped[1].Error(), "unknown://") {
+		t.Errorf("expected the kcp and unknown links to be skipped, got %v", skipped)
+	}
+
+	var cfg struct {
+		Proxies []ClashProxy `yaml:"proxies"`
+		Groups  []struct {
+			Name    string   `yaml:"name"`
+			Proxies []string `yaml:"proxies"`
+		} `yaml:"proxy-groups"`
+		Rules []string `yaml:"rules"`
+	}
+	if err := yaml.Unmarshal(out, &cfg); err != nil {
+		t.Fatalf("output is not valid yaml: %v", err)
+	}
+	if len(cfg.Proxies) != 2 {
+		t.Fatalf("expected 2 proxies, got %d", len(cfg.Proxies))
+	}
+	if cfg.Proxies[0].str("name") != "same" || cfg.Proxies[1].str("name") != "same 2" {
+		t.Errorf("duplicate names were not made unique: %q, %q", cfg.Proxies[0].str("name"), cfg.Proxies[1].str("name"))
+	}
+	if cfg.Proxies[1].str("ports") != "443,20000-30000" || cfg.Proxies[1].str("port") != "443" {
+		t.Errorf("hysteria2 port list not kept: %v", cfg.Proxies[1])
+	}
+	if ws := cfg.Proxies[0].sub("ws-opts"); ws.str("path") != "/ws" || ws.sub("headers").str("Host") != "cdn.example.com" {
+		t.Errorf("trojan ws-opts = %v", ws)
+	}
+	if len(cfg.Groups) != 2 || cfg.Groups[0].Name != ClashSelectGroup || len(cfg.Groups[1].Proxies) != 2 {
+		t.Errorf("unexpected proxy groups: %+v", cfg.Groups)
+	}
+	if len(cfg.Rules) != 1 || cfg.Rules[0] != "MATCH,"+ClashSelectGroup {
+		t.Errorf("unexpected rules: %v", cfg.Rules)
+	}
+}
+
+func TestClashSmuxRoundTrip(t *testing.T) {
+	links := []string{
+		"trojan://pass@example.com:443?mux=h2mux&mux_concurrency=8&mux_padding=1&security=tls&type=tcp#t",
+		"ss://YWVzLTEyOC1nY206cGFzcw@example.com:8388/?mux=1#s",
+	}
+	for _, link := range links {
+		p, err := LinkToClashProxy(link)
+		if err != nil {
+			t.Fatalf("LinkToClashProxy(%q) error = %v", link, err)
+		}
+		if !p.sub("smux").bool("enabled") {
+			t.Errorf("%s: smux not enabled in %v", link, p)
+		}
+		back, err := ClashProxyToLink(p)
+		if err != nil {
+			t.Fatalf("ClashProxyToLink() error = %v", err)
+		}
+		if back != link {
+			t.Errorf("round trip mismatch:\n got %s\nwant %s", back, link)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package convert
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const clashSample = `
+port: 7890
+mode: rule
+proxies:
+  - name: "vmess-ws"
+    type: vmess
+    server: vm.example.com
+    port: 443
+    uuid: 11111111-2222-3333-4444-555555555555
+    alterId: 0
+    cipher: auto
+    tls: true
+    servername: cdn.example.com
+    network: ws
+    ws-opts:
+      path: /ray
+      headers:
+        Host: cdn.example.com
+  - name: reality
+    type: vless
+    server: 1.2.3.4
+    port: 443
+    uuid: 66666666-7777-8888-9999-000000000000
+    flow: xtls-rprx-vision
+    tls: true
+    servername: www.microsoft.com
+    client-fingerprint: chrome
+    reality-opts:
+      public-key: PUBKEY
+      short-id: abcd
+  - name: trojan-grpc
+    type: trojan
+    server: tj.example.com
+    port: 443
+    password: secret
+    sni: tj.example.com
+    network: grpc
+    grpc-opts:
+      grpc-service-name: svc
+  - name: ss-obfs
+    type: ss
+    server: ss.example.com
+    port: 8388
+    cipher: aes-256-gcm
+    password: pass
+    plugin: obfs
+    plugin-opts:
+      mode: http
+      host: bing.com
+  - name: hy2
+    type: hysteria2
+    server: hy.example.com
+    port: 443
+    ports: 20000-30000
+    password: hypass
+    up: "30 Mbps"
+    skip-cert-verify: true
+  - name: tuic
+    type: tuic
+    server: tuic.example.com
+    port: 443
+    uuid: aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee
+    password: tpass
+    alpn: [h3]
+    congestion-controller: bbr
+  - name: wg
+    type: wireguard
+    server: 162.159.192.1
+    port: 2408
+    ip: 172.16.0.2
+    ipv6: 2606:4700:110:8a36::2
+    private-key: PRIVKEY
+    public-key: PEERKEY
+    reserved: [1, 2, 3]
+    mtu: 1280
+  - name: unsupported
+    type: snell
+    server: snell.example.com
+    port: 443
+proxy-groups: []
+`
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want Format
+	}{
+		{"clash", clashSample, FormatClash},
+		{"links", "vless://a@b:1#x\ntrojan://p@h:443", FormatLinks},
+		{"base64", base64.StdEncoding.EncodeToString([]byte("vless://a@b:1#x\n")), FormatBase64},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat([]byte(tt.body)); got != tt.want {
+			t.Errorf("%s: DetectFormat() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClashToLinks(t *testing.T) {
+	links, err := ExtractLinks([]byte(clashSample))
+	if err != nil {
+		t.Fatalf("ExtractLinks() error = %v", err)
+	}
+	if len(links) != 7 {
+		t.Fatalf("expected 7 links (snell skipped), got %d: %v", len(links), links)
+	}
+
+	// vmess: base64 JSON
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(links[0], "vmess://"))
+	if err != nil {
+		t.Fatalf("vmess link is not base64: %v", err)
+	}
+	var vm map[string]string
+	if err := json.Unmarshal(raw, &vm); err != nil {
+		t.Fatalf("vmess link is not JSON: %v", err)
+	}
+	if vm["net"] != "ws" || vm["path"] != "/ray" || vm["host"] != "cdn.example.com" || vm["tls"] != "tls" || vm["ps"] != "vmess-ws" {
+		t.Errorf("unexpected vmess payload: %v", vm)
+	}
+
+	checks := []struct {
+		index  int
+		scheme string
+		query  map[string]string
+	}{
+		{1, "vless", map[string]string{"security": "reality", "pbk": "PUBKEY", "sid": "abcd", "flow": "xtls-rprx-vision", "fp": "chrome", "sni": "www.microsoft.com"}},
+		{2, "trojan", map[string]string{"type": "grpc", "serviceName": "svc", "sni": "tj.example.com"}},
+		{3, "ss", map[string]string{"plugin": "obfs-local;obfs=http;obfs-host=bing.com"}},
+		{4, "hysteria2", map[string]string{"mport": "20000-30000", "up": "30 Mbps", "insecure": "1"}},
+		{5, "tuic", map[string]string{"alpn": "h3", "congestion_control": "bbr"}},
+		{6, "wireguard", map[string]string{"publickey": "PEERKEY", "reserved": "1,2,3", "mtu": "1280", "address": "172.16.0.2/32,2606:4700:110:8a36::2/128"}},
+	}
+	for _, c := range checks {
+		u, err := url.Parse(links[c.index])
+		if err != nil {
+			t.Fatalf("link %d does not parse: %v", c.index, err)
+		}
+		if u.Scheme != c.scheme {
+			t.Errorf("link %d scheme = %q, want %q", c.index, u.Scheme, c.scheme)
+		}
+		for k, want := range c.query {
+			if got := u.Query().Get(k); got != want {
+				t.Errorf("link %d (%s) %s = %q, want %q", c.index, c.scheme, k, got, want)
+			}
+		}
+	}
+
+	if u, _ := url.Parse(links[5]); u.User.Username() != "aaaaaaaa-bbbb-cccc-dddd-eeeeeeeeeeee" {
+		t.Errorf("tuic uuid not in userinfo: %s", links[5])
+	}
+}
+
+func TestExtractLinks_PlainAndBase64(t *testing.T) {
+	plain := "vless://a@b:1#x\n\n  \ntrojan://p@h:443\n"
+	for _, body := range []string{plain, base64.StdEncoding.EncodeToString([]byte(plain))} {
+		links, err := ExtractLinks([]byte(body))
+		if err != nil {
+			t.Fatalf("ExtractLinks() error = %v", err)
+		}
+		if len(links) != 2 {
+			t.Errorf("expected 2 links, got %v", links)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package convert turns subscription bodies in third-party formats
+// (Clash/Mihomo YAML, sing-box and xray-core JSON, SIP008, wg-quick) into the share links used everywhere else.
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/lilendian0x00/xray-knife/v10/utils"
+)
+
+// Format identifies the layout of a subscription body.
+type Format int
+
+const (
+	FormatLinks       Format = iota // Newline separated share links
+	FormatBase64                    // Base64 encoded share links
+	FormatClash                     // Clash / Mihomo YAML with a `proxies:` list
+	FormatSingboxJSON               // sing-box JSON configuration
+	FormatXrayJSON                  // xray-core JSON configuration
+	FormatSIP008                    // SIP008 / Outline Shadowsocks server list
+	FormatWgQuick                   // wg-quick .conf file(s)
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatLinks:
+		return "links"
+	case FormatBase64:
+		return "base64"
+	case FormatClash:
+		return "clash"
+	case FormatSingboxJSON:
+		return "sing-box json"
+	case FormatXrayJSON:
+		return "xray json"
+	case FormatSIP008:
+		return "sip008"
+	case FormatWgQuick:
+		return "wg-quick"
+	default:
+		return "unknown"
+	}
+}
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+var clashProxiesRe = regexp.MustCompile(`(?m)^proxies:\s*(#.*)?$`)
+
+// DetectFormat guesses the format of a subscription body.
+func DetectFormat(body []byte) Format {
+	body = bytes.TrimSpace(bytes.TrimPrefix(body, utf8BOM))
+
+	if f, ok := detectJSONFormat(body); ok {
+		return f
+	}
+	if clashProxiesRe.Match(body) {
+		return FormatClash
+	}
+	if isWgQuick(body) {
+		return FormatWgQuick
+	}
+	if !bytes.Contains(body, []byte("://")) {
+		if _, err := utils.Base64Decode(string(body)); err == nil {
+			return FormatBase64
+		}
+	}
+	return FormatLinks
+}
+
+// ExtractLinks detects the body's format and returns the share links it
+// contains, one per element, with empty lines removed.
+func ExtractLinks(body []byte) ([]string, error) {
+	body = bytes.TrimPrefix(body, utf8BOM)
+	var text string
+	switch DetectFormat(body) {
+	case FormatClash:
+		return ClashToLinks(body)
+	case FormatSingboxJSON:
+		return SingboxJSONToLinks(body)
+	case FormatXrayJSON:
+		return XrayJSONToLinks(body)
+	case FormatSIP008:
+		return SIP008ToLinks(body)
+	case FormatWgQuick:
+		return WgQuickToLinks(body)
+	case FormatBase64:
+		decoded, err := utils.Base64Decode(strings.TrimSpace(string(body)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode base64 body: %w", err)
+		}
+		text = string(decoded)
+	default:
+		text = string(body)
+	}
+
+	var links []string
+	for _, l := range strings.Split(text, "\n") {
+		if trimmed := strings.TrimSpace(l); trimmed != "" {
+			links = append(links, trimmed)
+		}
+	}
+	return links, nil
+}
+
+// ReadLinksFile reads a file in any supported format and returns its share links.
+// wg-quick configs carry no name, so they are named after the file.
+func ReadLinksFile(fileName string) ([]string, error) {
+	body, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("error in reading file: %w", err)
+	}
+	links, err := ExtractLinks(body)
+	if err == nil && DetectFormat(body) == FormatWgQuick {
+		base := filepath.Base(fileName)
+		links = NameUnnamedLinks(links, strings.TrimSuffix(base, filepath.Ext(base)))
+	}
+	return links, err
+}
@@ -0,0 +1,450 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+)
+
+// jsonConfig holds the parts of a sing-box or xray-core configuration that
+// describe outgoing proxies. Outbounds are kept as generic maps and reuse the
+// ClashProxy accessors; sing-box >= 1.11 moved WireGuard into `endpoints`.
+type jsonConfig struct {
+	Outbounds []ClashProxy `json:"outbounds"`
+	Endpoints []ClashProxy `json:"endpoints"`
+}
+
+// Outbound types that carry no proxy and are skipped without an error.
+var (
+	singboxNonProxyTypes = map[string]bool{"direct": true, "block": true, "dns": true, "selector": true, "urltest": true}
+	xrayNonProxyTypes    = map[string]bool{"freedom": true, "blackhole": true, "dns": true, "loopback": true}
+)
+
+// detectJSONFormat reports whether body is a sing-box, xray-core or SIP008
+// document. Sing-box outbounds are keyed by `type`, xray-core ones by
+// `protocol`; SIP008 lists Shadowsocks servers with a `method`.
+func detectJSONFormat(body []byte) (Format, bool) {
+	if len(body) == 0 || body[0] != '{' {
+		return 0, false
+	}
+	var doc ClashProxy
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return 0, false
+	}
+	if isSIP008(doc) {
+		return FormatSIP008, true
+	}
+	for _, o := range doc.subList("outbounds") {
+		if o.str("protocol") != "" {
+			return FormatXrayJSON, true
+		}
+		if o.str("type") != "" {
+			return FormatSingboxJSON, true
+		}
+	}
+	if len(doc.subList("endpoints")) > 0 {
+		return FormatSingboxJSON, true
+	}
+	return 0, false
+}
+
+// SingboxJSONToLinks converts every proxy outbound (and WireGuard endpoint)
+// of a sing-box configuration into a share link.
+func SingboxJSONToLinks(body []byte) ([]string, error) {
+	var cfg jsonConfig
+	if err := json.Unmarshal(body, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid sing-box json: %w", err)
+	}
+	return outboundsToLinks(append(cfg.Outbounds, cfg.Endpoints...), "type", singboxNonProxyTypes, singboxToClash)
+}
+
+// XrayJSONToLinks converts every proxy outbound of an xray-core configuration
+// into a share link.
+func XrayJSONToLinks(body []byte) ([]string, error) {
+	var cfg jsonConfig
+	if err := json.Unmarshal(body, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid xray json: %w", err)
+	}
+	return outboundsToLinks(cfg.Outbounds, "protocol", xrayNonProxyTypes, xrayToClash)
+}
+
+// outboundsToLinks maps each outbound onto the equivalent Clash proxy entry
+// and reuses ClashProxyToLink to build the share link. Like ClashToLinks, an
+// error is only returned when nothing could be converted.
+func outboundsToLinks(outbounds []ClashProxy, typeKey string, skip map[string]bool, toClash func(ClashProxy) (ClashProxy, error)) ([]string, error) {
+	var links []string
+	var errs []error
+	for i, o := range outbounds {
+		if skip[o.str(typeKey)] {
+			continue
+		}
+		link, err := outboundToLink(o, toClash)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("outbound %d (%s): %w", i, o.str("tag"), err))
+			continue
+		}
+		links = append(links, link)
+	}
+
+	if len(links) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return links, nil
+}
+
+func outboundToLink(o ClashProxy, toClash func(ClashProxy) (ClashProxy, error)) (string, error) {
+	p, err := toClash(o)
+	if err != nil {
+		return "", err
+	}
+	return ClashProxyToLink(p)
+}
+
+// singboxToClash translates a sing-box outbound into a Clash proxy entry.
+func singboxToClash(o ClashProxy) (ClashProxy, error) {
+	p := ClashProxy{
+		"name":   o.str("tag"),
+		"server": o.str("server"),
+		"port":   o.str("server_port"),
+	}
+
+	switch o.str("type") {
+	case "vmess":
+		p["type"] = "vmess"
+		p["uuid"] = o.str("uuid")
+		p["alterId"] = o.str("alter_id")
+		p["cipher"] = o.str("security")
+	case "vless":
+		p["type"] = "vless"
+		p["uuid"] = o.str("uuid")
+		p["flow"] = o.str("flow")
+	case "trojan":
+		p["type"] = "trojan"
+		p["password"] = o.str("password")
+	case "shadowsocks":
+		p["type"] = "ss"
+		p["cipher"] = o.str("method")
+		p["password"] = o.str("password")
+		if plugin := o.str("plugin"); plugin != "" {
+			p["plugin"], p["plugin-opts"] = sip003ToClashPlugin(plugin, o.str("plugin_opts"))
+		}
+	case "hysteria2":
+		p["type"] = "hysteria2"
+		p["password"] = o.str("password")
+		var ports []string
+		for _, r := range o.strList("server_ports") {
+			ports = append(ports, strings.Replace(r, ":", "-", 1))
+		}
+		p["ports"] = strings.Join(ports, ",")
+		p["hop-interval"] = o.str("hop_interval")
+		p["up"] = o.str("up_mbps")
+		p["down"] = o.str("down_mbps")
+		if obfs := o.sub("obfs"); len(obfs) > 0 {
+			p["obfs"] = obfs.str("type")
+			p["obfs-password"] = obfs.str("password")
+		}
+	case "tuic":
+		p["type"] = "tuic"
+		p["uuid"] = o.str("uuid")
+		p["password"] = o.str("password")
+		p["congestion-controller"] = o.str("congestion_control")
+		p["udp-relay-mode"] = o.str("udp_relay_mode")
+		p["disable-sni"] = o.sub("tls").bool("disable_sni")
+	case "socks":
+		p["type"] = "socks5"
+		p["username"] = o.str("username")
+		p["password"] = o.str("password")
+	case "wireguard":
+		return singboxWireguardToClash(o)
+	default:
+		return nil, fmt.Errorf("unsupported outbound type %q", o.str("type"))
+	}
+
+	if tls := o.sub("tls"); tls.bool("enabled") {
+		p["tls"] = true
+		p["servername"] = tls.str("server_name")
+		p["alpn"] = stringsToAny(tls.strList("alpn"))
+		p["skip-cert-verify"] = tls.bool("insecure")
+		if utls := tls.sub("utls"); utls.bool("enabled") {
+			p["client-fingerprint"] = utls.str("fingerprint")
+		}
+		if reality := tls.sub("reality"); reality.bool("enabled") {
+			p["reality-opts"] = ClashProxy{
+				"public-key": reality.str("public_key"),
+				"short-id":   reality.str("short_id"),
+			}
+		}
+	}
+
+	if t := o.sub("transport"); len(t) > 0 {
+		host := firstNonEmpty(t.sub("headers").str("Host"), t.sub("headers").str("host"), t.str("host"))
+		switch t.str("type") {
+		case "ws":
+			p["network"] = "ws"
+			p["ws-opts"] = ClashProxy{"path": t.str("path"), "headers": ClashProxy{"Host": host}}
+		case "httpupgrade":
+			p["network"] = "httpupgrade"
+			p["http-upgrade-opts"] = ClashProxy{"path": t.str("path"), "headers": ClashProxy{"Host": host}}
+		case "grpc":
+			p["network"] = "grpc"
+			p["grpc-opts"] = ClashProxy{"grpc-service-name": t.str("service_name")}
+		case "http":
+			// sing-box's http transport is HTTP/2 over TLS and plain HTTP/1.1 otherwise.
+			if p.bool("tls") {
+				p["network"] = "h2"
+				p["h2-opts"] = ClashProxy{"host": stringsToAny(t.strList("host")), "path": t.str("path")}
+			} else {
+				p["network"] = "http"
+				p["http-opts"] = ClashProxy{"path": []any{t.str("path")}, "headers": ClashProxy{"Host": stringsToAny(t.strList("host"))}}
+			}
+		default:
+			return nil, fmt.Errorf("unsupported transport %q", t.str("type"))
+		}
+	}
+	return p, nil
+}
+
+// singboxWireguardToClash handles both the legacy WireGuard outbound and the
+// WireGuard endpoint introduced in sing-box 1.11.
+func singboxWireguardToClash(o ClashProxy) (ClashProxy, error) {
+	p := ClashProxy{
+		"type":        "wireguard",
+		"name":        o.str("tag"),
+		"private-key": o.str("private_key"),
+		"mtu":         o.str("mtu"),
+		"workers":     o.str("workers"),
+	}
+	addresses := firstNonEmptyList(o.strList("local_address"), o.strList("address"))
+	p["ip"], p["ipv6"] = splitAddressFamilies(addresses)
+
+	// Legacy outbounds may have the single peer inline; endpoints always use `peers`.
+	var peers []any
+	for _, peer := range o.subList("peers") {
+		peers = append(peers, ClashProxy{
+			"server":         firstNonEmpty(peer.str("server"), peer.str("address")),
+			"port":           firstNonEmpty(peer.str("server_port"), peer.str("port")),
+			"public-key":     peer.str("public_key"),
+			"pre-shared-key": peer.str("pre_shared_key"),
+			"allowed-ips":    peer["allowed_ips"],
+			"reserved":       peer["reserved"],
+		})
+		if ka := peer.str("persistent_keepalive_interval"); ka != "" && p.str("persistent-keepalive") == "" {
+			p["persistent-keepalive"] = ka
+		}
+	}
+	if len(peers) == 0 {
+		p["server"], p["port"] = o.str("server"), o.str("server_port")
+		p["public-key"], p["pre-shared-key"] = o.str("peer_public_key"), o.str("pre_shared_key")
+		p["reserved"] = o["reserved"]
+	} else {
+		p["peers"] = peers
+	}
+	return p, nil
+}
+
+// xrayToClash translates an xray-core outbound into a Clash proxy entry.
+func xrayToClash(o ClashProxy) (ClashProxy, error) {
+	settings := o.sub("settings")
+	p := ClashProxy{"name": o.str("tag")}
+
+	// Servers are listed under `vnext` (vmess/vless), `servers`
+	// (trojan/shadowsocks/socks) or, in newer configs, inline in settings.
+	server := settings
+	if list := firstNonEmptyObjects(settings.subList("vnext"), settings.subList("servers")); len(list) > 0 {
+		server = list[0]
+	}
+	user := server
+	if users := server.subList("users"); len(users) > 0 {
+		user = users[0]
+	}
+	p["server"] = server.str("address")
+	p["port"] = server.str("port")
+
+	switch o.str("protocol") {
+	case "vmess":
+		p["type"] = "vmess"
+		p["uuid"] = user.str("id")
+		p["alterId"] = user.str("alterId")
+		p["cipher"] = user.str("security")
+	case "vless":
+		p["type"] = "vless"
+		p["uuid"] = user.str("id")
+		p["flow"] = user.str("flow")
+	case "trojan":
+		p["type"] = "trojan"
+		p["password"] = server.str("password")
+	case "shadowsocks":
+		p["type"] = "ss"
+		p["cipher"] = server.str("method")
+		p["password"] = server.str("password")
+	case "socks":
+		p["type"] = "socks5"
+		p["username"] = user.str("user")
+		p["password"] = user.str("pass")
+	case "wireguard":
+		return xrayWireguardToClash(o)
+	default:
+		return nil, fmt.Errorf("unsupported outbound protocol %q", o.str("protocol"))
+	}
+
+	stream := o.sub("streamSettings")
+	switch stream.str("security") {
+	case "tls":
+		tls := stream.sub("tlsSettings")
+		p["tls"] = true
+		p["servername"] = tls.str("serverName")
+		p["alpn"] = stringsToAny(tls.strList("alpn"))
+		p["skip-cert-verify"] = tls.bool("allowInsecure")
+		p["client-fingerprint"] = tls.str("fingerprint")
+	case "reality":
+		reality := stream.sub("realitySettings")
+		p["tls"] = true
+		p["servername"] = reality.str("serverName")
+		p["client-fingerprint"] = reality.str("fingerprint")
+		p["reality-opts"] = ClashProxy{
+			"public-key": firstNonEmpty(reality.str("publicKey"), reality.str("password")),
+			"short-id":   reality.str("shortId"),
+		}
+	}
+
+	switch network := stream.str("network"); network {
+	case "", "tcp", "raw":
+		header := firstNonEmptyObject(stream.sub("rawSettings"), stream.sub("tcpSettings")).sub("header")
+		if header.str("type") == "http" {
+			req := header.sub("request")
+			p["network"] = "http"
+			p["http-opts"] = ClashProxy{"path": req["path"], "headers": ClashProxy{"Host": req.sub("headers")["Host"]}}
+		}
+	case "ws":
+		ws := stream.sub("wsSettings")
+		host := firstNonEmpty(ws.str("host"), ws.sub("headers").str("Host"))
+		p["network"] = "ws"
+		p["ws-opts"] = ClashProxy{"path": ws.str("path"), "headers": ClashProxy{"Host": host}}
+	case "httpupgrade":
+		hu := stream.sub("httpupgradeSettings")
+		p["network"] = "httpupgrade"
+		p["http-upgrade-opts"] = ClashProxy{"path": hu.str("path"), "headers": ClashProxy{"Host": hu.str("host")}}
+	case "grpc":
+		p["network"] = "grpc"
+		p["grpc-opts"] = ClashProxy{"grpc-service-name": stream.sub("grpcSettings").str("serviceName")}
+	case "xhttp", "splithttp":
+		xh := firstNonEmptyObject(stream.sub("xhttpSettings"), stream.sub("splithttpSettings"))
+		p["network"] = "xhttp"
+		p["xhttp-opts"] = ClashProxy{"path": xh.str("path"), "host": xh.str("host"), "mode": xh.str("mode")}
+	case "h2", "http":
+		h2 := stream.sub("httpSettings")
+		p["network"] = "h2"
+		p["h2-opts"] = ClashProxy{"host": stringsToAny(h2.strList("host")), "path": h2.str("path")}
+	default:
+		return nil, fmt.Errorf("unsupported network %q", network)
+	}
+	return p, nil
+}
+
+func xrayWireguardToClash(o ClashProxy) (ClashProxy, error) {
+	settings := o.sub("settings")
+	var peers []any
+	for _, peer := range settings.subList("peers") {
+		host, port, err := net.SplitHostPort(peer.str("endpoint"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer endpoint: %w", err)
+		}
+		peers = append(peers, ClashProxy{
+			"server":         host,
+			"port":           port,
+			"public-key":     peer.str("publicKey"),
+			"pre-shared-key": peer.str("preSharedKey"),
+			"allowed-ips":    peer["allowedIPs"],
+		})
+	}
+	if len(peers) == 0 {
+		return nil, errors.New("wireguard outbound has no peers")
+	}
+
+	p := ClashProxy{
+		"type":        "wireguard",
+		"name":        o.str("tag"),
+		"private-key": settings.str("secretKey"),
+		"reserved":    settings["reserved"],
+		"mtu":         settings.str("mtu"),
+		"workers":     settings.str("workers"),
+		"peers":       peers,
+	}
+	if ka := settings.subList("peers")[0].str("keepAlive"); ka != "0" {
+		p["persistent-keepalive"] = ka
+	}
+	p["ip"], p["ipv6"] = splitAddressFamilies(settings.strList("address"))
+	return p, nil
+}
+
+// sip003ToClashPlugin converts a SIP003 plugin name and option string into
+// the Clash `plugin` / `plugin-opts` pair understood by clashShadowsocks.
+func sip003ToClashPlugin(plugin, opts string) (string, ClashProxy) {
+	name, _ := protocol.SplitSIP003Plugin(plugin)
+	kv := protocol.ParseSIP003Options(opts)
+	out := ClashProxy{}
+	switch name {
+	case protocol.PluginObfsLocal:
+		out["mode"] = kv["obfs"]
+		out["host"] = kv["obfs-host"]
+	case protocol.PluginV2rayPlugin:
+		_, tls := kv["tls"]
+		out["mode"] = kv["mode"]
+		out["host"] = kv["host"]
+		out["path"] = kv["path"]
+		out["tls"] = tls
+		if mux, ok := kv["mux"]; ok {
+			out["mux"] = mux != "0"
+		}
+	default:
+		for k, v := range kv {
+			out[k] = v
+		}
+	}
+	return name, out
+}
+
+// splitAddressFamilies returns the first IPv4 and the first IPv6 interface
+// address, keeping any prefix length.
+func splitAddressFamilies(addresses []string) (v4, v6 string) {
+	for _, a := range addresses {
+		if strings.Contains(a, ":") {
+			if v6 == "" {
+				v6 = a
+			}
+		} else if v4 == "" {
+			v4 = a
+		}
+	}
+	return v4, v6
+}
+
+func stringsToAny(list []string) []any {
+	out := make([]any, len(list))
+	for i, s := range list {
+		out[i] = s
+	}
+	return out
+}
+
+func firstNonEmptyObject(objects ...ClashProxy) ClashProxy {
+	for _, o := range objects {
+		if len(o) > 0 {
+			return o
+		}
+	}
+	return ClashProxy{}
+}
+
+func firstNonEmptyObjects(lists ...[]ClashProxy) []ClashProxy {
+	for _, l := range lists {
+		if len(l) > 0 {
+			return l
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package convert
+
+import (
+	"net/url"
+	"testing"
+)
+
+const singboxSample = `{
+  "log": {"level": "info"},
+  "outbounds": [
+    {
+      "type": "vless",
+      "tag": "vless-reality",
+      "server": "1.2.3.4",
+      "server_port": 443,
+      "uuid": "66666666-7777-8888-9999-000000000000",
+      "flow": "xtls-rprx-vision",
+      "tls": {
+        "enabled": true,
+        "server_name": "www.microsoft.com",
+        "utls": {"enabled": true, "fingerprint": "chrome"},
+        "reality": {"enabled": true, "public_key": "PUBKEY", "short_id": "abcd"}
+      }
+    },
+    {
+      "type": "trojan",
+      "tag": "trojan-ws",
+      "server": "tj.example.com",
+      "server_port": 443,
+      "password": "secret",
+      "tls": {"enabled": true, "server_name": "tj.example.com", "insecure": true},
+      "transport": {"type": "ws", "path": "/ws", "headers": {"Host": "cdn.example.com"}}
+    },
+    {
+      "type": "shadowsocks",
+      "tag": "ss",
+      "server": "ss.example.com",
+      "server_port": 8388,
+      "method": "aes-256-gcm",
+      "password": "pass",
+      "plugin": "obfs-local",
+      "plugin_opts": "obfs=http;obfs-host=bing.com"
+    },
+    {
+      "type": "hysteria2",
+      "tag": "hy2",
+      "server": "hy.example.com",
+      "server_port": 443,
+      "server_ports": ["20000:30000"],
+      "password": "hypass",
+      "up_mbps": 30,
+      "tls": {"enabled": true, "server_name": "hy.example.com"}
+    },
+    {"type": "direct", "tag": "direct"},
+    {"type": "selector", "tag": "select", "outbounds": ["vless-reality"]}
+  ],
+  "endpoints": [
+    {
+      "type": "wireguard",
+      "tag": "wg",
+      "address": ["172.16.0.2/32", "2606:4700:110:8a36::2/128"],
+      "private_key": "PRIVKEY",
+      "peers": [{"address": "162.159.192.1", "port": 2408, "public_key": "PEERKEY", "reserved": [1, 2, 3]}]
+    }
+  ]
+}`
+
+const xraySample = `{
+  "inbounds": [{"protocol": "socks", "port": 10808}],
+  "outbounds": [
+    {
+      "tag": "proxy",
+      "protocol": "vless",
+      "settings": {
+        "vnext": [{"address": "vl.example.com", "port": 443, "users": [{"id": "11111111-2222-3333-4444-555555555555", "encryption": "none"}]}]
+      },
+      "streamSettings": {
+        "network": "xhttp",
+        "security": "tls",
+        "tlsSettings": {"serverName": "vl.example.com", "alpn": ["h2"], "fingerprint": "firefox"},
+        "xhttpSettings": {"path": "/x", "host": "vl.example.com", "mode": "packet-up"}
+      }
+    },
+    {
+      "tag": "ss",
+      "protocol": "shadowsocks",
+      "settings": {"servers": [{"address": "ss.example.com", "port": 8388, "method": "chacha20-ietf-poly1305", "password": "pw"}]}
+    },
+    {"tag": "direct", "protocol": "freedom"},
+    {"tag": "block", "protocol": "blackhole"}
+  ]
+}`
+
+func TestDetectFormat_JSON(t *testing.T) {
+	if got := DetectFormat([]byte(singboxSample)); got != FormatSingboxJSON {
+		t.Errorf("sing-box: DetectFormat() = %v", got)
+	}
+	if got := DetectFormat([]byte(xraySample)); got != FormatXrayJSON {
+		t.Errorf("xray: DetectFormat() = %v", got)
+	}
+	if got := DetectFormat([]byte(`{"log": {}}`)); got != FormatLinks {
+		t.Errorf("json without outbounds: DetectFormat() = %v", got)
+	}
+}
+
+func TestSingboxJSONToLinks(t *testing.T) {
+	links, err := ExtractLinks([]byte(singboxSample))
+	if err != nil {
+		t.Fatalf("ExtractLinks() error = %v", err)
+	}
+	if len(links) != 5 {
+		t.Fatalf("expected 5 links (direct/selector skipped), got %d: %v", len(links), links)
+	}
+
+	checks := []struct {
+		scheme string
+		host   string
+		query  map[string]string
+	}{
+		{"vless", "1.2.3.4:443", map[string]string{"security": "reality", "pbk": "PUBKEY", "sid": "abcd", "fp": "chrome", "flow": "xtls-rprx-vision"}},
+		{"trojan", "tj.example.com:443", map[string]string{"type": "ws", "path": "/ws", "host": "cdn.example.com", "allowInsecure": "1"}},
+		{"ss", "ss.example.com:8388", map[string]string{"plugin": "obfs-local;obfs=http;obfs-host=bing.com"}},
+		{"hysteria2", "hy.example.com:443", map[string]string{"mport": "20000-30000", "up": "30", "sni": "hy.example.com"}},
+		{"wireguard", "162.159.192.1:2408", map[string]string{"publickey": "PEERKEY", "reserved": "1,2,3", "address": "172.16.0.2/32,2606:4700:110:8a36::2/128"}},
+	}
+	for i, c := range checks {
+		assertLink(t, links[i], c.scheme, c.host, c.query)
+	}
+}
+
+func TestXrayJSONToLinks(t *testing.T) {
+	links, err := ExtractLinks([]byte(xraySample))
+	if err != nil {
+		t.Fatalf("ExtractLinks() error = %v", err)
+	}
+	if len(links) != 2 {
+		t.Fatalf("expected 2 links (freedom/blackhole skipped), got %d: %v", len(links), links)
+	}
+	assertLink(t, links[0], "vless", "vl.example.com:443", map[string]string{
+		"type": "xhttp", "mode": "packet-up", "path": "/x", "security": "tls", "alpn": "h2", "fp": "firefox",
+	})
+	assertLink(t, links[1], "ss", "ss.example.com:8388", nil)
+}
+
+func assertLink(t *testing.T, link, scheme, host string, query map[string]string) {
+	t.Helper()
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("link %q does not parse: %v", link, err)
+	}
+	if u.Scheme != scheme || u.Host != host {
+		t.Errorf("link %q: got %s://%s, want %s://%s", link, u.Scheme, u.Host, scheme, host)
+	}
+	for k, want := range query {
+		if got := u.Query().Get(k); got != want {
+			t.Errorf("link %q: %s = %q, want %q", link, k, got, want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package convert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+)
+
+// SSConfScheme is the scheme of Outline dynamic access keys. The key points
+// at an HTTPS document holding the actual server(s).
+const SSConfScheme = "ssconf"
+
+// isSIP008 reports whether doc is a SIP008 server list or a single
+// Outline-style server object.
+func isSIP008(doc ClashProxy) bool {
+	if servers := doc.subList("servers"); len(servers) > 0 {
+		return servers[0].str("method") != "" && servers[0].str("server") != ""
+	}
+	return doc.str("method") != "" && doc.str("server") != "" && doc.str("server_port") != ""
+}
+
+// SIP008ToLinks converts a SIP008 document ({"version":1,"servers":[...]})
+// or a single Outline server object into ss:// links, keeping each server's
+// remarks as the link name.
+func SIP008ToLinks(body []byte) ([]string, error) {
+	var doc ClashProxy
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("invalid sip008 json: %w", err)
+	}
+	servers := doc.subList("servers")
+	if len(servers) == 0 {
+		servers = []ClashProxy{doc}
+	}
+
+	var links []string
+	var errs []error
+	for i, s := range servers {
+		link, err := sip008ServerToLink(s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("server %d (%s): %w", i, s.str("remarks"), err))
+			continue
+		}
+		links = append(links, link)
+	}
+
+	if len(links) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return links, nil
+}
+
+func sip008ServerToLink(s ClashProxy) (string, error) {
+	if s.str("server") == "" || s.str("server_port") == "" {
+		return "", errors.New("missing server or server_port")
+	}
+	p := ClashProxy{
+		"type":     "ss",
+		"name":     s.str("remarks"),
+		"server":   s.str("server"),
+		"port":     s.str("server_port"),
+		"cipher":   s.str("method"),
+		"password": s.str("password"),
+	}
+	if plugin := s.str("plugin"); plugin != "" {
+		p["plugin"], p["plugin-opts"] = sip003ToClashPlugin(plugin, s.str("plugin_opts"))
+	}
+	return clashShadowsocks(p)
+}
+
+// SSConfFetchURL returns the HTTPS URL an ssconf:// access key refers to.
+func SSConfFetchURL(u *url.URL) *url.URL {
+	fetch := *u
+	fetch.Scheme = "https"
+	fetch.Fragment = ""
+	fetch.RawFragment = ""
+	return &fetch
+}
+
+// NameUnnamedLinks sets name as the fragment of every link that has none,
+// e.g. to carry the name of an ssconf:// key over to the servers it returns.
+func NameUnnamedLinks(links []string, name string) []string {
+	if name == "" {
+		return links
+	}
+	out := make([]string, len(links))
+	for i, link := range links {
+		if !strings.Contains(link, "#") && !strings.HasPrefix(link, protocol.VmessIdentifier+"://") {
+			link += "#" + url.PathEscape(name)
+		}
+		out[i] = link
+	}
+	return out
+}
@@ -0,0 +1,90 @@
+package convert
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/lilendian0x00/xray-knife/v10/utils"
+)
+
+const sip008Sample = `{
+  "version": 1,
+  "servers": [
+    {
+      "id": "27b8a625-4f4b-4428-9f0f-8a2317db7c79",
+      "remarks": "Server #1",
+      "server": "198.51.100.1",
+      "server_port": 8388,
+      "password": "example",
+      "method": "chacha20-ietf-poly1305",
+      "plugin": "obfs-local",
+      "plugin_opts": "obfs=http;obfs-host=www.example.com"
+    },
+    {
+      "id": "7842c068-c667-41f2-8f7d-04feece3cb67",
+      "remarks": "Server #2",
+      "server": "198.51.100.2",
+      "server_port": 8388,
+      "password": "example",
+      "method": "aes-256-gcm"
+    }
+  ],
+  "bytes_used": 274877906944
+}`
+
+func TestSIP008ToLinks(t *testing.T) {
+	if got := DetectFormat([]byte(sip008Sample)); got != FormatSIP008 {
+		t.Fatalf("DetectFormat() = %v, want %v", got, FormatSIP008)
+	}
+	links, err := ExtractLinks([]byte(sip008Sample))
+	if err != nil {
+		t.Fatalf("ExtractLinks() error = %v", err)
+	}
+	if len(links) != 2 {
+		t.Fatalf("expected 2 links, got %v", links)
+	}
+
+	u, err := url.Parse(links[0])
+	if err != nil {
+		t.Fatalf("link does not parse: %v", err)
+	}
+	if u.Scheme != "ss" || u.Host != "198.51.100.1:8388" || u.Fragment != "Server #1" {
+		t.Errorf("unexpected link %s", links[0])
+	}
+	creds, err := utils.Base64Decode(u.User.Username())
+	if err != nil || string(creds) != "chacha20-ietf-poly1305:example" {
+		t.Errorf("userinfo = %q (%v)", creds, err)
+	}
+	if got := u.Query().Get("plugin"); got != "obfs-local;obfs=http;obfs-host=www.example.com" {
+		t.Errorf("plugin = %q", got)
+	}
+	if u, _ := url.Parse(links[1]); u.Fragment != "Server #2" || u.RawQuery != "" {
+		t.Errorf("unexpected link %s", links[1])
+	}
+}
+
+func TestSIP008_OutlineSingleServer(t *testing.T) {
+	body := `{"server":"203.0.113.5","server_port":443,"password":"pw","method":"chacha20-ietf-poly1305","prefix":"\u0016\u0003\u0001"}`
+	links, err := ExtractLinks([]byte(body))
+	if err != nil {
+		t.Fatalf("ExtractLinks() error = %v", err)
+	}
+	if len(links) != 1 {
+		t.Fatalf("expected 1 link, got %v", links)
+	}
+
+	named := NameUnnamedLinks(links, "My Key")
+	if u, _ := url.Parse(named[0]); u.Host != "203.0.113.5:443" || u.Fragment != "My Key" {
+		t.Errorf("unexpected link %s", named[0])
+	}
+}
+
+func TestSSConfFetchURL(t *testing.T) {
+	u, _ := url.Parse("ssconf://keys.example.com/abc/def.json#Outline%20Key")
+	if got := SSConfFetchURL(u).String(); got != "https://keys.example.com/abc/def.json" {
+		t.Errorf("SSConfFetchURL() = %q", got)
+	}
+	if u.Fragment != "Outline Key" {
+		t.Errorf("SSConfFetchURL modified its argument: %v", u)
+	}
+}
@@ -0,0 +1,211 @@
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+)
+
+var wgQuickInterfaceRe = regexp.MustCompile(`(?mi)^\s*\[Interface\]\s*$`)
+
+// isWgQuick reports whether body looks like a wg-quick .conf file.
+func isWgQuick(body []byte) bool {
+	return wgQuickInterfaceRe.Match(body) && bytes.Contains(bytes.ToLower(body), []byte("privatekey"))
+}
+
+// wgQuickConfig is one [Interface] section with the [Peer] sections after it.
+type wgQuickConfig struct {
+	privateKey string
+	addresses  []string
+	mtu        string
+	reserved   string
+	peers      []protocol.WireguardPeer
+}
+
+// WgQuickToLinks converts wg-quick .conf content into wireguard:// links.
+// Several configs may be concatenated; each [Interface] starts a new one.
+// Peers without an Endpoint can't be dialed and are left out. Besides the
+// standard keys, a `Reserved` key (WARP) is accepted in either section.
+func WgQuickToLinks(body []byte) ([]string, error) {
+	var configs []*wgQuickConfig
+	var cur *wgQuickConfig
+	var peer *protocol.WireguardPeer
+	section := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			switch section {
+			case "interface":
+				cur = &wgQuickConfig{}
+				configs = append(configs, cur)
+			case "peer":
+				if cur == nil {
+					return nil, fmt.Errorf("line %d: [Peer] before [Interface]", n)
+				}
+				cur.peers = append(cur.peers, protocol.WireguardPeer{})
+				peer = &cur.peers[len(cur.peers)-1]
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		switch section {
+		case "interface":
+			switch key {
+			case "privatekey":
+				cur.privateKey = value
+			case "address":
+				cur.addresses = append(cur.addresses, splitStrings(value)...)
+			case "mtu":
+				cur.mtu = value
+			case "reserved":
+				cur.reserved = value
+			}
+		case "peer":
+			switch key {
+			case "publickey":
+				peer.PublicKey = value
+			case "presharedkey":
+				peer.PreSharedKey = value
+			case "endpoint":
+				peer.Endpoint = value
+			case "allowedips":
+				peer.AllowedIPs = strings.Join(append(splitStrings(peer.AllowedIPs), splitStrings(value)...), ",")
+			case "reserved":
+				peer.Reserved = value
+			case "persistentkeepalive":
+				if value != "off" {
+					ka, err := strconv.ParseInt(value, 10, 32)
+					if err != nil {
+						return nil, fmt.Errorf("line %d: invalid PersistentKeepalive %q", n, value)
+					}
+					peer.KeepAlive = int32(ka)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var links []string
+	var errs []error
+	for i, c := range configs {
+		link, err := c.link()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("interface %d: %w", i+1, err))
+			continue
+		}
+		links = append(links, link)
+	}
+	if len(links) == 0 {
+		if len(errs) == 0 {
+			return nil, errors.New("no [Interface] section found")
+		}
+		return nil, errors.Join(errs...)
+	}
+	return links, nil
+}
+
+func (c *wgQuickConfig) link() (string, error) {
+	if c.privateKey == "" {
+		return "", errors.New("missing PrivateKey")
+	}
+
+	var peers []protocol.WireguardPeer
+	for _, p := range c.peers {
+		if p.Endpoint == "" || p.PublicKey == "" {
+			continue
+		}
+		reserved := firstNonEmpty(p.Reserved, c.reserved)
+		if reserved != "" {
+			b, err := protocol.ParseWireguardReserved(reserved)
+			if err != nil {
+				return "", err
+			}
+			reserved = protocol.FormatWireguardReserved(b)
+		}
+		p.Reserved = reserved
+		if routesEverything(p.AllowedIPs) {
+			p.AllowedIPs = ""
+		}
+		peers = append(peers, p)
+	}
+	if len(peers) == 0 {
+		return "", errors.New("no [Peer] with an Endpoint and PublicKey")
+	}
+
+	var addresses []string
+	for _, a := range c.addresses {
+		if !strings.Contains(a, "/") {
+			if strings.Contains(a, ":") {
+				a += "/128"
+			} else {
+				a += "/32"
+			}
+		}
+		addresses = append(addresses, a)
+	}
+
+	first := peers[0]
+	params := url.Values{}
+	addParam(params, "publickey", first.PublicKey)
+	addParam(params, "presharedkey", first.PreSharedKey)
+	addParam(params, "address", strings.Join(addresses, ","))
+	addParam(params, "allowedips", first.AllowedIPs)
+	addParam(params, "reserved", first.Reserved)
+	if first.KeepAlive != 0 {
+		params.Set("keepalive", strconv.Itoa(int(first.KeepAlive)))
+	}
+	addParam(params, "mtu", c.mtu)
+	for _, p := range peers[1:] {
+		params.Add(protocol.WireguardPeerParam, p.String())
+	}
+
+	u := url.URL{
+		Scheme:   protocol.WireguardIdentifier,
+		User:     url.User(c.privateKey),
+		Host:     first.Endpoint,
+		RawQuery: params.Encode(),
+	}
+	return u.String(), nil
+}
+
+// routesEverything reports whether allowedIPs is the catch-all the cores use
+// by default anyway.
+func routesEverything(allowedIPs string) bool {
+	v4, v6 := false, false
+	for _, prefix := range splitStrings(allowedIPs) {
+		switch prefix {
+		case "0.0.0.0/0":
+			v4 = true
+		case "::/0", "::0/0":
+			v6 = true
+		default:
+			return false
+		}
+	}
+	return v4 && v6
+}
@@ -0,0 +1,137 @@
+package convert
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+)
+
+const wgQuickSample = `[Interface]
+# WARP profile
+PrivateKey = cHJpdmF0ZWtleQ==
+Address = 172.16.0.2/32, 2606:4700:110:8a36::2/128
+DNS = 1.1.1.1
+MTU = 1280
+
+[Peer]
+PublicKey = cGVlcmtleQ==
+AllowedIPs = 0.0.0.0/0
+AllowedIPs = ::/0
+Endpoint = engage.cloudflareclient.com:2408
+PersistentKeepalive = 25
+Reserved = AQID
+
+[Peer]
+PublicKey = c2Vjb25k
+PresharedKey = cHNr
+AllowedIPs = 10.10.0.0/16
+Endpoint = [2001:db8::1]:51820
+`
+
+func TestWgQuickToLinks(t *testing.T) {
+	if got := DetectFormat([]byte(wgQuickSample)); got != FormatWgQuick {
+		t.Fatalf("DetectFormat() = %v, want %v", got, FormatWgQuick)
+	}
+	links, err := ExtractLinks([]byte(wgQuickSample))
+	if err != nil {
+		t.Fatalf("ExtractLinks() error = %v", err)
+	}
+	if len(links) != 1 {
+		t.Fatalf("expected 1 link, got %v", links)
+	}
+
+	u, err := url.Parse(links[0])
+	if err != nil {
+		t.Fatalf("link does not parse: %v", err)
+	}
+	if u.Scheme != "wireguard" || u.Host != "engage.cloudflareclient.com:2408" || u.User.Username() != "cHJpdmF0ZWtleQ==" {
+		t.Errorf("unexpected link %s", links[0])
+	}
+	q := u.Query()
+	want := map[string]string{
+		"publickey":  "cGVlcmtleQ==",
+		"address":    "172.16.0.2/32,2606:4700:110:8a36::2/128",
+		"reserved":   "1,2,3",
+		"keepalive":  "25",
+		"mtu":        "1280",
+		"allowedips": "",
+	}
+	for k, v := range want {
+		if got := q.Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+
+	peers := q[protocol.WireguardPeerParam]
+	if len(peers) != 1 {
+		t.Fatalf("expected 1 extra peer, got %v", peers)
+	}
+	peer, err := protocol.ParseWireguardPeer(peers[0])
+	if err != nil {
+		t.Fatalf("ParseWireguardPeer() error = %v", err)
+	}
+	wantPeer := protocol.WireguardPeer{
+		PublicKey:    "c2Vjb25k",
+		PreSharedKey: "cHNr",
+		Endpoint:     "[2001:db8::1]:51820",
+		AllowedIPs:   "10.10.0.0/16",
+	}
+	if peer != wantPeer {
+		t.Errorf("extra peer = %+v, want %+v", peer, wantPeer)
+	}
+}
+
+func TestWgQuickToLinks_Errors(t *testing.T) {
+	for name, body := range map[string]string{
+		"no endpoint":    "[Interface]\nPrivateKey = a\n[Peer]\nPublicKey = b\n",
+		"no private key": "[Interface]\nAddress = 10.0.0.2\n[Peer]\nPublicKey = b\nEndpoint = 1.2.3.4:51820\n",
+		"bad reserved":   "[Interface]\nPrivateKey = a\n[Peer]\nPublicKey = b\nEndpoint = 1.2.3.4:51820\nReserved = 1,2\n",
+	} {
+		if _, err := WgQuickToLinks([]byte(body)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestReadLinksFile_WgQuickName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "warp-de.conf")
+	if err := os.WriteFile(path, []byte(wgQuickSample), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	links, err := ReadLinksFile(path)
+	if err != nil {
+		t.Fatalf("ReadLinksFile() error = %v", err)
+	}
+	if u, _ := url.Parse(links[0]); u == nil || u.Fragment != "warp-de" {
+		t.Errorf("expected the link to be named after the file, got %s", links[0])
+	}
+}
+
+func TestWireguardPeersClashRoundTrip(t *testing.T) {
+	links, err := WgQuickToLinks([]byte(wgQuickSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := LinkToClashProxy(links[0])
+	if err != nil {
+		t.Fatalf("LinkToClashProxy() error = %v", err)
+	}
+	if got := len(p.subList("peers")); got != 2 {
+		t.Fatalf("expected 2 clash peers, got %d", got)
+	}
+
+	back, err := ClashProxyToLink(p)
+	if err != nil {
+		t.Fatalf("ClashProxyToLink() error = %v", err)
+	}
+	u, _ := url.Parse(back)
+	if got := u.Query().Get("keepalive"); got != "25" {
+		t.Errorf("keepalive = %q after round trip", got)
+	}
+	if got := u.Query()[protocol.WireguardPeerParam]; len(got) != 1 {
+		t.Errorf("extra peers lost in round trip: %s", back)
+	}
+}
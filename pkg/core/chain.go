@@ -1,9 +1,20 @@
 package core
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/singbox"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/xray"
 )
 
 // ChainConfig holds the parsed chain of proxy hops.
@@ -12,14 +23,19 @@ type ChainConfig struct {
 }
 
 // ValidateChainForCore checks whether the given chain of hops is valid
-// for the specified core engine.
+// for the specified core engine. Hops parsed by a specific core (see
+// HopCore) are checked against that core instead.
 func ValidateChainForCore(coreName string, hops []protocol.Protocol) error {
 	if len(hops) < 2 {
 		return fmt.Errorf("chain requires at least 2 hops, got %d", len(hops))
 	}
 
-	if coreName == "singbox" || coreName == "sing-box" {
-		for i, hop := range hops {
+	for i, hop := range hops {
+		hopCore := HopCore(hop)
+		if hopCore == "" {
+			hopCore = coreName
+		}
+		if hopCore == SingboxCoreName || hopCore == "sing-box" {
 			g := hop.ConvertToGeneralConfig()
 			if g.Protocol == protocol.VlessIdentifier && g.Type == "tcp" {
 				return fmt.Errorf("sing-box does not support VLESS with TCP transport in chain hop %d (%s)", i, g.Remark)
@@ -29,3 +45,259 @@ func ValidateChainForCore(coreName string, hops []protocol.Protocol) error {
 
 	return nil
 }
+
+// HopCore returns the name of the core that created p, or "" if unknown.
+func HopCore(p protocol.Protocol) string {
+	switch p.(type) {
+	case xray.Protocol:
+		return XrayCoreName
+	case singbox.Protocol:
+		return SingboxCoreName
+	}
+	return ""
+}
+
+// ChainBuilder builds proxy chains whose hops may belong to different
+// cores, e.g. a Hysteria2 hop (sing-box) in front of a VLESS-XHTTP hop
+// (xray). Each run of consecutive hops of one core becomes a segment with
+// its own core instance; a segment's last hop dials through the next
+// segment over a loopback SOCKS bridge with random credentials.
+//
+// Like the single-core chains, hop 0 receives the inbound traffic and
+// every hop dials its server through the hop after it, so only the last
+// segment touches the network. BindInterface and Fragment apply there only.
+type ChainBuilder struct {
+	entry  string
+	opts   FactoryOptions
+	parser *AutomaticCore
+}
+
+// NewChainBuilder returns a ChainBuilder whose inbound runs on entryCore
+// ("xray" or "singbox").
+func NewChainBuilder(entryCore string, opts FactoryOptions) *ChainBuilder {
+	return &ChainBuilder{
+		entry: entryCore,
+		opts:  opts,
+		// Like linkParser, hops are only created and parsed here
+		parser: &AutomaticCore{
+			xrayCore:    &xray.Core{},
+			singboxCore: &singbox.Core{},
+			policy:      PreferCorePolicy(entryCore),
+		},
+	}
+}
+
+// Parser returns a Core that creates hops on the entry core, or on the
+// other core for links only that one implements.
+func (b *ChainBuilder) Parser() Core {
+	return b.parser
+}
+
+// MakeInstance builds the chain with inbound on the entry core. The
+// returned instance starts and closes all segments together.
+func (b *ChainBuilder) MakeInstance(ctx context.Context, inbound protocol.Protocol, hops []protocol.Protocol) (protocol.Instance, error) {
+	return b.build(ctx, b.entry, inbound, hops)
+}
+
+// MakeHttpClient builds the chain behind a loopback SOCKS inbound and
+// returns an http.Client using it. The instance is already started.
+func (b *ChainBuilder) MakeHttpClient(ctx context.Context, hops []protocol.Protocol, maxDelay time.Duration) (*http.Client, protocol.Instance, error) {
+	if len(hops) == 0 {
+		return nil, nil, errors.New("chain has no hops")
+	}
+	entry := HopCore(hops[0])
+	bridge, err := newChainBridge()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	instance, err := b.build(ctx, entry, bridge.hop(entry), hops)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := instance.Start(); err != nil {
+		instance.Close()
+		return nil, nil, err
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyURL(bridge.url()),
+			DisableKeepAlives: true,
+		},
+		Timeout: maxDelay,
+	}, instance, nil
+}
+
+// chainSegment is a run of consecutive hops handled by one core.
+type chainSegment struct {
+	core string
+	hops []protocol.Protocol
+}
+
+func splitChain(hops []protocol.Protocol) []chainSegment {
+	var segments []chainSegment
+	for _, hop := range hops {
+		name := HopCore(hop)
+		if n := len(segments); n > 0 && segments[n-1].core == name {
+			segments[n-1].hops = append(segments[n-1].hops, hop)
+			continue
+		}
+		segments = append(segments, chainSegment{core: name, hops: []protocol.Protocol{hop}})
+	}
+	return segments
+}
+
+func (b *ChainBuilder) build(ctx context.Context, entry string, inbound protocol.Protocol, hops []protocol.Protocol) (protocol.Instance, error) {
+	if len(hops) == 0 {
+		return nil, errors.New("chain has no hops")
+	}
+	segments := splitChain(hops)
+	for i, seg := range segments {
+		if seg.core == "" {
+			return nil, fmt.Errorf("chain segment %d: hop of unknown core", i)
+		}
+	}
+	if segments[0].core != entry {
+		// The inbound belongs to the entry core, so it gets a segment that
+		// only bridges to the first hop.
+		segments = append([]chainSegment{{core: entry}}, segments...)
+	}
+
+	chain := &chainInstance{segments: make([]protocol.Instance, len(segments))}
+	var next *chainBridge // bridge into the segment built last
+	for i := len(segments) - 1; i >= 0; i-- {
+		seg := segments[i]
+
+		opts := b.opts
+		if next != nil {
+			// Only the last segment dials out; binding or fragmenting the
+			// loopback bridge would break it.
+			opts.BindInterface, opts.Fragment = "", nil
+		}
+
+		in := inbound
+		var bridge *chainBridge
+		if i > 0 {
+			var err error
+			if bridge, err = newChainBridge(); err != nil {
+				chain.Close()
+				return nil, err
+			}
+			in = bridge.hop(seg.core)
+		}
+
+		outs := seg.hops
+		if next != nil {
+			outs = append(append([]protocol.Protocol{}, seg.hops...), next.hop(seg.core))
+		}
+
+		instance, err := makeSegmentInstance(ctx, seg.core, opts, in, outs)
+		if err != nil {
+			chain.Close()
+			return nil, fmt.Errorf("chain segment %d (%s): %w", i, seg.core, err)
+		}
+		chain.segments[i] = instance
+		next = bridge
+	}
+	return chain, nil
+}
+
+// makeSegmentInstance builds the core instance of one segment on a core of
+// its own, since the inbound is per core.
+func makeSegmentInstance(ctx context.Context, coreName string, opts FactoryOptions, inbound protocol.Protocol, outs []protocol.Protocol) (protocol.Instance, error) {
+	switch coreName {
+	case XrayCoreName:
+		c := xray.NewXrayService(opts.Verbose, opts.InsecureTLS, opts.xrayServiceOptions()...)
+		if err := c.SetInbound(inbound); err != nil {
+			return nil, err
+		}
+		if len(outs) == 1 {
+			return c.MakeInstance(ctx, outs[0])
+		}
+		return c.MakeChainedInstance(ctx, outs)
+	case SingboxCoreName:
+		c := singbox.NewSingboxService(opts.Verbose, opts.InsecureTLS, opts.singboxServiceOptions()...)
+		if err := c.SetInbound(inbound); err != nil {
+			return nil, err
+		}
+		if len(outs) == 1 {
+			return c.MakeInstance(ctx, outs[0])
+		}
+		return c.MakeChainedInstance(ctx, outs)
+	}
+	return nil, fmt.Errorf("unknown core %q", coreName)
+}
+
+// chainBridge is a loopback SOCKS listener connecting two chain segments.
+type chainBridge struct {
+	port     string
+	username string
+	password string
+}
+
+func newChainBridge() (*chainBridge, error) {
+	// Grab a free port; the segment's inbound binds it right after
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("chain bridge: %w", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("chain bridge: %w", err)
+	}
+	return &chainBridge{
+		port:     strconv.Itoa(port),
+		username: hex.EncodeToString(secret[:8]),
+		password: hex.EncodeToString(secret[8:]),
+	}, nil
+}
+
+// hop returns the bridge as a SOCKS protocol of coreName, serving both as
+// the listening segment's inbound and as the dialing segment's last hop.
+func (b *chainBridge) hop(coreName string) protocol.Protocol {
+	if coreName == SingboxCoreName {
+		return &singbox.Socks{Remark: "chain-bridge", Address: "127.0.0.1", Port: b.port, Username: b.username, Password: b.password}
+	}
+	return &xray.Socks{Remark: "chain-bridge", Address: "127.0.0.1", Port: b.port, Username: b.username, Password: b.password}
+}
+
+func (b *chainBridge) url() *url.URL {
+	return &url.URL{
+		Scheme: "socks5",
+		User:   url.UserPassword(b.username, b.password),
+		Host:   net.JoinHostPort("127.0.0.1", b.port),
+	}
+}
+
+// chainInstance runs the segments of a mixed-core chain as one instance.
+type chainInstance struct {
+	segments []protocol.Instance
+}
+
+func (c *chainInstance) Start() error {
+	// Start from the dialing end so every bridge listens before the
+	// segment in front of it can use it.
+	for i := len(c.segments) - 1; i >= 0; i-- {
+		if err := c.segments[i].Start(); err != nil {
+			return fmt.Errorf("chain segment %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (c *chainInstance) Close() error {
+	var errs []error
+	for _, s := range c.segments {
+		if s == nil {
+			continue
+		}
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
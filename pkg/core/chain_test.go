@@ -0,0 +1,166 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/singbox"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/xray"
+)
+
+func TestSplitChain(t *testing.T) {
+	hops := []protocol.Protocol{
+		&singbox.Hysteria2{},
+		&singbox.Vless{},
+		&xray.Vless{},
+		&singbox.Vless{},
+	}
+	segments := splitChain(hops)
+
+	want := []struct {
+		core string
+		hops int
+	}{
+		{SingboxCoreName, 2},
+		{XrayCoreName, 1},
+		{SingboxCoreName, 1},
+	}
+	if len(segments) != len(want) {
+		t.Fatalf("splitChain() returned %d segments, want %d", len(segments), len(want))
+	}
+	for i, w := range want {
+		if segments[i].core != w.core || len(segments[i].hops) != w.hops {
+			t.Errorf("segment %d = %s with %d hops, want %s with %d", i, segments[i].core, len(segments[i].hops), w.core, w.hops)
+		}
+	}
+}
+
+func TestValidateChainForCoreUsesHopCore(t *testing.T) {
+	// A VLESS-TCP hop parsed by xray is fine in a chain run from sing-box.
+	hops := []protocol.Protocol{
+		&singbox.Hysteria2{},
+		&xray.Vless{Type: "tcp"},
+	}
+	if err := ValidateChainForCore(SingboxCoreName, hops); err != nil {
+		t.Errorf("ValidateChainForCore() = %v, want nil", err)
+	}
+
+	hops[1] = &singbox.Vless{Type: "tcp"}
+	if err := ValidateChainForCore(XrayCoreName, hops); err == nil {
+		t.Error("ValidateChainForCore() = nil for sing-box VLESS-TCP, want error")
+	}
+}
+
+func TestChainBuilderMixedCores(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer target.Close()
+
+	// Both hops are SOCKS servers on loopback: the sing-box hop connects to
+	// it and the xray hop reaches it again through the sing-box hop.
+	_, port, _ := net.SplitHostPort(startSocksServer(t))
+	hops := []protocol.Protocol{
+		&xray.Socks{Remark: "xray-hop", Address: "127.0.0.1", Port: port},
+		&singbox.Socks{Remark: "singbox-hop", Address: "127.0.0.1", Port: port},
+	}
+
+	b := NewChainBuilder(XrayCoreName, FactoryOptions{})
+	client, instance, err := b.MakeHttpClient(context.Background(), hops, 5*time.Second)
+	if err != nil {
+		t.Fatalf("MakeHttpClient() = %v", err)
+	}
+	defer instance.Close()
+
+	resp, err := client.Get(target.URL)
+	if err != nil {
+		t.Fatalf("GET through the chain: %v", err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); string(body) != "ok" {
+		t.Errorf("body = %q, want %q", body, "ok")
+	}
+}
+
+// startSocksServer serves unauthenticated SOCKS5 CONNECT on loopback and
+// returns its address.
+func startSocksServer(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveSocks(conn)
+		}
+	}()
+	return l.Addr().String()
+}
+
+func serveSocks(conn net.Conn) {
+	defer conn.Close()
+	buf := make([]byte, 262)
+	// Greeting: version, method count, methods; pick "no authentication"
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil || buf[0] != 5 {
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+		return
+	}
+	conn.Write([]byte{5, 0})
+
+	// Request: version, CONNECT, reserved, address type
+	if _, err := io.ReadFull(conn, buf[:4]); err != nil || buf[1] != 1 {
+		return
+	}
+	var host string
+	switch buf[3] {
+	case 1, 4:
+		ip := make(net.IP, 4)
+		if buf[3] == 4 {
+			ip = make(net.IP, 16)
+		}
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return
+		}
+		host = ip.String()
+	case 3:
+		if _, err := io.ReadFull(conn, buf[:1]); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, buf[1:1+buf[0]]); err != nil {
+			return
+		}
+		host = string(buf[1 : 1+buf[0]])
+	default:
+		return
+	}
+	if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+		return
+	}
+	port := int(buf[0])<<8 | int(buf[1])
+
+	upstream, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer upstream.Close()
+	conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	go io.Copy(upstream, conn)
+	io.Copy(conn, upstream)
+}
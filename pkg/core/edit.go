@@ -0,0 +1,34 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+)
+
+// EditProtocol applies e to p and returns the edited config, created by c
+// from the rewritten link so its GetLink is the new share link. e.Remark
+// may be a template (see protocol.RenderRemark); its variables describe p
+// and can be extended by vars, e.g. with "country" and "delay".
+func EditProtocol(c Core, p protocol.Protocol, e protocol.Edits, vars map[string]string) (protocol.Protocol, error) {
+	if e.Remark != "" {
+		all := protocol.RemarkVars(p.ConvertToGeneralConfig())
+		for k, v := range vars {
+			all[k] = v
+		}
+		e.Remark = protocol.RenderRemark(e.Remark, all)
+	}
+
+	link, err := protocol.EditLink(p.GetLink(), e)
+	if err != nil {
+		return nil, err
+	}
+	edited, err := c.CreateProtocol(link)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create edited config: %w", err)
+	}
+	if err := edited.Parse(); err != nil {
+		return nil, fmt.Errorf("edited config is invalid: %w", err)
+	}
+	return edited, nil
+}
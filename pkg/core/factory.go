@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"net/url"
 	"time"
 
 	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
@@ -38,6 +37,33 @@ type FactoryOptions struct {
 	// BindInterface pins all outbound dials of the constructed core to
 	// the named OS interface (e.g. "eth0"). Empty disables binding.
 	BindInterface string
+	// Fragment, when set, fragments the TLS ClientHello of every outbound
+	// (and sends noise packets ahead of UDP traffic on xray-core) to get
+	// past SNI-matching DPI.
+	Fragment *protocol.FragmentOptions
+	// Mux, when set, overrides the multiplexing settings of every outbound
+	// regardless of what the links say (Enabled=false forces it off).
+	// nil leaves mux to the links.
+	Mux *protocol.MuxOptions
+	// CorePolicy picks the core per protocol and transport for the
+	// automatic core. nil uses DefaultCoreRules with fallback on.
+	CorePolicy *CorePolicy
+}
+
+func (o FactoryOptions) xrayServiceOptions() []xray.ServiceOption {
+	return []xray.ServiceOption{
+		xray.WithBindInterface(o.BindInterface),
+		xray.WithFragment(o.Fragment),
+		xray.WithMux(o.Mux),
+	}
+}
+
+func (o FactoryOptions) singboxServiceOptions() []singbox.ServiceOption {
+	return []singbox.ServiceOption{
+		singbox.WithBindInterface(o.BindInterface),
+		singbox.WithFragment(o.Fragment),
+		singbox.WithMux(o.Mux),
+	}
 }
 
 // CoreFactory is the factory method to create concrete cores.
@@ -49,19 +75,20 @@ func CoreFactory(coreType CoreType, insecureTLS bool, verbose bool) Core {
 func CoreFactoryWith(coreType CoreType, opts FactoryOptions) Core {
 	switch coreType {
 	case XrayCoreType:
-		return xray.NewXrayService(opts.Verbose, opts.InsecureTLS, xray.WithBindInterface(opts.BindInterface))
+		return xray.NewXrayService(opts.Verbose, opts.InsecureTLS, opts.xrayServiceOptions()...)
 	case SingboxCoreType:
-		return singbox.NewSingboxService(opts.Verbose, opts.InsecureTLS, singbox.WithBindInterface(opts.BindInterface))
+		return singbox.NewSingboxService(opts.Verbose, opts.InsecureTLS, opts.singboxServiceOptions()...)
 	default:
 		return nil
 	}
 }
 
 // AutomaticCore implementation of the Core interface
-// Selects Core based on the config link
+// Selects Core based on the config link and its CorePolicy
 type AutomaticCore struct {
 	xrayCore    Core
 	singboxCore Core
+	policy      *CorePolicy
 }
 
 func (c *AutomaticCore) Name() string {
@@ -74,57 +101,151 @@ func NewAutomaticCore(verbose bool, allowInsecure bool) Core {
 
 // NewAutomaticCoreWith builds an AutomaticCore with the given options
 // (e.g. a BindInterface that should apply to both xray and sing-box).
-func NewAutomaticCoreWith(opts FactoryOptions) Core {
+func NewAutomaticCoreWith(opts FactoryOptions) *AutomaticCore {
 	return &AutomaticCore{
-		xrayCore:    xray.NewXrayService(opts.Verbose, opts.InsecureTLS, xray.WithBindInterface(opts.BindInterface)),
-		singboxCore: singbox.NewSingboxService(opts.Verbose, opts.InsecureTLS, singbox.WithBindInterface(opts.BindInterface)),
+		xrayCore:    xray.NewXrayService(opts.Verbose, opts.InsecureTLS, opts.xrayServiceOptions()...),
+		singboxCore: singbox.NewSingboxService(opts.Verbose, opts.InsecureTLS, opts.singboxServiceOptions()...),
+		policy:      opts.CorePolicy,
 	}
 }
 
-// selectCoreForLink is a helper to determine which core to use based on the protocol scheme.
-func (c *AutomaticCore) selectCoreForLink(configLink string) (Core, error) {
-	uri, err := url.Parse(configLink)
-	if err != nil {
-		return nil, err
+// coresFor returns the cores to try for a link, in order: the one the
+// policy picks, then the other one unless fallback is off.
+func (c *AutomaticCore) coresFor(configLink string) []Core {
+	if c.policy.CoreFor(configLink) == SingboxCoreName {
+		if !c.policy.Fallback() {
+			return []Core{c.singboxCore}
+		}
+		return []Core{c.singboxCore, c.xrayCore}
 	}
+	if !c.policy.Fallback() {
+		return []Core{c.xrayCore}
+	}
+	return []Core{c.xrayCore, c.singboxCore}
+}
 
-	switch uri.Scheme {
-	case protocol.Hysteria2Identifier, "hy2":
-		return c.singboxCore, nil
-	case protocol.VmessIdentifier, protocol.VlessIdentifier, protocol.TrojanIdentifier, protocol.ShadowsocksIdentifier, protocol.SocksIdentifier, protocol.WireguardIdentifier:
-		return c.xrayCore, nil
-	default:
-		return nil, fmt.Errorf("unsupported protocol for automatic core: %s", uri.Scheme)
+// owns reports whether p was created by sub-core sub, i.e. whether sub can
+// build it without re-creating it from its link.
+func (c *AutomaticCore) owns(sub Core, p protocol.Protocol) bool {
+	switch p.(type) {
+	case xray.Protocol:
+		return sub == c.xrayCore
+	case singbox.Protocol:
+		return sub == c.singboxCore
 	}
+	return false
 }
 
 // CreateProtocol for AutomaticCore dispatches to the correct underlying core.
 func (c *AutomaticCore) CreateProtocol(configLink string) (protocol.Protocol, error) {
-	selectedCore, err := c.selectCoreForLink(configLink)
-	if err != nil {
-		return nil, err
+	for _, sub := range c.coresFor(configLink) {
+		if p, err := sub.CreateProtocol(configLink); err == nil {
+			return p, nil
+		}
 	}
-	return selectedCore.CreateProtocol(configLink)
+	scheme, _ := linkRouteKey(configLink)
+	return nil, fmt.Errorf("unsupported protocol for automatic core: %s", scheme)
 }
 
-// MakeHttpClient dispatches to the correct underlying core.
+// MakeHttpClient dispatches to the correct underlying core, falling back to
+// the other one when it fails.
 func (c *AutomaticCore) MakeHttpClient(ctx context.Context, outbound protocol.Protocol, maxDelay time.Duration) (*http.Client, protocol.Instance, error) {
-	generalConfig := outbound.ConvertToGeneralConfig()
-	selectedCore, err := c.selectCoreForLink(generalConfig.OrigLink)
+	return c.makeHttpClient(ctx, outbound.ConvertToGeneralConfig().OrigLink, outbound, nil, maxDelay)
+}
+
+// MakeHttpClientForLink is MakeHttpClient for a link that isn't parsed yet.
+// prepare, when non-nil, is run on the protocol each core parses before its
+// client is built (e.g. to override the server address).
+func (c *AutomaticCore) MakeHttpClientForLink(ctx context.Context, configLink string, maxDelay time.Duration, prepare func(protocol.Protocol) error) (*http.Client, protocol.Instance, error) {
+	return c.makeHttpClient(ctx, configLink, nil, prepare, maxDelay)
+}
+
+func (c *AutomaticCore) makeHttpClient(ctx context.Context, configLink string, outbound protocol.Protocol, prepare func(protocol.Protocol) error, maxDelay time.Duration) (*http.Client, protocol.Instance, error) {
+	var client *http.Client
+	instance, err := c.tryCores(configLink, outbound, prepare, func(sub Core, p protocol.Protocol) (protocol.Instance, error) {
+		var (
+			inst protocol.Instance
+			err  error
+		)
+		client, inst, err = sub.MakeHttpClient(ctx, p, maxDelay)
+		return inst, err
+	})
 	if err != nil {
 		return nil, nil, err
 	}
-	return selectedCore.MakeHttpClient(ctx, outbound, maxDelay)
+	return client, instance, nil
 }
 
-// MakeInstance dispatches to the correct underlying core.
+// MakeInstance dispatches to the correct underlying core, falling back to
+// the other one when it fails.
 func (c *AutomaticCore) MakeInstance(ctx context.Context, outbound protocol.Protocol) (protocol.Instance, error) {
-	generalConfig := outbound.ConvertToGeneralConfig()
-	selectedCore, err := c.selectCoreForLink(generalConfig.OrigLink)
+	return c.tryCores(outbound.ConvertToGeneralConfig().OrigLink, outbound, nil, func(sub Core, p protocol.Protocol) (protocol.Instance, error) {
+		return sub.MakeInstance(ctx, p)
+	})
+}
+
+// tryCores runs build on the cores coresFor picks until one succeeds.
+// outbound (when non-nil) is handed to the core that created it; the other
+// core gets its own protocol parsed from configLink. The error of the
+// preferred core is returned when all of them fail.
+func (c *AutomaticCore) tryCores(configLink string, outbound protocol.Protocol, prepare func(protocol.Protocol) error, build func(Core, protocol.Protocol) (protocol.Instance, error)) (protocol.Instance, error) {
+	var firstErr error
+	for _, sub := range c.coresFor(configLink) {
+		p := outbound
+		if p == nil || !c.owns(sub, p) {
+			var err error
+			if p, err = createAndParse(sub, configLink, prepare); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+		}
+
+		instance, err := build(sub, p)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			} else {
+				firstErr = fmt.Errorf("%w (%s fallback: %v)", firstErr, sub.Name(), err)
+			}
+			continue
+		}
+		return &coreInstance{Instance: instance, core: sub.Name()}, nil
+	}
+	return nil, firstErr
+}
+
+func createAndParse(sub Core, configLink string, prepare func(protocol.Protocol) error) (protocol.Protocol, error) {
+	p, err := sub.CreateProtocol(configLink)
 	if err != nil {
 		return nil, err
 	}
-	return selectedCore.MakeInstance(ctx, outbound)
+	if err := p.Parse(); err != nil {
+		return nil, err
+	}
+	if prepare != nil {
+		if err := prepare(p); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// coreInstance records which sub-core of an AutomaticCore built an instance.
+type coreInstance struct {
+	protocol.Instance
+	core string
+}
+
+// UsedCore returns the name of the core that built instance, which c
+// returned. For an AutomaticCore that is the core the policy, or its
+// fallback, picked; otherwise it is c itself.
+func UsedCore(c Core, instance protocol.Instance) string {
+	if ci, ok := instance.(*coreInstance); ok {
+		return ci.core
+	}
+	return c.Name()
 }
 
 // SetInbound is not applicable for the AutomaticCore itself.
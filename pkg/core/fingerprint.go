@@ -0,0 +1,36 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/singbox"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/xray"
+)
+
+// linkParser only creates and parses protocols, so it is built from bare
+// cores to avoid the logging setup NewXrayService/NewSingboxService do.
+var linkParser = &AutomaticCore{
+	xrayCore:    &xray.Core{},
+	singboxCore: &singbox.Core{},
+}
+
+// LinkFingerprint parses link and returns the fingerprint of the server it
+// points at (see protocol.GeneralConfig.Fingerprint). Links are always parsed
+// the same way regardless of the core used later, so fingerprints stay stable.
+func LinkFingerprint(link string) (fp string, err error) {
+	// Malformed links must not crash the caller
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to parse config: %v", r)
+		}
+	}()
+
+	p, err := linkParser.CreateProtocol(link)
+	if err != nil {
+		return "", err
+	}
+	if err := p.Parse(); err != nil {
+		return "", err
+	}
+	return p.ConvertToGeneralConfig().Fingerprint(), nil
+}
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+)
+
+// LintReport is the lint result of one share link.
+type LintReport struct {
+	Link        string                `json:"link"`
+	Protocol    string                `json:"protocol,omitempty"`
+	Status      string                `json:"status"` // ok, warning or error
+	Diagnostics []protocol.Diagnostic `json:"diagnostics"`
+}
+
+// LintLink parses link with c (nil parses it like LinkFingerprint does) and
+// runs the lint rules on it. A link that fails to parse gets a parse-error
+// diagnostic and is then linted as read off the raw URL, so all of its
+// problems are reported at once rather than just the first.
+func LintLink(c Core, link string) (r LintReport) {
+	link = strings.TrimSpace(link)
+	r = LintReport{Link: link, Diagnostics: []protocol.Diagnostic{}}
+	defer func() {
+		// Malformed links must not crash the caller
+		if rec := recover(); rec != nil {
+			r.Diagnostics = append(r.Diagnostics, protocol.Diagnostic{
+				Severity: protocol.SeverityError,
+				Code:     protocol.LintParseError,
+				Message:  fmt.Sprintf("failed to parse config: %v", rec),
+			})
+		}
+		r.Status = protocol.LintStatus(r.Diagnostics)
+	}()
+
+	if c == nil {
+		c = linkParser
+	}
+	p, err := c.CreateProtocol(link)
+	if err != nil {
+		r.Diagnostics = append(r.Diagnostics, protocol.Diagnostic{
+			Severity: protocol.SeverityError,
+			Code:     protocol.LintUnsupportedProtocol,
+			Message:  err.Error(),
+		})
+		return r
+	}
+
+	if err := p.Parse(); err != nil {
+		r.Diagnostics = append(r.Diagnostics, protocol.Diagnostic{
+			Severity: protocol.SeverityError,
+			Code:     protocol.LintParseError,
+			Message:  err.Error(),
+		})
+		if f, ok := protocol.LintFieldsFromURL(link); ok {
+			// Keep the core specific knobs of the (unparsed) protocol
+			if l, ok := p.(protocol.Linter); ok {
+				known := l.LintFields()
+				f.KnownFingerprint, f.MapsNonUUID = known.KnownFingerprint, known.MapsNonUUID
+			}
+			r.Diagnostics = append(r.Diagnostics, f.Lint()...)
+		}
+		return r
+	}
+
+	r.Protocol = p.ConvertToGeneralConfig().Protocol
+	r.Diagnostics = append(r.Diagnostics, protocol.LintProtocol(p)...)
+	return r
+}
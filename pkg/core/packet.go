@@ -0,0 +1,33 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/singbox"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/xray"
+)
+
+// ListenPacket opens a UDP connection to destination (ip:port) through
+// instance, which c's MakeHttpClient built for outbound. Packets should
+// only be sent to destination, and the connection may ignore deadlines:
+// close it to unblock a read. It returns protocol.ErrUDPUnsupported when
+// the outbound only carries TCP.
+func ListenPacket(ctx context.Context, c Core, instance protocol.Instance, outbound protocol.Protocol, destination string) (net.PacketConn, error) {
+	if outbound.ConvertToGeneralConfig().Protocol == "http" {
+		return nil, protocol.ErrUDPUnsupported
+	}
+	used := UsedCore(c, instance)
+	if ci, ok := instance.(*coreInstance); ok {
+		instance = ci.Instance
+	}
+	switch used {
+	case XrayCoreName:
+		return xray.ListenPacket(ctx, instance)
+	case SingboxCoreName:
+		return singbox.ListenPacket(ctx, instance, destination)
+	}
+	return nil, fmt.Errorf("unknown core %q", used)
+}
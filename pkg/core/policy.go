@@ -0,0 +1,247 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+	"github.com/lilendian0x00/xray-knife/v10/utils"
+)
+
+// Core names used in policies, matching Core.Name().
+const (
+	XrayCoreName    = "xray"
+	SingboxCoreName = "singbox"
+)
+
+// CoreRule routes links of a protocol, and optionally a transport, to a
+// core.
+type CoreRule struct {
+	// Protocol is the link scheme (vless, vmess, ss, hysteria2, ...); "*"
+	// matches any.
+	Protocol string `json:"protocol"`
+	// Transport is the link's type/net (ws, grpc, xhttp, ...), or "plugin"
+	// for Shadowsocks links with a SIP003 plugin. Empty matches any.
+	Transport string `json:"transport,omitempty"`
+	// Core is "xray" or "singbox".
+	Core string `json:"core"`
+}
+
+func (r CoreRule) String() string {
+	if r.Transport != "" {
+		return fmt.Sprintf("%s/%s=%s", r.Protocol, r.Transport, r.Core)
+	}
+	return fmt.Sprintf("%s=%s", r.Protocol, r.Core)
+}
+
+func (r CoreRule) matches(scheme, transport string) bool {
+	if r.Protocol != "*" && !strings.EqualFold(r.Protocol, scheme) {
+		return false
+	}
+	return r.Transport == "" || strings.EqualFold(r.Transport, transport)
+}
+
+// exclusiveCoreRules send what only one of the cores implements to it.
+var exclusiveCoreRules = []CoreRule{
+	{Protocol: protocol.Hysteria2Identifier, Core: SingboxCoreName},
+	{Protocol: "hy2", Core: SingboxCoreName},
+	{Protocol: protocol.TuicIdentifier, Core: SingboxCoreName},
+	{Protocol: protocol.ShadowsocksIdentifier, Transport: "plugin", Core: SingboxCoreName}, // SIP003 plugins
+	{Protocol: "*", Transport: "xhttp", Core: XrayCoreName},                                // sing-box has no XHTTP
+}
+
+// DefaultCoreRules are consulted after the rules of a CorePolicy. They
+// send what only sing-box implements to sing-box and the rest to xray.
+var DefaultCoreRules = append(append([]CoreRule{}, exclusiveCoreRules...), CoreRule{Protocol: "*", Core: XrayCoreName})
+
+// PreferCorePolicy returns a policy that picks coreName for every link
+// except those only the other core implements.
+func PreferCorePolicy(coreName string) *CorePolicy {
+	return &CorePolicy{Rules: append(append([]CoreRule{}, exclusiveCoreRules...), CoreRule{Protocol: "*", Core: coreName})}
+}
+
+// CorePolicy decides which core the AutomaticCore uses for a link. The
+// first matching rule wins; DefaultCoreRules apply after Rules.
+type CorePolicy struct {
+	Rules []CoreRule `json:"rules,omitempty"`
+	// NoFallback stops the AutomaticCore from retrying a link on the other
+	// core when the chosen one fails to build an instance for it.
+	NoFallback bool `json:"noFallback,omitempty"`
+}
+
+// CoreFor returns the name of the core the policy picks for link. A nil
+// policy only uses DefaultCoreRules.
+func (p *CorePolicy) CoreFor(link string) string {
+	scheme, transport := linkRouteKey(link)
+	if p != nil {
+		for _, r := range p.Rules {
+			if r.matches(scheme, transport) {
+				return r.Core
+			}
+		}
+	}
+	for _, r := range DefaultCoreRules {
+		if r.matches(scheme, transport) {
+			return r.Core
+		}
+	}
+	return XrayCoreName
+}
+
+// Fallback reports whether a failing link may be retried on the other core.
+func (p *CorePolicy) Fallback() bool {
+	return p == nil || !p.NoFallback
+}
+
+func (p *CorePolicy) String() string {
+	if p == nil || (len(p.Rules) == 0 && !p.NoFallback) {
+		return "default"
+	}
+	parts := make([]string, 0, len(p.Rules)+1)
+	for _, r := range p.Rules {
+		parts = append(parts, r.String())
+	}
+	if p.NoFallback {
+		parts = append(parts, "fallback=off")
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseCorePolicy parses the CLI form of a policy: comma or newline
+// separated "protocol[/transport]=core" rules plus an optional
+// "fallback=off", e.g. "vless/ws=singbox,trojan=singbox". Lines starting
+// with # are ignored. Empty returns nil, the default policy.
+func ParseCorePolicy(spec string) (*CorePolicy, error) {
+	p := &CorePolicy{}
+	for _, line := range strings.Split(spec, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, entry := range strings.Split(line, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(entry, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid core policy rule %q: want protocol[/transport]=core", entry)
+			}
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+			if strings.EqualFold(key, "fallback") {
+				switch strings.ToLower(value) {
+				case "on", "true", "1":
+					p.NoFallback = false
+				case "off", "false", "0":
+					p.NoFallback = true
+				default:
+					return nil, fmt.Errorf("invalid core policy fallback %q: want on or off", value)
+				}
+				continue
+			}
+
+			r := CoreRule{Core: value}
+			r.Protocol, r.Transport, _ = strings.Cut(key, "/")
+			if err := r.validate(); err != nil {
+				return nil, err
+			}
+			p.Rules = append(p.Rules, r)
+		}
+	}
+	if len(p.Rules) == 0 && !p.NoFallback {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// LoadCorePolicy parses arg as a policy when it contains rules ("="), or
+// reads it from the file named by arg otherwise. Files hold either the CLI
+// form, one or more rules per line, or JSON: {"rules": [{"protocol":
+// "vless", "transport": "ws", "core": "singbox"}], "noFallback": false}.
+func LoadCorePolicy(arg string) (*CorePolicy, error) {
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		return nil, nil
+	}
+	if strings.Contains(arg, "=") {
+		return ParseCorePolicy(arg)
+	}
+	body, err := os.ReadFile(arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read core policy file: %w", err)
+	}
+
+	if trimmed := strings.TrimSpace(string(body)); strings.HasPrefix(trimmed, "{") {
+		p := &CorePolicy{}
+		if err := json.Unmarshal(body, p); err != nil {
+			return nil, fmt.Errorf("invalid core policy %q: %w", arg, err)
+		}
+		for i := range p.Rules {
+			if err := p.Rules[i].validate(); err != nil {
+				return nil, fmt.Errorf("invalid core policy %q: %w", arg, err)
+			}
+		}
+		return p, nil
+	}
+	p, err := ParseCorePolicy(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("invalid core policy %q: %w", arg, err)
+	}
+	return p, nil
+}
+
+// validate normalizes the core name and checks the rule is complete.
+func (r *CoreRule) validate() error {
+	r.Protocol = strings.ToLower(strings.TrimSpace(r.Protocol))
+	r.Transport = strings.ToLower(strings.TrimSpace(r.Transport))
+	if r.Protocol == "" {
+		return fmt.Errorf("core policy rule %q has no protocol", r.String())
+	}
+	switch strings.ToLower(strings.TrimSpace(r.Core)) {
+	case "xray":
+		r.Core = XrayCoreName
+	case "singbox", "sing-box":
+		r.Core = SingboxCoreName
+	default:
+		return fmt.Errorf("core policy rule %q: unknown core %q (want xray or singbox)", r.String(), r.Core)
+	}
+	return nil
+}
+
+// linkRouteKey extracts what policies match on from a link: its scheme and
+// transport. It doesn't parse the link fully, so it works for links either
+// core may fail on.
+func linkRouteKey(link string) (scheme, transport string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(link), "://")
+	scheme = strings.ToLower(scheme)
+
+	if scheme == protocol.VmessIdentifier {
+		// vmess://base64(JSON)
+		b64, _, _ := strings.Cut(rest, "#")
+		if decoded, err := utils.Base64Decode(b64); err == nil {
+			var v struct {
+				Net string `json:"net"`
+			}
+			if json.Unmarshal(decoded, &v) == nil {
+				return scheme, strings.ToLower(v.Net)
+			}
+		}
+	}
+
+	// Port-hopping Hysteria2 links ("host:443,20000-30000") are not valid
+	// URLs, so only the query is looked at.
+	_, query, _ := strings.Cut(rest, "?")
+	query, _, _ = strings.Cut(query, "#")
+	q, _ := url.ParseQuery(query)
+	if scheme == protocol.ShadowsocksIdentifier && q.Get("plugin") != "" {
+		return scheme, "plugin"
+	}
+	if t := q.Get("type"); t != "" {
+		return scheme, strings.ToLower(t)
+	}
+	return scheme, strings.ToLower(q.Get("net"))
+}
@@ -0,0 +1,49 @@
+package core
+
+import "testing"
+
+func TestCorePolicyCoreFor(t *testing.T) {
+	policy, err := ParseCorePolicy("vless/ws=sing-box, trojan=singbox\n# comment\nfallback=off")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.Fallback() {
+		t.Error("Fallback() = true with fallback=off")
+	}
+	if got, want := policy.String(), "vless/ws=singbox,trojan=singbox,fallback=off"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		link        string
+		withPolicy  string
+		withDefault string
+	}{
+		{link: "vless://id@example.com:443?type=ws", withPolicy: SingboxCoreName, withDefault: XrayCoreName},
+		{link: "vless://id@example.com:443?type=xhttp", withPolicy: XrayCoreName, withDefault: XrayCoreName},
+		{link: "trojan://pass@example.com:443", withPolicy: SingboxCoreName, withDefault: XrayCoreName},
+		{link: "hy2://pass@example.com:443,20000-30000?sni=a.com", withPolicy: SingboxCoreName, withDefault: SingboxCoreName},
+		{link: "ss://YWVzLTEyOC1nY206cGFzcw@example.com:8388?plugin=obfs-local", withPolicy: SingboxCoreName, withDefault: SingboxCoreName},
+		{link: "ss://YWVzLTEyOC1nY206cGFzcw@example.com:8388", withPolicy: XrayCoreName, withDefault: XrayCoreName},
+		{link: "vmess://eyJuZXQiOiJ3cyJ9", withPolicy: XrayCoreName, withDefault: XrayCoreName}, // {"net":"ws"}
+	}
+	for _, tt := range tests {
+		if got := policy.CoreFor(tt.link); got != tt.withPolicy {
+			t.Errorf("CoreFor(%q) = %q, want %q", tt.link, got, tt.withPolicy)
+		}
+		if got := (*CorePolicy)(nil).CoreFor(tt.link); got != tt.withDefault {
+			t.Errorf("default CoreFor(%q) = %q, want %q", tt.link, got, tt.withDefault)
+		}
+	}
+}
+
+func TestParseCorePolicyErrors(t *testing.T) {
+	for _, spec := range []string{"vless", "vless=v2ray", "/ws=xray", "fallback=maybe"} {
+		if _, err := ParseCorePolicy(spec); err == nil {
+			t.Errorf("ParseCorePolicy(%q): want error", spec)
+		}
+	}
+	if p, err := ParseCorePolicy(" \n# nothing\n"); err != nil || p != nil {
+		t.Errorf("ParseCorePolicy(empty) = %v, %v; want nil, nil", p, err)
+	}
+}
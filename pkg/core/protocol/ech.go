@@ -0,0 +1,232 @@
+package protocol
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Share link parameters carrying an ECH config. Both names are in use;
+// ECHParam wins when a link has both.
+const (
+	ECHParam           = "ech"
+	ECHConfigListParam = "echConfigList"
+)
+
+// ParseECHParam reads the ECH link parameter and checks it is either a
+// base64 ECHConfigList or a DNS query spec (see ParseECHQuery). It returns
+// "" when the link carries none.
+func ParseECHParam(q url.Values) (string, error) {
+	ech := strings.TrimSpace(q.Get(ECHParam))
+	if ech == "" {
+		ech = strings.TrimSpace(q.Get(ECHConfigListParam))
+	}
+	if ech == "" {
+		return "", nil
+	}
+	if err := ValidateECH(ech); err != nil {
+		return "", err
+	}
+	return ech, nil
+}
+
+// ValidateECH checks an ECH value as accepted by ParseECHParam.
+func ValidateECH(ech string) error {
+	if IsECHQuery(ech) {
+		_, _, err := ParseECHQuery(ech)
+		return err
+	}
+	if _, err := base64.StdEncoding.DecodeString(ech); err != nil {
+		return fmt.Errorf("invalid %s: not base64 and not a DNS server: %w", ECHParam, err)
+	}
+	return nil
+}
+
+// IsECHQuery reports whether ech asks for the config to be looked up in
+// DNS rather than carrying it inline.
+func IsECHQuery(ech string) bool {
+	return strings.Contains(ech, "://")
+}
+
+// ParseECHQuery splits an ECH DNS query spec, in xray-core's format:
+// "https://1.1.1.1/dns-query" or "udp://1.1.1.1:53" queries the server
+// name of the connection, "example.com+https://1.1.1.1/dns-query" queries
+// example.com instead. name is "" when the spec doesn't set one.
+func ParseECHQuery(ech string) (name, server string, err error) {
+	server = ech
+	if before, after, ok := strings.Cut(ech, "+"); ok {
+		name, server = before, after
+		if strings.Contains(server, "+") {
+			return "", "", fmt.Errorf("invalid ECH DNS server %q", ech)
+		}
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid ECH DNS server %q: %w", ech, err)
+	}
+	switch u.Scheme {
+	case "https", "udp", "tcp":
+	default:
+		return "", "", fmt.Errorf("invalid ECH DNS server %q: want https://, udp:// or tcp://", ech)
+	}
+	if u.Host == "" {
+		return "", "", fmt.Errorf("invalid ECH DNS server %q: missing host", ech)
+	}
+	return name, server, nil
+}
+
+// ECHConfigPEM wraps a base64 ECHConfigList in the PEM block sing-box
+// expects in tls.ech.config.
+func ECHConfigPEM(echConfigList string) ([]string, error) {
+	raw, err := base64.StdEncoding.DecodeString(echConfigList)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ECH config list: %w", err)
+	}
+	block := pem.EncodeToMemory(&pem.Block{Type: "ECH CONFIGS", Bytes: raw})
+	return strings.Split(strings.TrimSpace(string(block)), "\n"), nil
+}
+
+// echLookupTimeout bounds a single ECH DNS lookup.
+const echLookupTimeout = 5 * time.Second
+
+// echMinTTL keeps very short lived records from being looked up for
+// every config of a large test run.
+const echMinTTL = 5 * time.Minute
+
+type echCacheEntry struct {
+	config  string
+	expires time.Time
+}
+
+var echCache sync.Map // "name|server" -> echCacheEntry
+
+// echHTTPClient sends DoH queries; swapped out in tests.
+var echHTTPClient = http.DefaultClient
+
+// ResolveECH returns the base64 ECHConfigList for ech: inline configs are
+// returned as is, DNS query specs are looked up for serverName (unless the
+// spec names its own domain).
+func ResolveECH(ctx context.Context, ech, serverName string) (string, error) {
+	if !IsECHQuery(ech) {
+		return ech, nil
+	}
+	name, server, err := ParseECHQuery(ech)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		name = serverName
+	}
+	if name == "" || net.ParseIP(strings.Trim(name, "[]")) != nil {
+		return "", fmt.Errorf("ECH lookup needs a domain server name or the \"example.com+%s\" form", server)
+	}
+	return LookupECHConfig(ctx, name, server)
+}
+
+// LookupECHConfig queries the HTTPS record of name at server (an https://,
+// udp:// or tcp:// DNS server) and returns its ECHConfigList in base64.
+// Results are cached for the record's TTL.
+func LookupECHConfig(ctx context.Context, name, server string) (string, error) {
+	key := name + "|" + server
+	if e, ok := echCache.Load(key); ok && time.Now().Before(e.(echCacheEntry).expires) {
+		return e.(echCacheEntry).config, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, echLookupTimeout)
+	defer cancel()
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), dns.TypeHTTPS)
+	msg.RecursionDesired = true
+
+	resp, err := exchangeDNS(ctx, msg, server)
+	if err != nil {
+		return "", fmt.Errorf("ECH lookup for %s at %s: %w", name, server, err)
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		return "", fmt.Errorf("ECH lookup for %s at %s: %s", name, server, dns.RcodeToString[resp.Rcode])
+	}
+	config, ttl := echFromAnswer(resp.Answer)
+	if config == "" {
+		return "", fmt.Errorf("ECH lookup for %s at %s: no ECH config in HTTPS record", name, server)
+	}
+
+	if ttl < echMinTTL {
+		ttl = echMinTTL
+	}
+	echCache.Store(key, echCacheEntry{config: config, expires: time.Now().Add(ttl)})
+	return config, nil
+}
+
+// echFromAnswer picks the first "ech" SvcParam out of the HTTPS records
+// in answer.
+func echFromAnswer(answer []dns.RR) (string, time.Duration) {
+	for _, rr := range answer {
+		https, ok := rr.(*dns.HTTPS)
+		if !ok {
+			continue
+		}
+		for _, kv := range https.Value {
+			if ech, ok := kv.(*dns.SVCBECHConfig); ok && len(ech.ECH) > 0 {
+				return base64.StdEncoding.EncodeToString(ech.ECH), time.Duration(https.Hdr.Ttl) * time.Second
+			}
+		}
+	}
+	return "", 0
+}
+
+func exchangeDNS(ctx context.Context, msg *dns.Msg, server string) (*dns.Msg, error) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" {
+		host := u.Host
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "53")
+		}
+		client := &dns.Client{Net: u.Scheme}
+		resp, _, err := client.ExchangeContext(ctx, msg, host)
+		return resp, err
+	}
+
+	// DNS over HTTPS (RFC 8484)
+	packed, err := msg.Pack()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	res, err := echHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH server returned %s", res.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(res.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+	resp := new(dns.Msg)
+	if err := resp.Unpack(body); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
@@ -0,0 +1,102 @@
+package protocol
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseECHParam(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{query: "security=tls", want: ""},
+		{query: "ech=AEX%2BDQBB", want: "AEX+DQBB"},
+		{query: "echConfigList=AEX%2BDQBB", want: "AEX+DQBB"},
+		{query: "ech=https://1.1.1.1/dns-query", want: "https://1.1.1.1/dns-query"},
+		{query: "ech=cdn.example.com%2Budp://8.8.8.8", want: "cdn.example.com+udp://8.8.8.8"},
+		{query: "ech=not*base64", wantErr: true},
+		{query: "ech=ftp://1.1.1.1", wantErr: true},
+		{query: "ech=a%2Bb%2Bhttps://1.1.1.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			got, err := ParseECHParam(q)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseECHParam() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseECHParam() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestECHConfigPEM(t *testing.T) {
+	lines, err := ECHConfigPEM("AEX+DQBB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, rest := pem.Decode([]byte(strings.Join(lines, "\n")))
+	if block == nil || block.Type != "ECH CONFIGS" || len(rest) > 0 {
+		t.Fatalf("ECHConfigPEM() = %q, not a single ECH CONFIGS block", lines)
+	}
+	if string(block.Bytes) != "\x00\x45\xfe\x0d\x00\x41" {
+		t.Errorf("ECHConfigPEM() payload = %x", block.Bytes)
+	}
+}
+
+func TestLookupECHConfigDoH(t *testing.T) {
+	echConfig := []byte{0x00, 0x45, 0xfe, 0x0d, 0x00, 0x41}
+	queries := 0
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries++
+		body, _ := io.ReadAll(r.Body)
+		req := new(dns.Msg)
+		if err := req.Unpack(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Answer = append(resp.Answer, &dns.HTTPS{SVCB: dns.SVCB{
+			Hdr:      dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeHTTPS, Class: dns.ClassINET, Ttl: 300},
+			Priority: 1,
+			Target:   ".",
+			Value:    []dns.SVCBKeyValue{&dns.SVCBECHConfig{ECH: echConfig}},
+		}})
+		packed, _ := resp.Pack()
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(packed)
+	}))
+	defer srv.Close()
+	defer func(c *http.Client) { echHTTPClient = c }(echHTTPClient)
+	echHTTPClient = srv.Client()
+
+	for i := 0; i < 2; i++ {
+		got, err := ResolveECH(context.Background(), srv.URL+"/dns-query", "cdn.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != "AEX+DQBB" {
+			t.Errorf("ResolveECH() = %q, want %q", got, "AEX+DQBB")
+		}
+	}
+	if queries != 1 {
+		t.Errorf("DoH server got %d queries, want 1 (cached)", queries)
+	}
+
+	if _, err := ResolveECH(context.Background(), srv.URL+"/dns-query", "1.2.3.4"); err == nil {
+		t.Error("ResolveECH() with an IP server name: want error")
+	}
+}
@@ -0,0 +1,87 @@
+package protocol
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"maps"
+	"net"
+	"slices"
+	"strings"
+)
+
+// Fingerprint returns a canonical identifier of the server a config points
+// at: protocol, address, port, credentials, transport and obfuscation
+// (shadowsocks plugin, hysteria2 obfs). The remark and client-side knobs
+// (uTLS fingerprint, ALPN) are ignored, so the same server shared under
+// different names, parameter orders or vmess JSON layouts yields the same
+// fingerprint.
+func (g GeneralConfig) Fingerprint() string {
+	address, port := g.Address, g.Port
+	if port == "" {
+		if h, p, err := net.SplitHostPort(address); err == nil {
+			address, port = h, p
+		}
+	}
+
+	protocol := strings.ToLower(g.Protocol)
+	if protocol == "hy2" {
+		protocol = Hysteria2Identifier
+	}
+	tls := strings.ToLower(g.TLS)
+	if tls == "" {
+		tls = "none"
+	}
+	aid := g.Aid
+	if aid == "" || aid == "<nil>" {
+		aid = "0"
+	}
+	path := g.Path
+	if path == "" {
+		path = "/"
+	}
+
+	fields := []string{
+		protocol,
+		strings.ToLower(strings.Trim(address, "[]")),
+		strings.TrimLeft(port, "0"),
+		g.ID,
+		aid,
+		strings.ToLower(g.Security),
+		tls,
+		strings.ToLower(g.SNI),
+		canonicalTransport(g.Network),
+		canonicalTransport(g.Type),
+		strings.ToLower(g.Host),
+		path,
+		g.ServiceName,
+		g.Mode,
+		g.Authority,
+		g.Plugin,
+		canonicalPluginOpts(g.PluginOpts),
+		strings.ToLower(g.Obfs),
+		g.ObfsPassword,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// canonicalTransport folds the spellings of "plain TCP, no header" together.
+func canonicalTransport(t string) string {
+	switch t = strings.ToLower(t); t {
+	case "", "tcp", "raw", "none":
+		return ""
+	default:
+		return t
+	}
+}
+
+// canonicalPluginOpts sorts SIP003 plugin options so their order doesn't
+// matter.
+func canonicalPluginOpts(opts string) string {
+	parsed := ParseSIP003Options(opts)
+	var parts []string
+	for _, k := range slices.Sorted(maps.Keys(parsed)) {
+		parts = append(parts, k+"="+parsed[k])
+	}
+	return strings.Join(parts, ";")
+}
@@ -0,0 +1,86 @@
+package protocol
+
+import "testing"
+
+func TestGeneralConfig_Fingerprint(t *testing.T) {
+	base := GeneralConfig{
+		Protocol: VlessIdentifier,
+		Address:  "Example.com",
+		Port:     "443",
+		ID:       "uuid",
+		TLS:      "tls",
+		SNI:      "example.com",
+		Type:     "ws",
+		Host:     "cdn.example.com",
+		Path:     "/ws",
+		Remark:   "first",
+	}
+
+	same := base
+	same.Remark = "renamed"
+	same.Address = "example.com"
+	same.TlsFingerprint = "chrome"
+	same.ALPN = "h2,http/1.1"
+	if base.Fingerprint() != same.Fingerprint() {
+		t.Error("remark, address case and client-side TLS knobs must not change the fingerprint")
+	}
+
+	for name, mutate := range map[string]func(*GeneralConfig){
+		"port":      func(g *GeneralConfig) { g.Port = "8443" },
+		"id":        func(g *GeneralConfig) { g.ID = "other" },
+		"path":      func(g *GeneralConfig) { g.Path = "/other" },
+		"transport": func(g *GeneralConfig) { g.Type = "grpc" },
+		"protocol":  func(g *GeneralConfig) { g.Protocol = TrojanIdentifier },
+	} {
+		other := base
+		mutate(&other)
+		if base.Fingerprint() == other.Fingerprint() {
+			t.Errorf("changing %s must change the fingerprint", name)
+		}
+	}
+}
+
+func TestGeneralConfig_FingerprintDefaults(t *testing.T) {
+	a := GeneralConfig{Protocol: VmessIdentifier, Address: "1.2.3.4", Port: "80", ID: "id", Network: "tcp", Type: "none", Aid: "0", Path: "/"}
+	b := GeneralConfig{Protocol: VmessIdentifier, Address: "1.2.3.4", Port: "80", ID: "id", TLS: "none"}
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Error("default transport, alterId, TLS and path spellings must fingerprint the same")
+	}
+
+	w1 := GeneralConfig{Protocol: WireguardIdentifier, Address: "162.159.192.1:2408", ID: "peer"}
+	w2 := GeneralConfig{Protocol: WireguardIdentifier, Address: "162.159.192.1", Port: "2408", ID: "peer"}
+	if w1.Fingerprint() != w2.Fingerprint() {
+		t.Error("host:port addresses must be split before fingerprinting")
+	}
+}
+
+func TestGeneralConfig_FingerprintObfuscation(t *testing.T) {
+	ss := GeneralConfig{Protocol: ShadowsocksIdentifier, Address: "1.2.3.4", Port: "8388", ID: "pass", Security: "aes-128-gcm",
+		Plugin: PluginObfsLocal, PluginOpts: "obfs=http;obfs-host=a.example.com"}
+	reordered := ss
+	reordered.PluginOpts = "obfs-host=a.example.com;obfs=http"
+	if ss.Fingerprint() != reordered.Fingerprint() {
+		t.Error("the order of plugin options must not change the fingerprint")
+	}
+	otherHost := ss
+	otherHost.PluginOpts = "obfs=http;obfs-host=b.example.com"
+	noPlugin := ss
+	noPlugin.Plugin, noPlugin.PluginOpts = "", ""
+	for name, other := range map[string]GeneralConfig{"plugin options": otherHost, "plugin": noPlugin} {
+		if ss.Fingerprint() == other.Fingerprint() {
+			t.Errorf("changing the %s must change the fingerprint", name)
+		}
+	}
+
+	hy := GeneralConfig{Protocol: Hysteria2Identifier, Address: "1.2.3.4", Port: "443", ID: "pass", SNI: "example.com",
+		Obfs: "salamander", ObfsPassword: "secret"}
+	otherPassword := hy
+	otherPassword.ObfsPassword = "other"
+	noObfs := hy
+	noObfs.Obfs, noObfs.ObfsPassword = "", ""
+	for name, other := range map[string]GeneralConfig{"obfs password": otherPassword, "obfs": noObfs} {
+		if hy.Fingerprint() == other.Fingerprint() {
+			t.Errorf("changing the %s must change the fingerprint", name)
+		}
+	}
+}
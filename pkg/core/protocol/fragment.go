@@ -0,0 +1,161 @@
+package protocol
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Default TLS fragmentation settings, matching what most xray clients ship.
+const (
+	DefaultFragmentPackets  = "tlshello"
+	DefaultFragmentLength   = "100-200"
+	DefaultFragmentInterval = "10-20"
+)
+
+var fragmentRangeRe = regexp.MustCompile(`^\d+(-\d+)?$`)
+
+// FragmentOptions splits the first packets of outbound connections (the TLS
+// ClientHello by default) and optionally sends noise packets before UDP
+// traffic, to get past DPI that matches on SNI. Ranges are "MIN-MAX" or a
+// single number.
+type FragmentOptions struct {
+	// Packets is "tlshello" or a packet index range such as "1-3".
+	// Empty disables fragmentation (noises may still apply).
+	Packets string `json:"packets,omitempty"`
+	// Length is the size range of each fragment in bytes.
+	Length string `json:"length,omitempty"`
+	// Interval is the delay range between fragments in ms.
+	Interval string  `json:"interval,omitempty"`
+	Noises   []Noise `json:"noises,omitempty"`
+}
+
+// Noise is a junk packet sent ahead of UDP traffic (xray-core only).
+type Noise struct {
+	// Type is one of "rand", "str", "hex" or "base64".
+	Type string `json:"type"`
+	// Packet is a length range for "rand", the payload otherwise.
+	Packet string `json:"packet"`
+	// Delay is the delay range after the packet in ms.
+	Delay string `json:"delay,omitempty"`
+}
+
+// NewFragmentOptions builds FragmentOptions from the CLI flag forms: a
+// "packets,length,interval" spec (empty parts and the word "default" take
+// the defaults) and noises in ParseNoise form. It returns nil when both are
+// empty.
+func NewFragmentOptions(spec string, noises []string) (*FragmentOptions, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" && len(noises) == 0 {
+		return nil, nil
+	}
+
+	f := &FragmentOptions{}
+	if spec != "" {
+		parts := strings.Split(spec, ",")
+		if strings.EqualFold(spec, "default") {
+			parts = nil
+		} else if len(parts) > 3 {
+			return nil, fmt.Errorf("invalid fragment %q: expected packets,length,interval", spec)
+		}
+		for len(parts) < 3 {
+			parts = append(parts, "")
+		}
+		f.Packets = orDefault(parts[0], DefaultFragmentPackets)
+		f.Length = orDefault(parts[1], DefaultFragmentLength)
+		f.Interval = orDefault(parts[2], DefaultFragmentInterval)
+	}
+
+	for _, raw := range noises {
+		n, err := ParseNoise(raw)
+		if err != nil {
+			return nil, err
+		}
+		f.Noises = append(f.Noises, n)
+	}
+
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ParseNoise parses a noise given as "type:packet[:delay]", e.g.
+// "rand:10-20:10-16" or "str:hello".
+func ParseNoise(s string) (Noise, error) {
+	typ, rest, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok || rest == "" {
+		return Noise{}, fmt.Errorf("invalid noise %q: expected type:packet[:delay]", s)
+	}
+	n := Noise{Type: strings.ToLower(typ), Packet: rest}
+	if i := strings.LastIndexByte(rest, ':'); i >= 0 && fragmentRangeRe.MatchString(rest[i+1:]) {
+		n.Packet, n.Delay = rest[:i], rest[i+1:]
+	}
+	if err := n.validate(); err != nil {
+		return Noise{}, err
+	}
+	return n, nil
+}
+
+// Validate checks the ranges and noise types.
+func (f *FragmentOptions) Validate() error {
+	if f == nil {
+		return nil
+	}
+	if f.Packets != "" && !strings.EqualFold(f.Packets, "tlshello") && !fragmentRangeRe.MatchString(f.Packets) {
+		return fmt.Errorf("invalid fragment packets %q: expected tlshello or a range", f.Packets)
+	}
+	if f.Length != "" && !fragmentRangeRe.MatchString(f.Length) {
+		return fmt.Errorf("invalid fragment length %q: expected a range such as 100-200", f.Length)
+	}
+	if f.Interval != "" && !fragmentRangeRe.MatchString(f.Interval) {
+		return fmt.Errorf("invalid fragment interval %q: expected a range such as 10-20", f.Interval)
+	}
+	for _, n := range f.Noises {
+		if err := n.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n Noise) validate() error {
+	switch n.Type {
+	case "rand":
+		if !fragmentRangeRe.MatchString(n.Packet) {
+			return fmt.Errorf("invalid noise packet %q: rand expects a length range", n.Packet)
+		}
+	case "str", "hex", "base64":
+		if n.Packet == "" {
+			return fmt.Errorf("noise of type %s has no packet", n.Type)
+		}
+	default:
+		return fmt.Errorf("invalid noise type %q: expected rand, str, hex or base64", n.Type)
+	}
+	if n.Delay != "" && !fragmentRangeRe.MatchString(n.Delay) {
+		return fmt.Errorf("invalid noise delay %q: expected a range such as 10-20", n.Delay)
+	}
+	return nil
+}
+
+func orDefault(v, def string) string {
+	if v = strings.TrimSpace(v); v != "" {
+		return v
+	}
+	return def
+}
+
+// String renders the options for config summaries.
+func (f *FragmentOptions) String() string {
+	if f == nil {
+		return "off"
+	}
+	var parts []string
+	if f.Packets != "" {
+		parts = append(parts, fmt.Sprintf("%s,%s,%s", f.Packets, f.Length, f.Interval))
+	}
+	if len(f.Noises) > 0 {
+		parts = append(parts, fmt.Sprintf("%d noise(s)", len(f.Noises)))
+	}
+	return strings.Join(parts, " + ")
+}
@@ -0,0 +1,53 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewFragmentOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		noises  []string
+		want    *FragmentOptions
+		wantErr bool
+	}{
+		{name: "disabled", want: nil},
+		{
+			name: "defaults",
+			spec: "default",
+			want: &FragmentOptions{Packets: "tlshello", Length: "100-200", Interval: "10-20"},
+		},
+		{
+			name: "partial spec",
+			spec: "1-3,,5",
+			want: &FragmentOptions{Packets: "1-3", Length: "100-200", Interval: "5"},
+		},
+		{
+			name:   "noises only",
+			noises: []string{"rand:10-20:10-16", "str:a:b"},
+			want: &FragmentOptions{Noises: []Noise{
+				{Type: "rand", Packet: "10-20", Delay: "10-16"},
+				{Type: "str", Packet: "a:b"},
+			}},
+		},
+		{name: "bad packets", spec: "clienthello", wantErr: true},
+		{name: "bad length", spec: "tlshello,big", wantErr: true},
+		{name: "too many parts", spec: "1,2,3,4", wantErr: true},
+		{name: "bad noise type", noises: []string{"zero:10"}, wantErr: true},
+		{name: "bad rand packet", noises: []string{"rand:abc"}, wantErr: true},
+		{name: "noise without packet", noises: []string{"hex"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewFragmentOptions(tt.spec, tt.noises)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewFragmentOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewFragmentOptions() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,5 +1,11 @@
 package protocol
 
+import "errors"
+
+// ErrUDPUnsupported is returned when asked for a UDP connection through an
+// outbound that can only carry TCP.
+var ErrUDPUnsupported = errors.New("outbound does not support UDP")
+
 const (
 	VmessIdentifier       = "vmess"
 	VlessIdentifier       = "vless"
@@ -8,6 +14,7 @@ const (
 	WireguardIdentifier   = "wireguard"
 	SocksIdentifier       = "socks"
 	Hysteria2Identifier   = "hysteria2"
+	TuicIdentifier        = "tuic"
 	TunIdentifier         = "tun"
 )
 const (
@@ -44,9 +51,23 @@ type GeneralConfig struct {
 	SNI            string
 	ALPN           string
 	TlsFingerprint string
+	ECH            string
 	Authority      string
 	ServiceName    string
 	Mode           string
 	Type           string
+	Plugin         string // shadowsocks SIP003 plugin
+	PluginOpts     string
+	Obfs           string // hysteria2 obfuscation
+	ObfsPassword   string
 	OrigLink       string
 }
+
+// Transport returns the network the config runs over (tcp, ws, grpc,
+// xhttp, ...). vmess keeps it in Network and its header type in Type.
+func (g GeneralConfig) Transport() string {
+	if g.Network != "" {
+		return g.Network
+	}
+	return g.Type
+}
@@ -0,0 +1,240 @@
+package protocol
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Severity of a lint diagnostic. Errors make the config unusable, warnings
+// point at settings that probably don't do what the link author meant.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Lint codes, stable for scripts matching on them.
+const (
+	LintUnsupportedProtocol = "unsupported-protocol"
+	LintParseError          = "parse-error"
+	LintMissingAddress      = "missing-address"
+	LintInvalidPort         = "invalid-port"
+	LintInvalidUUID         = "invalid-uuid"
+	LintUnknownTransport    = "unknown-transport"
+	LintUnknownFlow         = "unknown-flow"
+	LintFlowNonTCP          = "flow-non-tcp"
+	LintFlowWithoutTLS      = "flow-without-tls"
+	LintRealityMissingPbk   = "reality-missing-pbk"
+	LintRealityInvalidPbk   = "reality-invalid-pbk"
+	LintRealityMissingSid   = "reality-missing-sid"
+	LintRealityInvalidSid   = "reality-invalid-sid"
+	LintUnknownFingerprint  = "unknown-fingerprint"
+)
+
+// Lint statuses, the worst severity among a config's diagnostics.
+const (
+	LintStatusOK      = "ok"
+	LintStatusWarning = "warning"
+	LintStatusError   = "error"
+)
+
+// Diagnostic is a single problem found in a config.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Field    string   `json:"field,omitempty"` // link parameter the problem is about
+	Message  string   `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	if d.Field != "" {
+		return fmt.Sprintf("%s [%s] %s: %s", d.Severity, d.Code, d.Field, d.Message)
+	}
+	return fmt.Sprintf("%s [%s] %s", d.Severity, d.Code, d.Message)
+}
+
+// LintStatus returns the worst severity in diags as a lint status.
+func LintStatus(diags []Diagnostic) string {
+	status := LintStatusOK
+	for _, d := range diags {
+		switch d.Severity {
+		case SeverityError:
+			return LintStatusError
+		case SeverityWarning:
+			status = LintStatusWarning
+		}
+	}
+	return status
+}
+
+// LintCodes returns the distinct codes of diags, comma separated, as the
+// database stores them.
+func LintCodes(diags []Diagnostic) string {
+	var codes []string
+	for _, d := range diags {
+		if !slices.Contains(codes, d.Code) {
+			codes = append(codes, d.Code)
+		}
+	}
+	return strings.Join(codes, ",")
+}
+
+// LintFields is the part of a config the lint rules look at, named after
+// the share link parameters. The cores fill it from their protocol types.
+type LintFields struct {
+	Protocol    string
+	Address     string
+	Port        string
+	ID          string // UUID for vless/vmess
+	Security    string // none, tls or reality
+	Transport   string // type/net
+	Flow        string
+	PublicKey   string // reality pbk
+	ShortID     string // reality sid
+	Fingerprint string // uTLS fp
+
+	// KnownFingerprint reports whether the core supports a uTLS
+	// fingerprint. nil skips the check.
+	KnownFingerprint func(string) bool
+	// MapsNonUUID is set when the core maps non-UUID ids of up to 30 bytes
+	// to a UUID (xray-core) instead of rejecting them.
+	MapsNonUUID bool
+}
+
+var uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// knownTransports are the transport names either core understands.
+var knownTransports = map[string]bool{
+	"": true, "tcp": true, "raw": true, "ws": true, "grpc": true, "gun": true,
+	"http": true, "h2": true, "httpupgrade": true, "xhttp": true,
+	"splithttp": true, "kcp": true, "mkcp": true, "quic": true,
+}
+
+var knownFlows = map[string]bool{
+	"xtls-rprx-vision":        true,
+	"xtls-rprx-vision-udp443": true,
+}
+
+// Lint runs every rule against f and returns all problems found.
+func (f LintFields) Lint() []Diagnostic {
+	var diags []Diagnostic
+	add := func(sev Severity, code, field, format string, args ...any) {
+		diags = append(diags, Diagnostic{Severity: sev, Code: code, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if strings.Trim(f.Address, "[]") == "" {
+		add(SeverityError, LintMissingAddress, "address", "server address is empty")
+	}
+	if port, err := strconv.Atoi(f.Port); err != nil || port < 1 || port > 65535 {
+		add(SeverityError, LintInvalidPort, "port", "port %q is not in 1-65535", f.Port)
+	}
+
+	if f.Protocol == VlessIdentifier || f.Protocol == VmessIdentifier {
+		switch {
+		case uuidRe.MatchString(f.ID):
+		case f.MapsNonUUID && len(f.ID) >= 1 && len(f.ID) <= 30:
+			add(SeverityWarning, LintInvalidUUID, "id", "%q is not a UUID; the core maps it to one, which only works if the server does the same", f.ID)
+		default:
+			add(SeverityError, LintInvalidUUID, "id", "%q is not a valid UUID", f.ID)
+		}
+	}
+
+	transport := strings.ToLower(f.Transport)
+	if !knownTransports[transport] {
+		add(SeverityError, LintUnknownTransport, "type", "unknown transport %q", f.Transport)
+	}
+
+	if f.Flow != "" {
+		if !knownFlows[f.Flow] {
+			add(SeverityError, LintUnknownFlow, "flow", "unknown flow %q", f.Flow)
+		}
+		if transport != "" && transport != "tcp" && transport != "raw" {
+			add(SeverityError, LintFlowNonTCP, "flow", "flow %q only works over the tcp transport, not %q", f.Flow, f.Transport)
+		}
+		if f.Security != "tls" && f.Security != "reality" {
+			add(SeverityError, LintFlowWithoutTLS, "flow", "flow %q needs tls or reality security", f.Flow)
+		}
+	}
+
+	if f.Security == "reality" {
+		if f.PublicKey == "" {
+			add(SeverityError, LintRealityMissingPbk, "pbk", "reality needs the server public key")
+		} else if key, err := base64.RawURLEncoding.DecodeString(f.PublicKey); err != nil || len(key) != 32 {
+			add(SeverityError, LintRealityInvalidPbk, "pbk", "public key %q is not a base64url X25519 key", f.PublicKey)
+		}
+		if f.ShortID == "" {
+			add(SeverityWarning, LintRealityMissingSid, "sid", "no short id; the server must accept an empty one")
+		} else if _, err := hex.DecodeString(f.ShortID); err != nil || len(f.ShortID) > 16 {
+			add(SeverityError, LintRealityInvalidSid, "sid", "short id %q is not up to 16 hex digits", f.ShortID)
+		}
+	}
+
+	if f.Fingerprint != "" && f.Fingerprint != "none" && f.KnownFingerprint != nil && !f.KnownFingerprint(f.Fingerprint) {
+		add(SeverityError, LintUnknownFingerprint, "fp", "the core doesn't know the uTLS fingerprint %q", f.Fingerprint)
+	}
+
+	return diags
+}
+
+// Linter is implemented by protocols that give the lint rules more than
+// their GeneralConfig.
+type Linter interface {
+	LintFields() LintFields
+}
+
+// LintProtocol lints a parsed protocol. Protocols that aren't a Linter only
+// get the address and port checked.
+func LintProtocol(p Protocol) []Diagnostic {
+	if l, ok := p.(Linter); ok {
+		return l.LintFields().Lint()
+	}
+	g := p.ConvertToGeneralConfig()
+	f := LintFields{Protocol: g.Protocol, Address: g.Address, Port: g.Port}
+	if f.Port == "" {
+		// wireguard keeps "host:port" in Address
+		if h, port, err := net.SplitHostPort(f.Address); err == nil {
+			f.Address, f.Port = h, port
+		}
+	}
+	return f.Lint()
+}
+
+// LintFieldsFromURL reads LintFields straight off a URL style share link,
+// for links the cores fail to parse. ok is false for links that aren't
+// URLs (e.g. base64 vmess).
+func LintFieldsFromURL(link string) (f LintFields, ok bool) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return f, false
+	}
+	if u.Scheme == VmessIdentifier {
+		// vmess://base64(JSON) parses as a URL with a garbage host
+		if _, _, err := net.SplitHostPort(u.Host); err != nil || u.User == nil {
+			return f, false
+		}
+	}
+	q := u.Query()
+	f = LintFields{
+		Protocol:    u.Scheme,
+		Address:     u.Hostname(),
+		Port:        u.Port(),
+		Security:    q.Get("security"),
+		Transport:   q.Get("type"),
+		Flow:        q.Get("flow"),
+		PublicKey:   q.Get("pbk"),
+		ShortID:     q.Get("sid"),
+		Fingerprint: q.Get("fp"),
+	}
+	if u.User != nil {
+		f.ID = u.User.Username()
+	}
+	return f, true
+}
@@ -0,0 +1,104 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func lintCodes(diags []Diagnostic) []string {
+	codes := []string{}
+	for _, d := range diags {
+		codes = append(codes, d.Code)
+	}
+	return codes
+}
+
+func TestLintFields(t *testing.T) {
+	const (
+		uuid = "b831381d-6324-4d53-ad4f-8cda48b30811"
+		pbk  = "SbVKOEMjK0sIlbwg4akyBg5mL5KZwwB-ed4eEE7YnRc"
+	)
+	onlyChrome := func(fp string) bool { return fp == "chrome" }
+
+	tests := []struct {
+		name   string
+		fields LintFields
+		want   []string
+		status string
+	}{
+		{
+			name:   "clean reality vision",
+			fields: LintFields{Protocol: "vless", Address: "example.com", Port: "443", ID: uuid, Security: "reality", Transport: "tcp", Flow: "xtls-rprx-vision", PublicKey: pbk, ShortID: "6ba85179e30d4fc2", Fingerprint: "chrome", KnownFingerprint: onlyChrome},
+			want:   []string{},
+			status: LintStatusOK,
+		},
+		{
+			name:   "reality without pbk or sid",
+			fields: LintFields{Protocol: "vless", Address: "example.com", Port: "443", ID: uuid, Security: "reality"},
+			want:   []string{LintRealityMissingPbk, LintRealityMissingSid},
+			status: LintStatusError,
+		},
+		{
+			name:   "bad reality values",
+			fields: LintFields{Protocol: "trojan", Address: "example.com", Port: "443", Security: "reality", PublicKey: "short", ShortID: "xyz"},
+			want:   []string{LintRealityInvalidPbk, LintRealityInvalidSid},
+			status: LintStatusError,
+		},
+		{
+			name:   "everything wrong at once",
+			fields: LintFields{Protocol: "vless", Address: "example.com", Port: "70000", ID: "not-a-uuid-but-way-too-long-to-be-mapped", Security: "none", Transport: "carrier-pigeon", Flow: "xtls-rprx-origin", Fingerprint: "netscape", KnownFingerprint: onlyChrome},
+			want:   []string{LintInvalidPort, LintInvalidUUID, LintUnknownTransport, LintUnknownFlow, LintFlowNonTCP, LintFlowWithoutTLS, LintUnknownFingerprint},
+			status: LintStatusError,
+		},
+		{
+			name:   "vision over ws",
+			fields: LintFields{Protocol: "vless", Address: "example.com", Port: "443", ID: uuid, Security: "tls", Transport: "ws", Flow: "xtls-rprx-vision"},
+			want:   []string{LintFlowNonTCP},
+			status: LintStatusError,
+		},
+		{
+			name:   "short id mapped by the core",
+			fields: LintFields{Protocol: "vmess", Address: "[::1]", Port: "8080", ID: "my-password", MapsNonUUID: true},
+			want:   []string{LintInvalidUUID},
+			status: LintStatusWarning,
+		},
+		{
+			name:   "trojan passwords aren't UUIDs",
+			fields: LintFields{Protocol: "trojan", Address: "example.com", Port: "443", ID: "secret", Security: "tls"},
+			want:   []string{},
+			status: LintStatusOK,
+		},
+		{
+			name:   "missing address and port",
+			fields: LintFields{Protocol: "ss"},
+			want:   []string{LintMissingAddress, LintInvalidPort},
+			status: LintStatusError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diags := tt.fields.Lint()
+			if got := lintCodes(diags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() codes = %v, want %v", got, tt.want)
+			}
+			if got := LintStatus(diags); got != tt.status {
+				t.Errorf("LintStatus() = %q, want %q", got, tt.status)
+			}
+		})
+	}
+}
+
+func TestLintFieldsFromURL(t *testing.T) {
+	f, ok := LintFieldsFromURL("vless://b831381d-6324-4d53-ad4f-8cda48b30811@[2001:db8::1]:443?security=reality&type=tcp&flow=xtls-rprx-vision&pbk=abc&sid=01&fp=chrome#name")
+	if !ok {
+		t.Fatal("LintFieldsFromURL() ok = false for a vless link")
+	}
+	want := LintFields{Protocol: "vless", Address: "2001:db8::1", Port: "443", ID: "b831381d-6324-4d53-ad4f-8cda48b30811", Security: "reality", Transport: "tcp", Flow: "xtls-rprx-vision", PublicKey: "abc", ShortID: "01", Fingerprint: "chrome"}
+	if !reflect.DeepEqual(f, want) {
+		t.Errorf("LintFieldsFromURL() = %+v, want %+v", f, want)
+	}
+
+	if _, ok := LintFieldsFromURL("vmess://eyJhZGQiOiIxLjIuMy40In0="); ok {
+		t.Error("LintFieldsFromURL() ok = true for a base64 vmess link")
+	}
+}
@@ -0,0 +1,192 @@
+package protocol
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Share link parameters carrying multiplexing hints.
+const (
+	MuxParam                = "mux" // 1/0, or a sing-mux protocol name
+	MuxConcurrencyParam     = "mux_concurrency"
+	MuxXudpConcurrencyParam = "xudp_concurrency"
+	MuxPaddingParam         = "mux_padding"
+)
+
+// MuxOptions multiplexes several streams over one proxy connection:
+// Mux.Cool (+ XUDP for UDP) on xray-core, sing-mux on sing-box. Only
+// VMess, VLESS, Trojan and Shadowsocks outbounds can carry it.
+type MuxOptions struct {
+	Enabled bool `json:"enabled"`
+	// Concurrency is the number of streams per connection (xray
+	// concurrency, sing-box max_streams). 0 keeps the core default.
+	Concurrency int `json:"concurrency,omitempty"`
+	// XudpConcurrency is the number of XUDP sessions per connection
+	// (xray-core only). 0 keeps the core default.
+	XudpConcurrency int `json:"xudpConcurrency,omitempty"`
+	// Padding enables sing-mux padding (sing-box only).
+	Padding bool `json:"padding,omitempty"`
+	// Protocol is the sing-mux protocol: smux, yamux or h2mux
+	// (sing-box only). Empty keeps the core default.
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// ParseMuxParams reads the mux link parameters. It returns nil when the
+// link carries none of them.
+func ParseMuxParams(q url.Values) (*MuxOptions, error) {
+	if !q.Has(MuxParam) && !q.Has(MuxConcurrencyParam) && !q.Has(MuxXudpConcurrencyParam) && !q.Has(MuxPaddingParam) {
+		return nil, nil
+	}
+
+	// The concurrency knobs alone imply mux is wanted
+	m := &MuxOptions{Enabled: true}
+	if v := q.Get(MuxParam); v != "" {
+		if err := m.setMode(v); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if v := q.Get(MuxConcurrencyParam); v != "" {
+		if m.Concurrency, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid %s %q", MuxConcurrencyParam, v)
+		}
+	}
+	if v := q.Get(MuxXudpConcurrencyParam); v != "" {
+		if m.XudpConcurrency, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid %s %q", MuxXudpConcurrencyParam, v)
+		}
+	}
+	if v := q.Get(MuxPaddingParam); v != "" {
+		if m.Padding, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid %s %q", MuxPaddingParam, v)
+		}
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AddParams is the inverse of ParseMuxParams. A nil MuxOptions adds nothing.
+func (m *MuxOptions) AddParams(q url.Values) {
+	if m == nil {
+		return
+	}
+	switch {
+	case !m.Enabled:
+		q.Set(MuxParam, "0")
+		return
+	case m.Protocol != "":
+		q.Set(MuxParam, m.Protocol)
+	default:
+		q.Set(MuxParam, "1")
+	}
+	if m.Concurrency != 0 {
+		q.Set(MuxConcurrencyParam, strconv.Itoa(m.Concurrency))
+	}
+	if m.XudpConcurrency != 0 {
+		q.Set(MuxXudpConcurrencyParam, strconv.Itoa(m.XudpConcurrency))
+	}
+	if m.Padding {
+		q.Set(MuxPaddingParam, "1")
+	}
+}
+
+// ParseMuxSpec parses the CLI form of a mux override: "off", "on" or a
+// sing-mux protocol name, optionally followed by comma separated settings,
+// e.g. "on,concurrency=8,xudp=16" or "h2mux,padding". Empty returns nil,
+// which leaves mux to the links.
+func ParseMuxSpec(spec string) (*MuxOptions, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(spec, ",")
+	m := &MuxOptions{}
+	if err := m.setMode(strings.TrimSpace(parts[0])); err != nil {
+		return nil, err
+	}
+	for _, part := range parts[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		var err error
+		switch strings.ToLower(key) {
+		case "concurrency":
+			m.Concurrency, err = strconv.Atoi(value)
+		case "xudp":
+			m.XudpConcurrency, err = strconv.Atoi(value)
+		case "padding":
+			m.Padding = value == "" || value == "1" || strings.EqualFold(value, "true")
+		case "protocol":
+			err = m.setMode(value)
+		default:
+			return nil, fmt.Errorf("unknown mux setting %q (want concurrency, xudp, padding or protocol)", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid mux setting %q", part)
+		}
+	}
+	if !m.Enabled && len(parts) > 1 {
+		return nil, fmt.Errorf("mux %q: settings given while mux is off", spec)
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// maxMuxConcurrency is the highest concurrency xray-core accepts.
+const maxMuxConcurrency = 1024
+
+func (m *MuxOptions) validate() error {
+	if m.Concurrency < 0 || m.Concurrency > maxMuxConcurrency {
+		return fmt.Errorf("mux concurrency %d out of range 0-%d", m.Concurrency, maxMuxConcurrency)
+	}
+	if m.XudpConcurrency < 0 || m.XudpConcurrency > maxMuxConcurrency {
+		return fmt.Errorf("xudp concurrency %d out of range 0-%d", m.XudpConcurrency, maxMuxConcurrency)
+	}
+	return nil
+}
+
+// setMode applies an on/off switch or a sing-mux protocol name.
+func (m *MuxOptions) setMode(v string) error {
+	switch strings.ToLower(v) {
+	case "1", "true", "on":
+		m.Enabled = true
+	case "0", "false", "off":
+		m.Enabled = false
+	case "smux", "yamux", "h2mux":
+		m.Enabled = true
+		m.Protocol = strings.ToLower(v)
+	default:
+		return fmt.Errorf("invalid mux %q: want on, off, smux, yamux or h2mux", v)
+	}
+	return nil
+}
+
+// String renders the options for config summaries.
+func (m *MuxOptions) String() string {
+	if m == nil {
+		return "per link"
+	}
+	if !m.Enabled {
+		return "off"
+	}
+	s := "on"
+	if m.Protocol != "" {
+		s = m.Protocol
+	}
+	if m.Concurrency != 0 {
+		s += fmt.Sprintf(", concurrency %d", m.Concurrency)
+	}
+	if m.XudpConcurrency != 0 {
+		s += fmt.Sprintf(", xudp %d", m.XudpConcurrency)
+	}
+	if m.Padding {
+		s += ", padding"
+	}
+	return s
+}
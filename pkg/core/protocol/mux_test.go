@@ -0,0 +1,78 @@
+package protocol
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseMuxParams(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    *MuxOptions
+		wantErr bool
+	}{
+		{query: "security=tls", want: nil},
+		{query: "mux=1", want: &MuxOptions{Enabled: true}},
+		{query: "mux=0", want: &MuxOptions{}},
+		{query: "mux=h2mux&mux_padding=true", want: &MuxOptions{Enabled: true, Protocol: "h2mux", Padding: true}},
+		{query: "mux_concurrency=8&xudp_concurrency=16", want: &MuxOptions{Enabled: true, Concurrency: 8, XudpConcurrency: 16}},
+		{query: "mux=maybe", wantErr: true},
+		{query: "mux=1&mux_concurrency=eight", wantErr: true},
+		{query: "mux=1&mux_concurrency=5000", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			got, err := ParseMuxParams(q)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMuxParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseMuxParams() = %+v, want %+v", got, tt.want)
+			}
+
+			// Whatever was parsed must survive a round trip through AddParams
+			if got != nil {
+				out := url.Values{}
+				got.AddParams(out)
+				again, err := ParseMuxParams(out)
+				if err != nil || !reflect.DeepEqual(again, got) {
+					t.Errorf("round trip via %q = %+v, %v", out.Encode(), again, err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseMuxSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *MuxOptions
+		wantErr bool
+	}{
+		{spec: "", want: nil},
+		{spec: "off", want: &MuxOptions{}},
+		{spec: "on", want: &MuxOptions{Enabled: true}},
+		{spec: "on,concurrency=8,xudp=16", want: &MuxOptions{Enabled: true, Concurrency: 8, XudpConcurrency: 16}},
+		{spec: "smux,padding", want: &MuxOptions{Enabled: true, Protocol: "smux", Padding: true}},
+		{spec: "on,protocol=yamux", want: &MuxOptions{Enabled: true, Protocol: "yamux"}},
+		{spec: "off,concurrency=8", wantErr: true},
+		{spec: "on,streams=8", wantErr: true},
+		{spec: "on,concurrency=-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseMuxSpec(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMuxSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMuxSpec() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
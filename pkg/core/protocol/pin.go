@@ -0,0 +1,48 @@
+package protocol
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// PinnedCertParam is the share link parameter pinning the SHA-256 of the
+// server's certificate, xray's pinnedPeerCertSha256. It takes a comma
+// separated list of hex hashes, whose bytes may be separated by colons.
+const PinnedCertParam = "pcs"
+
+// ParsePinnedCertParam reads the certificate pin link parameter and checks
+// every hash in it. It returns "" when the link carries none.
+func ParsePinnedCertParam(q url.Values) (string, error) {
+	pcs := strings.TrimSpace(q.Get(PinnedCertParam))
+	if pcs == "" {
+		return "", nil
+	}
+	if err := ValidatePinnedCert(pcs); err != nil {
+		return "", err
+	}
+	return pcs, nil
+}
+
+// ValidatePinnedCert checks a pin list as accepted by ParsePinnedCertParam.
+func ValidatePinnedCert(pcs string) error {
+	for _, pin := range strings.Split(pcs, ",") {
+		pin = strings.TrimSpace(pin)
+		if pin == "" {
+			continue
+		}
+		sum, err := hex.DecodeString(strings.ReplaceAll(pin, ":", ""))
+		if err != nil || len(sum) != sha256.Size {
+			return fmt.Errorf("invalid %s %q: want the hex SHA-256 of a certificate", PinnedCertParam, pin)
+		}
+	}
+	return nil
+}
+
+// CertSHA256 returns the pin of a DER encoded certificate.
+func CertSHA256(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,36 @@
+package protocol
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParsePinnedCertParam(t *testing.T) {
+	pin := CertSHA256([]byte("certificate"))
+	colons := strings.ToUpper(pin[:2]) + ":" + pin[2:]
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{query: "security=tls", want: ""},
+		{query: "pcs=" + pin, want: pin},
+		{query: "pcs=" + colons, want: colons},
+		{query: "pcs=" + pin + "," + pin, want: pin + "," + pin},
+		{query: "pcs=ba8845", wantErr: true},
+		{query: "pcs=not-hex", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			got, err := ParsePinnedCertParam(q)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePinnedCertParam() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParsePinnedCertParam() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package protocol
+
+import (
+	"strings"
+)
+
+// Shadowsocks SIP003 plugin names as they appear in the `plugin=` parameter of SIP002 links.
+const (
+	PluginObfsLocal   = "obfs-local"
+	PluginV2rayPlugin = "v2ray-plugin"
+	PluginShadowTLS   = "shadow-tls"
+)
+
+// SplitSIP003Plugin splits the value of a SIP002 `plugin` query parameter
+// ("name;opt1=v1;opt2=v2") into the plugin name and its raw option string.
+// Known aliases are normalized, so "simple-obfs" becomes "obfs-local".
+func SplitSIP003Plugin(raw string) (name string, opts string) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", ""
+	}
+	name, opts, _ = strings.Cut(raw, ";")
+	switch name {
+	case "simple-obfs", "obfs":
+		name = PluginObfsLocal
+	case "shadowtls", "shadow-tls-plugin":
+		name = PluginShadowTLS
+	}
+	return name, opts
+}
+
+// ParseSIP003Options parses a SIP003 option string ("k1=v1;k2;k3=v3") into a map.
+// Backslash escapes for ';', '=' and '\' are honored. Flags without a value map to "".
+func ParseSIP003Options(opts string) map[string]string {
+	result := make(map[string]string)
+	var key, value strings.Builder
+	inValue := false
+	flush := func() {
+		if key.Len() > 0 {
+			result[key.String()] = value.String()
+		}
+		key.Reset()
+		value.Reset()
+		inValue = false
+	}
+
+	for i := 0; i < len(opts); i++ {
+		c := opts[i]
+		switch {
+		case c == '\\' && i+1 < len(opts):
+			i++
+			c = opts[i]
+		case c == ';':
+			flush()
+			continue
+		case c == '=' && !inValue:
+			inValue = true
+			continue
+		}
+		if inValue {
+			value.WriteByte(c)
+		} else {
+			key.WriteByte(c)
+		}
+	}
+	flush()
+
+	return result
+}
+
+// JoinSIP003Plugin is the inverse of SplitSIP003Plugin.
+func JoinSIP003Plugin(name, opts string) string {
+	if name == "" {
+		return ""
+	}
+	if opts == "" {
+		return name
+	}
+	return name + ";" + opts
+}
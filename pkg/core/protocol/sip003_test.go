@@ -0,0 +1,45 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitSIP003Plugin(t *testing.T) {
+	tests := []struct {
+		raw       string
+		wantName  string
+		wantOpts  string
+		roundTrip bool
+	}{
+		{"", "", "", true},
+		{"obfs-local;obfs=http;obfs-host=example.com", PluginObfsLocal, "obfs=http;obfs-host=example.com", true},
+		{"simple-obfs;obfs=tls", PluginObfsLocal, "obfs=tls", false},
+		{"v2ray-plugin", PluginV2rayPlugin, "", true},
+	}
+
+	for _, tt := range tests {
+		name, opts := SplitSIP003Plugin(tt.raw)
+		if name != tt.wantName || opts != tt.wantOpts {
+			t.Errorf("SplitSIP003Plugin(%q) = (%q, %q), want (%q, %q)", tt.raw, name, opts, tt.wantName, tt.wantOpts)
+		}
+		if tt.roundTrip {
+			if got := JoinSIP003Plugin(name, opts); got != tt.raw {
+				t.Errorf("JoinSIP003Plugin(%q, %q) = %q, want %q", name, opts, got, tt.raw)
+			}
+		}
+	}
+}
+
+func TestParseSIP003Options(t *testing.T) {
+	got := ParseSIP003Options(`tls;host=cdn.example.com;path=/a\;b;mux=0`)
+	want := map[string]string{
+		"tls":  "",
+		"host": "cdn.example.com",
+		"path": "/a;b",
+		"mux":  "0",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSIP003Options() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,112 @@
+package protocol
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// WireguardPeerParam is the wireguard:// link parameter holding an additional
+// peer. It is repeated once per peer.
+const WireguardPeerParam = "peer"
+
+// WireguardPeer is an additional peer of a WireGuard link. The first peer
+// lives in the link itself (host, publickey, ...); every further peer is
+// carried in a `peer` parameter holding its own url-encoded query string,
+// e.g. peer=publickey%3D...%26endpoint%3D1.2.3.4%3A2408.
+type WireguardPeer struct {
+	PublicKey    string
+	PreSharedKey string
+	Endpoint     string // HOST:PORT
+	AllowedIPs   string // CIDRs separated by commas
+	Reserved     string // Three bytes separated by commas
+	KeepAlive    int32  // Persistent keepalive interval in seconds
+}
+
+// ParseWireguardPeer decodes the value of a `peer` link parameter.
+func ParseWireguardPeer(raw string) (WireguardPeer, error) {
+	q, err := url.ParseQuery(raw)
+	if err != nil {
+		return WireguardPeer{}, fmt.Errorf("invalid wireguard peer %q: %w", raw, err)
+	}
+	p := WireguardPeer{
+		PublicKey:    q.Get("publickey"),
+		PreSharedKey: q.Get("presharedkey"),
+		Endpoint:     q.Get("endpoint"),
+		AllowedIPs:   q.Get("allowedips"),
+		Reserved:     q.Get("reserved"),
+	}
+	if p.PublicKey == "" {
+		return WireguardPeer{}, fmt.Errorf("wireguard peer %q has no public key", raw)
+	}
+	if ka := q.Get("keepalive"); ka != "" {
+		v, err := strconv.ParseInt(ka, 10, 32)
+		if err != nil {
+			return WireguardPeer{}, fmt.Errorf("invalid wireguard peer keepalive %q", ka)
+		}
+		p.KeepAlive = int32(v)
+	}
+	return p, nil
+}
+
+// String encodes the peer as the value of a `peer` link parameter.
+func (p WireguardPeer) String() string {
+	q := url.Values{}
+	for key, value := range map[string]string{
+		"publickey":    p.PublicKey,
+		"presharedkey": p.PreSharedKey,
+		"endpoint":     p.Endpoint,
+		"allowedips":   p.AllowedIPs,
+		"reserved":     p.Reserved,
+	} {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	if p.KeepAlive != 0 {
+		q.Set("keepalive", strconv.FormatInt(int64(p.KeepAlive), 10))
+	}
+	return q.Encode()
+}
+
+// ParseWireguardReserved parses WireGuard reserved bytes, given either as
+// comma separated numbers ("1,2,3") or as a base64 WARP client ID ("AQID").
+// An empty string yields nil.
+func ParseWireguardReserved(s string) ([]uint8, error) {
+	s = strings.TrimSpace(strings.Trim(strings.TrimSpace(s), "[]"))
+	if s == "" {
+		return nil, nil
+	}
+
+	if !strings.Contains(s, ",") {
+		if b, err := base64.StdEncoding.DecodeString(s); err == nil && len(b) == 3 {
+			return b, nil
+		}
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid reserved value %q: expected 3 bytes", s)
+	}
+	reserved := make([]uint8, 0, 3)
+	for _, part := range parts {
+		n, err := strconv.ParseUint(strings.TrimSpace(part), 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid reserved value %q: %w", s, err)
+		}
+		reserved = append(reserved, uint8(n))
+	}
+	return reserved, nil
+}
+
+// FormatWireguardReserved is the inverse of ParseWireguardReserved and
+// always returns the "1,2,3" form.
+func FormatWireguardReserved(reserved []uint8) string {
+	parts := make([]string, len(reserved))
+	for i, b := range reserved {
+		parts[i] = strconv.Itoa(int(b))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,56 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWireguardPeer_RoundTrip(t *testing.T) {
+	peer := WireguardPeer{
+		PublicKey:    "cGVlcmtleQ==",
+		PreSharedKey: "cHNr",
+		Endpoint:     "[2001:db8::1]:51820",
+		AllowedIPs:   "10.0.0.0/8,fd00::/8",
+		Reserved:     "1,2,3",
+		KeepAlive:    25,
+	}
+	got, err := ParseWireguardPeer(peer.String())
+	if err != nil {
+		t.Fatalf("ParseWireguardPeer() error = %v", err)
+	}
+	if got != peer {
+		t.Errorf("round trip = %+v, want %+v", got, peer)
+	}
+
+	if _, err := ParseWireguardPeer("endpoint=1.2.3.4%3A51820"); err == nil {
+		t.Error("expected an error for a peer without public key")
+	}
+}
+
+func TestParseWireguardReserved(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []uint8
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"1,2,3", []uint8{1, 2, 3}, false},
+		{"[ 10, 20, 30 ]", []uint8{10, 20, 30}, false},
+		{"AQID", []uint8{1, 2, 3}, false},
+		{"1,2", nil, true},
+		{"1,2,300", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseWireguardReserved(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseWireguardReserved(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseWireguardReserved(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	if got := FormatWireguardReserved([]uint8{1, 2, 3}); got != "1,2,3" {
+		t.Errorf("FormatWireguardReserved() = %q", got)
+	}
+}
@@ -0,0 +1,33 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/singbox"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/xray"
+)
+
+// MakeServerInstance builds an instance of the named core serving inbound
+// and sending its traffic out directly, a local server for the inbound's
+// protocol. inbound must have been created by that core. The instance is
+// not started.
+func MakeServerInstance(ctx context.Context, coreName string, inbound protocol.Protocol, opts FactoryOptions) (protocol.Instance, error) {
+	switch coreName {
+	case XrayCoreName:
+		in, ok := inbound.(xray.Protocol)
+		if !ok {
+			return nil, fmt.Errorf("%T is not an xray inbound", inbound)
+		}
+		c := xray.NewXrayService(opts.Verbose, opts.InsecureTLS, xray.WithInbound(in))
+		return c.MakeServerInstance(ctx)
+	case SingboxCoreName:
+		if _, ok := inbound.(singbox.Protocol); !ok {
+			return nil, fmt.Errorf("%T is not a sing-box inbound", inbound)
+		}
+		c := singbox.NewSingboxService(opts.Verbose, opts.InsecureTLS, singbox.WithInbound(inbound))
+		return c.MakeServerInstance(ctx)
+	}
+	return nil, fmt.Errorf("unknown core %q", coreName)
+}
@@ -10,21 +10,8 @@ import (
 	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
 
 	box "github.com/sagernet/sing-box"
-	"github.com/sagernet/sing-box/adapter/endpoint"
-	"github.com/sagernet/sing-box/adapter/inbound"
-	boxOutbound "github.com/sagernet/sing-box/adapter/outbound"
-	boxService "github.com/sagernet/sing-box/adapter/service"
-	"github.com/sagernet/sing-box/dns"
 	"github.com/sagernet/sing-box/option"
-	"github.com/sagernet/sing-box/protocol/hysteria2"
-	"github.com/sagernet/sing-box/protocol/shadowsocks"
-	"github.com/sagernet/sing-box/protocol/socks"
-	"github.com/sagernet/sing-box/protocol/trojan"
-	"github.com/sagernet/sing-box/protocol/vless"
-	"github.com/sagernet/sing-box/protocol/vmess"
-	"github.com/sagernet/sing-box/protocol/wireguard"
 	M "github.com/sagernet/sing/common/metadata"
-	"github.com/sagernet/sing/service"
 )
 
 // setDetour sets the Detour field on the outbound's concrete options type.
@@ -41,6 +28,8 @@ func setDetour(outbound *option.Outbound, detourTag string) error {
 		o.Detour = detourTag
 	case *option.Hysteria2OutboundOptions:
 		o.Detour = detourTag
+	case *option.TUICOutboundOptions:
+		o.Detour = detourTag
 	case *option.LegacyWireGuardOutboundOptions:
 		o.Detour = detourTag
 	case *option.SOCKSOutboundOptions:
@@ -62,7 +51,7 @@ func (c *Core) MakeChainedInstance(ctx context.Context, hops []protocol.Protocol
 
 	for i, hop := range hops {
 		out := hop.(Protocol)
-		outOpts, err := out.CraftOutboundOptions(c.AllowInsecure)
+		outOpts, err := c.craftOutbound(ctx, out)
 		if err != nil {
 			return nil, fmt.Errorf("chain hop %d: failed to craft outbound options: %w", i, err)
 		}
@@ -75,6 +64,8 @@ func (c *Core) MakeChainedInstance(ctx context.Context, hops []protocol.Protocol
 			if err := setDetour(outOpts, fmt.Sprintf("chain-%d", i+1)); err != nil {
 				return nil, fmt.Errorf("chain hop %d: %w", i, err)
 			}
+		} else {
+			c.fragmentOutbound(outOpts)
 		}
 
 		outbounds = append(outbounds, *outOpts)
@@ -97,6 +88,7 @@ func (c *Core) MakeChainedInstance(ctx context.Context, hops []protocol.Protocol
 			Level:    "trace",
 		}
 	}
+	c.applyMux(&opts)
 
 	if c.Inbound != nil {
 		opts.Inbounds = append(opts.Inbounds, *c.Inbound)
@@ -104,7 +96,7 @@ func (c *Core) MakeChainedInstance(ctx context.Context, hops []protocol.Protocol
 
 	singboxInstance, err := box.New(box.Options{
 		Options: opts,
-		Context: ctx,
+		Context: boxContext(ctx),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("chain: failed to create sing-box instance: %w", err)
@@ -124,7 +116,7 @@ func (c *Core) MakeChainedHttpClient(ctx context.Context, hops []protocol.Protoc
 
 	for i, hop := range hops {
 		out := hop.(Protocol)
-		outOpts, err := out.CraftOutboundOptions(c.AllowInsecure)
+		outOpts, err := c.craftOutbound(ctx, out)
 		if err != nil {
 			return nil, nil, fmt.Errorf("chain hop %d: failed to craft outbound options: %w", i, err)
 		}
@@ -135,6 +127,8 @@ func (c *Core) MakeChainedHttpClient(ctx context.Context, hops []protocol.Protoc
 			if err := setDetour(outOpts, fmt.Sprintf("chain-%d", i+1)); err != nil {
 				return nil, nil, fmt.Errorf("chain hop %d: %w", i, err)
 			}
+		} else {
+			c.fragmentOutbound(outOpts)
 		}
 
 		outbounds = append(outbounds, *outOpts)
@@ -156,22 +150,11 @@ func (c *Core) MakeChainedHttpClient(ctx context.Context, hops []protocol.Protoc
 			Level:    "trace",
 		}
 	}
-
-	ctx = service.ContextWithDefaultRegistry(ctx)
-	outboundRegistry := boxOutbound.NewRegistry()
-	hysteria2.RegisterOutbound(outboundRegistry)
-	shadowsocks.RegisterOutbound(outboundRegistry)
-	socks.RegisterOutbound(outboundRegistry)
-	trojan.RegisterOutbound(outboundRegistry)
-	vless.RegisterOutbound(outboundRegistry)
-	vmess.RegisterOutbound(outboundRegistry)
-	wireguard.RegisterOutbound(outboundRegistry)
-
-	ctx = box.Context(ctx, inbound.NewRegistry(), outboundRegistry, endpoint.NewRegistry(), dns.NewTransportRegistry(), boxService.NewRegistry())
+	c.applyMux(&opts)
 
 	instance, err := box.New(box.Options{
 		Options: opts,
-		Context: ctx,
+		Context: boxContext(ctx),
 	})
 	if err != nil {
 		return nil, nil, fmt.Errorf("chain: failed to create sing-box instance: %w", err)
@@ -0,0 +1,70 @@
+package singbox
+
+import (
+	"context"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+
+	"github.com/sagernet/sing-box/option"
+)
+
+// echOptions turns a link's ECH value into sing-box options. A DNS server
+// to fetch the config from becomes sing-box's own ECH query, so crafting
+// options never touches the network; resolveECH looks the config up at
+// the link's server when an instance is made. An empty ech leaves ECH off.
+func echOptions(ech string) (*option.OutboundECHOptions, error) {
+	if ech == "" {
+		return nil, nil
+	}
+	if protocol.IsECHQuery(ech) {
+		name, _, err := protocol.ParseECHQuery(ech)
+		if err != nil {
+			return nil, err
+		}
+		return &option.OutboundECHOptions{
+			Enabled:         true,
+			QueryServerName: name,
+		}, nil
+	}
+	pem, err := protocol.ECHConfigPEM(ech)
+	if err != nil {
+		return nil, err
+	}
+	return &option.OutboundECHOptions{
+		Enabled: true,
+		Config:  pem,
+	}, nil
+}
+
+// resolveECH replaces the ECH query echOptions left in out with the config
+// of p's link, looked up at the DNS server the link names rather than
+// through the instance's DNS.
+func resolveECH(ctx context.Context, p Protocol, out *option.Outbound) error {
+	var tls *option.OutboundTLSOptions
+	switch o := out.Options.(type) {
+	case *option.VLESSOutboundOptions:
+		tls = o.TLS
+	case *option.TrojanOutboundOptions:
+		tls = o.TLS
+	case *option.VMessOutboundOptions:
+		tls = o.TLS
+	}
+	if tls == nil || tls.ECH == nil || len(tls.ECH.Config) > 0 {
+		return nil
+	}
+
+	g := p.ConvertToGeneralConfig()
+	config, err := protocol.ResolveECH(ctx, g.ECH, firstNonEmpty(g.SNI, g.Host, g.Address))
+	if err != nil {
+		return err
+	}
+	pem, err := protocol.ECHConfigPEM(config)
+	if err != nil {
+		return err
+	}
+	tls.ECH = &option.OutboundECHOptions{
+		Enabled: true,
+		Config:  pem,
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package singbox
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/sagernet/sing-box/option"
+)
+
+func TestECHLookedUpWhenInstanceIsMade(t *testing.T) {
+	var queries atomic.Int32
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		queries.Add(1)
+		resp := new(dns.Msg)
+		resp.SetReply(req)
+		resp.Answer = append(resp.Answer, &dns.HTTPS{SVCB: dns.SVCB{
+			Hdr:      dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeHTTPS, Class: dns.ClassINET, Ttl: 300},
+			Priority: 1,
+			Target:   ".",
+			Value:    []dns.SVCBKeyValue{&dns.SVCBECHConfig{ECH: []byte{0x00, 0x45, 0xfe, 0x0d, 0x00, 0x41}}},
+		}})
+		w.WriteMsg(resp)
+	})}
+	go srv.ActivateAndServe()
+	defer srv.Shutdown()
+
+	link := "vless://0090bbba-1118-46ca-87a1-52599cee74ab@ech.example.com:443?security=tls&type=ws&ech=udp://" + pc.LocalAddr().String() + "#ech"
+	vless := NewVless(link).(*Vless)
+	if err := vless.Parse(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Crafting, as export and convert do, leaves the query to sing-box
+	out, err := vless.CraftOutboundOptions(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ech := out.Options.(*option.VLESSOutboundOptions).TLS.ECH
+	if ech == nil || !ech.Enabled || len(ech.Config) != 0 {
+		t.Fatalf("crafted ECH options = %+v, want a query", ech)
+	}
+	if n := queries.Load(); n != 0 {
+		t.Fatalf("crafting sent %d DNS queries", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := resolveECH(ctx, vless, out); err == nil {
+		t.Error("resolveECH() with a cancelled context: want error")
+	}
+
+	if err := resolveECH(context.Background(), vless, out); err != nil {
+		t.Fatal(err)
+	}
+	ech = out.Options.(*option.VLESSOutboundOptions).TLS.ECH
+	if len(ech.Config) == 0 || queries.Load() != 1 {
+		t.Errorf("resolved ECH options = %+v after %d queries", ech, queries.Load())
+	}
+}
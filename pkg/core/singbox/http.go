@@ -59,7 +59,7 @@ func (h *Http) CraftInboundOptions() *option.Inbound {
 
 	return &option.Inbound{
 		Type:    h.Name(),
-		Options: opts,
+		Options: &opts,
 	}
 }
 
@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/netip"
 	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
 
@@ -13,6 +16,7 @@ import (
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/option"
 	sing_hysteria2 "github.com/sagernet/sing-box/protocol/hysteria2"
+	"github.com/sagernet/sing/common/json/badoption"
 	"github.com/sagernet/sing/common/logger"
 	"github.com/sagernet/sing/service"
 )
@@ -26,7 +30,11 @@ func (h *Hysteria2) Name() string {
 }
 
 func (h *Hysteria2) Parse() error {
-	uri, err := url.Parse(h.OrigLink)
+	// url.Parse rejects multi-port hosts such as "host:443,20000-30000",
+	// so the port list is cut out before parsing.
+	link, hopPorts := splitHysteria2PortList(h.OrigLink)
+
+	uri, err := url.Parse(link)
 	if err != nil {
 		return fmt.Errorf("failed to parse Hysteria2 link: %w", err)
 	}
@@ -46,11 +54,24 @@ func (h *Hysteria2) Parse() error {
 
 	// Explicitly parse known query parameters
 	h.SNI = query.Get("sni")
-	// ALPN for Hysteria2 is often handled by the protocol itself, but if specified:
-	// h.ALPN = query.Get("alpn")
+	h.ALPN = query.Get("alpn")
 	h.ObfusType = query.Get("obfs")
 	h.ObfusPassword = query.Get("obfs-password")
 	h.Insecure = query.Get("insecure") // "0", "1", "false", "true"
+	h.PinSHA256 = query.Get("pinSHA256")
+	h.UpMbps = firstNonEmpty(query.Get("up"), query.Get("upmbps"))
+	h.DownMbps = firstNonEmpty(query.Get("down"), query.Get("downmbps"))
+	h.HopInterval = firstNonEmpty(query.Get("hop_interval"), query.Get("hopInterval"))
+
+	h.ServerPorts = hopPorts
+	if mport := query.Get("mport"); mport != "" {
+		h.ServerPorts = mport
+	}
+	if h.ServerPorts != "" {
+		if _, err := hysteria2PortRanges(h.ServerPorts); err != nil {
+			return err
+		}
+	}
 
 	unescapedRemark, err := url.PathUnescape(uri.Fragment)
 	if err != nil {
@@ -76,11 +97,32 @@ func (h *Hysteria2) DetailsStr() string {
 		color.RedString("Password"), h.Password,
 		color.RedString("SNI"), h.SNI)
 
+	if h.ServerPorts != "" {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("Port Hopping"), h.ServerPorts)
+		if h.HopInterval != "" {
+			info += fmt.Sprintf("%s: %s\n", color.RedString("Hop Interval"), h.HopInterval)
+		}
+	}
+
+	if h.ALPN != "" {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("ALPN"), h.ALPN)
+	}
+
 	if h.Insecure != "" {
 		info += fmt.Sprintf("%s: %v\n",
 			color.RedString("Insecure"), h.Insecure)
 	}
 
+	if h.PinSHA256 != "" {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("Pinned SHA256"), h.PinSHA256)
+	}
+
+	if h.UpMbps != "" || h.DownMbps != "" {
+		info += fmt.Sprintf("%s: %s\n%s: %s\n",
+			color.RedString("Up (Mbps)"), h.UpMbps,
+			color.RedString("Down (Mbps)"), h.DownMbps)
+	}
+
 	if h.ObfusType != "" {
 		info += fmt.Sprintf("%s: %s\n%s: %s\n",
 			color.RedString("Obfuscation Type"), h.ObfusType,
@@ -90,14 +132,55 @@ func (h *Hysteria2) DetailsStr() string {
 }
 
 func (h *Hysteria2) GetLink() string {
-	return h.OrigLink
+	if h.OrigLink != "" {
+		return h.OrigLink
+	}
+
+	// Password holds the still-escaped userinfo, see Parse.
+	password, err := url.PathUnescape(h.Password)
+	if err != nil {
+		password = h.Password
+	}
+
+	baseURL := url.URL{
+		Scheme: protocol.Hysteria2Identifier,
+		User:   url.User(password),
+		Host:   net.JoinHostPort(h.Address, h.Port),
+		Path:   "/",
+	}
+
+	params := url.Values{}
+	addQueryParam := func(key, value string) {
+		if value != "" {
+			params.Add(key, value)
+		}
+	}
+	addQueryParam("sni", h.SNI)
+	addQueryParam("alpn", h.ALPN)
+	addQueryParam("obfs", h.ObfusType)
+	addQueryParam("obfs-password", h.ObfusPassword)
+	addQueryParam("insecure", h.Insecure)
+	addQueryParam("pinSHA256", h.PinSHA256)
+	addQueryParam("mport", h.ServerPorts)
+	addQueryParam("hop_interval", h.HopInterval)
+	addQueryParam("up", h.UpMbps)
+	addQueryParam("down", h.DownMbps)
+	baseURL.RawQuery = params.Encode()
+	baseURL.Fragment = h.Remark
+
+	return baseURL.String()
 }
 
 func (h *Hysteria2) ConvertToGeneralConfig() (g protocol.GeneralConfig) {
 	g.Protocol = h.Name()
 	g.Address = h.Address
 	g.Port = h.Port
+	g.ID = h.Password
 	g.Remark = h.Remark
+	g.SNI = h.SNI
+	g.ALPN = h.ALPN
+	g.Obfs = h.ObfusType
+	g.ObfsPassword = h.ObfusPassword
 
 	g.OrigLink = h.GetLink()
 
@@ -114,6 +197,18 @@ func (h *Hysteria2) CraftOutboundOptions(allowInsecure bool) (*option.Outbound,
 		}
 	}
 
+	// pinSHA256 is the hash of the whole certificate, while sing-box can only
+	// pin public keys. Neither can stand in for the other, and skipping
+	// verification would trust any server.
+	if h.PinSHA256 != "" {
+		return nil, fmt.Errorf("hysteria2 pinSHA256 pins the certificate, which sing-box cannot verify")
+	}
+
+	var alpn []string
+	if h.ALPN != "" && h.ALPN != "none" {
+		alpn = strings.Split(h.ALPN, ",")
+	}
+
 	opts := option.Hysteria2OutboundOptions{
 		DialerOptions: option.DialerOptions{},
 		ServerOptions: option.ServerOptions{
@@ -126,10 +221,41 @@ func (h *Hysteria2) CraftOutboundOptions(allowInsecure bool) (*option.Outbound,
 				Enabled:    true,
 				ServerName: h.SNI,
 				Insecure:   insecure,
+				ALPN:       alpn,
 			},
 		},
 	}
 
+	if h.ServerPorts != "" {
+		ranges, err := hysteria2PortRanges(h.ServerPorts)
+		if err != nil {
+			return nil, err
+		}
+		opts.ServerPorts = ranges
+		if h.HopInterval != "" {
+			interval, err := parseHopInterval(h.HopInterval)
+			if err != nil {
+				return nil, err
+			}
+			opts.HopInterval = badoption.Duration(interval)
+		}
+	}
+
+	if h.UpMbps != "" {
+		up, err := parseMbps(h.UpMbps)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hysteria2 up bandwidth %q: %w", h.UpMbps, err)
+		}
+		opts.UpMbps = up
+	}
+	if h.DownMbps != "" {
+		down, err := parseMbps(h.DownMbps)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hysteria2 down bandwidth %q: %w", h.DownMbps, err)
+		}
+		opts.DownMbps = down
+	}
+
 	if h.ObfusType != "" {
 		opts.Obfs = &option.Hysteria2Obfs{
 			Type:     h.ObfusType,
@@ -144,8 +270,37 @@ func (h *Hysteria2) CraftOutboundOptions(allowInsecure bool) (*option.Outbound,
 }
 
 func (h *Hysteria2) CraftInboundOptions() *option.Inbound {
+	port, _ := strconv.Atoi(h.Port)
+	addr, _ := netip.ParseAddr(h.Address)
+
+	tapAddr := badoption.Addr(addr)
+	opts := option.Hysteria2InboundOptions{
+		ListenOptions: option.ListenOptions{
+			Listen:     &tapAddr,
+			ListenPort: uint16(port),
+		},
+		Users: []option.Hysteria2User{
+			{
+				Name:     "user",
+				Password: h.Password,
+			},
+		},
+		// Hysteria2 runs over QUIC, which always needs a certificate
+		InboundTLSOptionsContainer: option.InboundTLSOptionsContainer{
+			TLS: inboundTLS("tls", h.CertFile, h.KeyFile, h.SNI, "h3"),
+		},
+	}
+	if h.ObfusType != "" {
+		opts.Obfs = &option.Hysteria2Obfs{
+			Type:     h.ObfusType,
+			Password: h.ObfusPassword,
+		}
+	}
+
 	return &option.Inbound{
-		Type: h.Name(),
+		Type:    h.Name(),
+		Tag:     "hysteria2-in",
+		Options: &opts,
 	}
 }
 
@@ -168,3 +323,90 @@ func (h *Hysteria2) CraftOutbound(ctx context.Context, l logger.ContextLogger, a
 
 	return out, nil
 }
+
+// splitHysteria2PortList cuts a multi-port list ("443,20000-30000") out of the
+// link's host so the rest can go through url.Parse. The first port is kept as
+// the link's main port. Links with a single port are returned unchanged.
+func splitHysteria2PortList(link string) (string, string) {
+	schemeEnd := strings.Index(link, "://")
+	if schemeEnd < 0 {
+		return link, ""
+	}
+	rest := link[schemeEnd+3:]
+	authEnd := strings.IndexAny(rest, "/?#")
+	if authEnd < 0 {
+		authEnd = len(rest)
+	}
+	authority := rest[:authEnd]
+	hostStart := strings.LastIndex(authority, "@") + 1
+	hostPort := authority[hostStart:]
+
+	colon := strings.LastIndex(hostPort, ":")
+	if colon < 0 || colon < strings.LastIndex(hostPort, "]") {
+		return link, ""
+	}
+	ports := hostPort[colon+1:]
+	sep := strings.IndexAny(ports, ",-")
+	if sep <= 0 {
+		return link, ""
+	}
+
+	return link[:schemeEnd+3] + authority[:hostStart] + hostPort[:colon+1] + ports[:sep] + rest[authEnd:], ports
+}
+
+// hysteria2PortRanges converts a Hysteria port list ("443,20000-30000") into
+// sing-box's server_ports format (["443:443", "20000:30000"]).
+func hysteria2PortRanges(ports string) ([]string, error) {
+	var ranges []string
+	for _, part := range strings.Split(ports, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		if !isRange {
+			to = from
+		}
+		start, err := strconv.ParseUint(from, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hysteria2 port range %q", part)
+		}
+		end, err := strconv.ParseUint(to, 10, 16)
+		if err != nil || end < start {
+			return nil, fmt.Errorf("invalid hysteria2 port range %q", part)
+		}
+		ranges = append(ranges, fmt.Sprintf("%d:%d", start, end))
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("empty hysteria2 port range list")
+	}
+	return ranges, nil
+}
+
+// parseHopInterval accepts both bare seconds ("30") and Go durations ("30s").
+func parseHopInterval(s string) (time.Duration, error) {
+	if secs, err := strconv.Atoi(s); err == nil {
+		return time.Duration(secs) * time.Second, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hysteria2 hop interval %q", s)
+	}
+	return d, nil
+}
+
+// parseMbps parses bandwidth hints such as "100", "100mbps" or "100 Mbps".
+func parseMbps(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimSpace(strings.TrimSuffix(s, "mbps"))
+	return strconv.Atoi(s)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
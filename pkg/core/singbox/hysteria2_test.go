@@ -1,7 +1,11 @@
 package singbox
 
 import (
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
 )
 
 func TestNewHysteria2(t *testing.T) {
@@ -17,6 +21,96 @@ func TestNewHysteria2(t *testing.T) {
 	t.Logf("%s\n", hys2.DetailsStr())
 }
 
+func TestHysteria2_PortHopping(t *testing.T) {
+	tests := []struct {
+		name       string
+		link       string
+		wantPort   string
+		wantRanges []string
+	}{
+		{
+			name:       "Range in host",
+			link:       "hysteria2://secret@hop.example.com:20000-30000/?sni=hop.example.com&hop_interval=30#hop",
+			wantPort:   "20000",
+			wantRanges: []string{"20000:30000"},
+		},
+		{
+			name:       "Mixed list in host",
+			link:       "hy2://secret@[2001:db8::1]:443,20000-30000?insecure=1",
+			wantPort:   "443",
+			wantRanges: []string{"443:443", "20000:30000"},
+		},
+		{
+			name:       "mport parameter",
+			link:       "hysteria2://secret@hop.example.com:443?mport=40000-50000&hop_interval=45s",
+			wantPort:   "443",
+			wantRanges: []string{"40000:50000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := (&Core{}).CreateProtocol(tt.link)
+			if err != nil {
+				t.Fatalf("CreateProtocol() error = %v", err)
+			}
+			hys2 := p.(*Hysteria2)
+			if err := hys2.Parse(); err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if hys2.Port != tt.wantPort {
+				t.Errorf("Port = %q, want %q", hys2.Port, tt.wantPort)
+			}
+
+			out, err := hys2.CraftOutboundOptions(false)
+			if err != nil {
+				t.Fatalf("CraftOutboundOptions() error = %v", err)
+			}
+			opts := out.Options.(*option.Hysteria2OutboundOptions)
+			if !reflect.DeepEqual([]string(opts.ServerPorts), tt.wantRanges) {
+				t.Errorf("ServerPorts = %v, want %v", opts.ServerPorts, tt.wantRanges)
+			}
+		})
+	}
+}
+
+func TestHysteria2_BandwidthAndALPN(t *testing.T) {
+	link := "hysteria2://secret@example.com:443?alpn=h3&up=50&down=200%20mbps&mport=20000-21000&hop_interval=45s#bw"
+
+	hys2 := NewHysteria2(link).(*Hysteria2)
+	if err := hys2.Parse(); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	out, err := hys2.CraftOutboundOptions(false)
+	if err != nil {
+		t.Fatalf("CraftOutboundOptions() error = %v", err)
+	}
+	opts := out.Options.(*option.Hysteria2OutboundOptions)
+	if opts.UpMbps != 50 || opts.DownMbps != 200 {
+		t.Errorf("bandwidth = %d/%d, want 50/200", opts.UpMbps, opts.DownMbps)
+	}
+	if time.Duration(opts.HopInterval) != 45*time.Second {
+		t.Errorf("HopInterval = %v, want 45s", time.Duration(opts.HopInterval))
+	}
+	if !reflect.DeepEqual([]string(opts.TLS.ALPN), []string{"h3"}) {
+		t.Errorf("ALPN = %v, want [h3]", opts.TLS.ALPN)
+	}
+}
+
+func TestHysteria2_PinSHA256(t *testing.T) {
+	hys2 := NewHysteria2("hysteria2://secret@pin.example.com:443?sni=pin.example.com&pinSHA256=ba:88:45:17:a1:29:7b:f3:3c:a8:45:31:0c:c6:34:44:0a:a4:1b:4a:d8:50:71:1c:4c:df:3d:5c:4b:3d:9e:50").(*Hysteria2)
+	if err := hys2.Parse(); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	// A pin sing-box can't check must not turn into an outbound that skips
+	// verification or one that always fails
+	out, err := hys2.CraftOutboundOptions(false)
+	if err == nil {
+		t.Errorf("expected a pinned link to be refused, got %+v", out.Options.(*option.Hysteria2OutboundOptions).TLS)
+	}
+}
+
 //func TestHysteria2_MakeHttpClient(t *testing.T) {
 //	var hys2 Hysteria2
 //	err := hys2.Parse("hysteria2://fKt0mUHH2UKx6kl3xdI43yiV@laser.kafsabtaheri.com:8443/?sni=laser.kafsabtaheri.com&alpn=h3%2Ch2%2Chttp%2F1.1&obfs=salamander&obfs-password=HGdgfYUJFGjgD&insecure=1#H")
@@ -0,0 +1,49 @@
+package singbox
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+)
+
+// inboundTransport returns the V2Ray transport of an inbound, nil for plain
+// TCP. sing-box has no server side for xhttp.
+func inboundTransport(network, path, host, serviceName string) *option.V2RayTransportOptions {
+	switch network {
+	case "ws":
+		return &option.V2RayTransportOptions{
+			Type:             network,
+			WebsocketOptions: option.V2RayWebsocketOptions{Path: path},
+		}
+	case "httpupgrade":
+		return &option.V2RayTransportOptions{
+			Type:               network,
+			HTTPUpgradeOptions: option.V2RayHTTPUpgradeOptions{Path: path, Host: host},
+		}
+	case "grpc":
+		return &option.V2RayTransportOptions{
+			Type:        network,
+			GRPCOptions: option.V2RayGRPCOptions{ServiceName: strings.TrimPrefix(serviceName, "/")},
+		}
+	}
+	return nil
+}
+
+// inboundTLS returns the TLS options of an inbound serving certFile, or nil
+// when there is no certificate: a client link carries none, so TLS is only
+// served when the caller provides one.
+func inboundTLS(security, certFile, keyFile, sni, alpn string) *option.InboundTLSOptions {
+	if security != "tls" || certFile == "" || keyFile == "" {
+		return nil
+	}
+	tls := &option.InboundTLSOptions{
+		Enabled:         true,
+		ServerName:      sni,
+		CertificatePath: certFile,
+		KeyPath:         keyFile,
+	}
+	if alpn != "" {
+		tls.ALPN = strings.Split(alpn, ",")
+	}
+	return tls
+}
@@ -37,12 +37,15 @@ type Vmess struct {
 	SNI            string      `json:"sni"`  // Server name indication
 	ALPN           string      `json:"alpn"` // Application-Layer Protocol Negotiation
 	TlsFingerprint string      `json:"fp"`   // TLS fingerprint
+	ECH            string      `json:"ech"`  // ECHConfigList (base64) or a DNS server to fetch it from
 	Type           string      `json:"type"` // Used for HTTP Obfuscation
 
 	//// It's also possible for Vmess to have REALITY...
 	//PublicKey string `json:"pbk"`
 	//ShortIds  string `json:"sid"` // Mandatory, the shortId list available to the client, which can be used to distinguish different clients
 	//SpiderX   string `json:"spx"` // Reality path
+	CertFile string `json:"-"`
+	KeyFile  string `json:"-"`
 
 	OrigLink string `json:"-"` // Original link
 }
@@ -71,7 +74,12 @@ type Vless struct {
 	Remark         string `json:"ps"`            // Config's name
 	ServiceName    string `json:"serviceName"`   // GRPC
 	Mode           string `json:"mode"`          // GRPC
-	OrigLink       string `json:"-"`             // Original link
+	CertFile       string `json:"-"`
+	KeyFile        string `json:"-"`
+	OrigLink       string `json:"-"` // Original link
+
+	ECH string               `json:"ech"` // ECHConfigList (base64) or a DNS server to fetch it from
+	Mux *protocol.MuxOptions `json:"-"`   // Multiplexing, from the mux* parameters
 }
 
 type Shadowsocks struct {
@@ -79,6 +87,9 @@ type Shadowsocks struct {
 	Port       string
 	Encryption string
 	Password   string
+	Plugin     string // SIP003 plugin name (obfs-local, v2ray-plugin, ...)
+	PluginOpts string // SIP003 plugin options, e.g. "obfs=http;obfs-host=example.com"
+	Mux        *protocol.MuxOptions
 	Remark     string
 	OrigLink   string // Original link
 }
@@ -103,12 +114,17 @@ type Trojan struct {
 	Remark         string // Config's name
 	ServiceName    string `json:"serviceName"` // GRPC
 	Mode           string `json:"mode"`        // GRPC
+	CertFile       string `json:"-"`
+	KeyFile        string `json:"-"`
 
 	// Yes, Trojan can have reality too xD
 	PublicKey string `json:"pbk"`
 	ShortIds  string `json:"sid"` // Mandatory, the shortId list available to the client, which can be used to distinguish different clients
 	SpiderX   string `json:"spx"` // Reality path
 
+	ECH string               `json:"ech"` // ECHConfigList (base64) or a DNS server to fetch it from
+	Mux *protocol.MuxOptions `json:"-"`   // Multiplexing, from the mux* parameters
+
 	OrigLink string `json:"-"` // Original link
 }
 
@@ -116,11 +132,17 @@ type Wireguard struct {
 	Remark       string
 	PublicKey    string `json:"publickey"`
 	SecretKey    string `json:"secretkey"`
+	PreSharedKey string `json:"presharedkey"`
 	Endpoint     string
 	Reserved     string `json:"reserved"`
-	LocalAddress string `json:"address"` // Local address IPv4/IPv6 seperated by commas
+	LocalAddress string `json:"address"`    // Local address IPv4/IPv6 seperated by commas
+	AllowedIPs   string `json:"allowedips"` // CIDRs routed to the peer seperated by commas
+	KeepAlive    int32  `json:"keepalive"`
+	Workers      int32  `json:"workers"`
 	Mtu          int32  `json:"mtu"`
 
+	Peers []protocol.WireguardPeer `json:"-"` // Additional peers
+
 	OrigLink string `json:"-"` // Original link
 }
 
@@ -137,10 +159,33 @@ type Hysteria2 struct {
 	Remark        string
 	Address       string
 	Port          string
+	ServerPorts   string `json:"mport"` // Port hopping ranges, e.g. "20000-30000,40000"
+	HopInterval   string `json:"hop_interval"`
 	Password      string
 	ObfusType     string `json:"obfs"`
 	ObfusPassword string `json:"obfs-password"`
 	SNI           string `json:"sni"`
+	ALPN          string `json:"alpn"`
 	Insecure      string `json:"insecure"`
+	PinSHA256     string `json:"pinSHA256"`
+	UpMbps        string `json:"up"`
+	DownMbps      string `json:"down"`
+	CertFile      string `json:"-"`
+	KeyFile       string `json:"-"`
 	OrigLink      string // Original link
 }
+
+type Tuic struct {
+	Remark            string
+	Address           string
+	Port              string
+	UUID              string
+	Password          string
+	CongestionControl string `json:"congestion_control"`
+	UDPRelayMode      string `json:"udp_relay_mode"`
+	ALPN              string `json:"alpn"`
+	SNI               string `json:"sni"`
+	AllowInsecure     string `json:"allow_insecure"`
+	DisableSNI        string `json:"disable_sni"`
+	OrigLink          string // Original link
+}
@@ -0,0 +1,62 @@
+package singbox
+
+import (
+	"fmt"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+)
+
+// KnownFingerprint reports whether sing-box has a uTLS fingerprint called
+// name. Mirrors uTLSClientHelloID in sing-box's common/tls.
+func KnownFingerprint(name string) bool {
+	switch name {
+	case "", "chrome", "chrome_psk", "chrome_psk_shuffle", "chrome_padding_psk_shuffle", "chrome_pq", "chrome_pq_psk",
+		"firefox", "edge", "safari", "360", "qq", "ios", "android", "random", "randomized":
+		return true
+	}
+	return false
+}
+
+func (v *Vless) LintFields() protocol.LintFields {
+	return protocol.LintFields{
+		Protocol:         v.Name(),
+		Address:          v.Address,
+		Port:             v.Port,
+		ID:               v.ID,
+		Security:         v.Security,
+		Transport:        v.Type,
+		Flow:             v.Flow,
+		PublicKey:        v.PublicKey,
+		ShortID:          v.ShortIds,
+		Fingerprint:      v.TlsFingerprint,
+		KnownFingerprint: KnownFingerprint,
+	}
+}
+
+func (t *Trojan) LintFields() protocol.LintFields {
+	return protocol.LintFields{
+		Protocol:         t.Name(),
+		Address:          t.Address,
+		Port:             t.Port,
+		Security:         t.Security,
+		Transport:        t.Type,
+		Flow:             t.Flow,
+		PublicKey:        t.PublicKey,
+		ShortID:          t.ShortIds,
+		Fingerprint:      t.TlsFingerprint,
+		KnownFingerprint: KnownFingerprint,
+	}
+}
+
+func (v *Vmess) LintFields() protocol.LintFields {
+	return protocol.LintFields{
+		Protocol:         v.Name(),
+		Address:          v.Address,
+		Port:             fmt.Sprintf("%v", v.Port),
+		ID:               v.ID,
+		Security:         v.TLS,
+		Transport:        v.Network,
+		Fingerprint:      v.TlsFingerprint,
+		KnownFingerprint: KnownFingerprint,
+	}
+}
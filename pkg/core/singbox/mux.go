@@ -0,0 +1,41 @@
+package singbox
+
+import (
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+
+	"github.com/sagernet/sing-box/option"
+)
+
+// multiplexOptions converts m into sing-mux options; nil stays nil.
+// XudpConcurrency is an xray-core knob and has no equivalent here.
+func multiplexOptions(m *protocol.MuxOptions) *option.OutboundMultiplexOptions {
+	if m == nil || !m.Enabled {
+		return nil
+	}
+	return &option.OutboundMultiplexOptions{
+		Enabled:    true,
+		Protocol:   m.Protocol,
+		MaxStreams: m.Concurrency,
+		Padding:    m.Padding,
+	}
+}
+
+// applyMux replaces the link's multiplex settings of every outbound in the
+// option tree with the core-wide override, if any.
+func (c *Core) applyMux(opts *option.Options) {
+	if c == nil || c.Mux == nil {
+		return
+	}
+	for _, ob := range opts.Outbounds {
+		switch o := ob.Options.(type) {
+		case *option.VMessOutboundOptions:
+			o.Multiplex = multiplexOptions(c.Mux)
+		case *option.VLESSOutboundOptions:
+			o.Multiplex = multiplexOptions(c.Mux)
+		case *option.TrojanOutboundOptions:
+			o.Multiplex = multiplexOptions(c.Mux)
+		case *option.ShadowsocksOutboundOptions:
+			o.Multiplex = multiplexOptions(c.Mux)
+		}
+	}
+}
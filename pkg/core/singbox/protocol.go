@@ -12,8 +12,9 @@ func (c *Core) CreateProtocol(configLink string) (protocol.Protocol, error) {
 	// Remove any spaces
 	configLink = strings.TrimSpace(configLink)
 
-	// Parse url
-	uri, err := url.Parse(configLink)
+	// Parse url; port-hopping Hysteria2 links carry a port list url.Parse rejects
+	base, _ := splitHysteria2PortList(configLink)
+	uri, err := url.Parse(base)
 	if err != nil {
 		return nil, err
 	}
@@ -35,6 +36,8 @@ func (c *Core) CreateProtocol(configLink string) (protocol.Protocol, error) {
 		return NewHysteria2(configLink), nil
 	case "hy2":
 		return NewHysteria2(configLink), nil
+	case protocol.TuicIdentifier:
+		return NewTuic(configLink), nil
 
 	default:
 		return nil, errors.New("invalid singbox protocol")
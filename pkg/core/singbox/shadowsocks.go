@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/netip"
 	"net/url"
 	"strconv"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/option"
 	sing_shadowsocks "github.com/sagernet/sing-box/protocol/shadowsocks"
+	"github.com/sagernet/sing/common/json/badoption"
 	"github.com/sagernet/sing/common/logger"
 	"github.com/sagernet/sing/service"
 )
@@ -75,6 +77,11 @@ func (s *Shadowsocks) Parse() error {
 		s.Address = "[" + s.Address + "]"
 	}
 
+	s.Plugin, s.PluginOpts = protocol.SplitSIP003Plugin(uri.Query().Get("plugin"))
+	if s.Mux, err = protocol.ParseMuxParams(uri.Query()); err != nil {
+		return err
+	}
+
 	s.Remark, err = url.PathUnescape(uri.Fragment)
 	if err != nil {
 		s.Remark = uri.Fragment
@@ -103,6 +110,16 @@ func (s *Shadowsocks) DetailsStr() string {
 		color.RedString("Port"), s.Port,
 		color.RedString("Encryption"), s.Encryption,
 		color.RedString("Password"), s.Password)
+
+	if s.Plugin != "" {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("Plugin"), s.Plugin)
+		if s.PluginOpts != "" {
+			info += fmt.Sprintf("%s: %s\n", color.RedString("Plugin Options"), s.PluginOpts)
+		}
+	}
+	if s.Mux != nil {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("Mux"), s.Mux)
+	}
 	return info
 }
 
@@ -114,16 +131,34 @@ func (s *Shadowsocks) ConvertToGeneralConfig() (g protocol.GeneralConfig) {
 	g.Protocol = s.Name()
 	g.Address = s.Address
 	g.ID = s.Password
+	g.Security = s.Encryption
 	g.Port = s.Port
 	g.Remark = s.Remark
+	g.Plugin = s.Plugin
+	g.PluginOpts = s.PluginOpts
 	g.OrigLink = s.GetLink()
 
 	return g
 }
 
 func (s *Shadowsocks) CraftInboundOptions() *option.Inbound {
+	port, _ := strconv.Atoi(s.Port)
+	addr, _ := netip.ParseAddr(s.Address)
+
+	tapAddr := badoption.Addr(addr)
+	opts := option.ShadowsocksInboundOptions{
+		ListenOptions: option.ListenOptions{
+			Listen:     &tapAddr,
+			ListenPort: uint16(port),
+		},
+		Method:   s.Encryption,
+		Password: s.Password,
+	}
+
 	return &option.Inbound{
-		Type: "shadowsocks",
+		Type:    s.Name(),
+		Tag:     "shadowsocks-in",
+		Options: &opts,
 	}
 }
 
@@ -136,8 +171,21 @@ func (s *Shadowsocks) CraftOutboundOptions(allowInsecure bool) (*option.Outbound
 			Server:     s.Address,
 			ServerPort: uint16(port),
 		},
-		Password: s.Password,
-		Method:   s.Encryption,
+		Password:  s.Password,
+		Method:    s.Encryption,
+		Multiplex: multiplexOptions(s.Mux),
+	}
+
+	switch s.Plugin {
+	case "":
+	case protocol.PluginObfsLocal, protocol.PluginV2rayPlugin:
+		// Both are built into sing-box's SIP003 implementation.
+		opts.Plugin = s.Plugin
+		opts.PluginOptions = s.PluginOpts
+	case protocol.PluginShadowTLS:
+		return nil, fmt.Errorf("shadowsocks plugin %q needs a separate shadowtls outbound and is not supported", s.Plugin)
+	default:
+		return nil, fmt.Errorf("unsupported shadowsocks plugin %q", s.Plugin)
 	}
 
 	return &option.Outbound{
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
@@ -17,15 +18,19 @@ import (
 	"github.com/sagernet/sing-box/dns"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/protocol/direct"
 	"github.com/sagernet/sing-box/protocol/hysteria2"
+	"github.com/sagernet/sing-box/protocol/mixed"
 	"github.com/sagernet/sing-box/protocol/shadowsocks"
 	"github.com/sagernet/sing-box/protocol/socks"
 	"github.com/sagernet/sing-box/protocol/trojan"
+	"github.com/sagernet/sing-box/protocol/tuic"
 	"github.com/sagernet/sing-box/protocol/vless"
 	"github.com/sagernet/sing-box/protocol/vmess"
 	"github.com/sagernet/sing-box/protocol/wireguard"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/service"
 )
 
@@ -41,6 +46,13 @@ type Core struct {
 	// BindInterface, when set, pins all outbound sing-box dials to the
 	// named OS interface via RouteOptions.DefaultInterface.
 	BindInterface string
+
+	// Fragment, when set, enables TLS ClientHello fragmentation on every
+	// TLS outbound. Noises have no sing-box equivalent and are ignored.
+	Fragment *protocol.FragmentOptions
+
+	// Mux, when set, overrides the multiplex settings of every outbound.
+	Mux *protocol.MuxOptions
 }
 
 func (c *Core) Name() string {
@@ -73,6 +85,22 @@ func WithBindInterface(iface string) ServiceOption {
 	}
 }
 
+// WithFragment configures TLS fragmentation for all outbounds. nil
+// disables it.
+func WithFragment(fragment *protocol.FragmentOptions) ServiceOption {
+	return func(c *Core) {
+		c.Fragment = fragment
+	}
+}
+
+// WithMux overrides the multiplex settings of all outbounds, whatever
+// their links say. nil leaves mux to the links.
+func WithMux(mux *protocol.MuxOptions) ServiceOption {
+	return func(c *Core) {
+		c.Mux = mux
+	}
+}
+
 func NewSingboxService(verbose bool, allowInsecure bool, opts ...ServiceOption) *Core {
 	s := &Core{
 		Inbound:       nil,
@@ -116,6 +144,32 @@ func (c *Core) applyBind(opts *option.Options) {
 	opts.Route.DefaultInterface = c.BindInterface
 }
 
+// applyFragment turns on tls.fragment for every TLS outbound in the option
+// tree. sing-box splits the ClientHello on its own (at the SNI), so only
+// Packets matters here; the length/interval ranges are xray-core knobs.
+func (c *Core) applyFragment(opts *option.Options) {
+	for i := range opts.Outbounds {
+		c.fragmentOutbound(&opts.Outbounds[i])
+	}
+}
+
+func (c *Core) fragmentOutbound(ob *option.Outbound) {
+	if c == nil || c.Fragment == nil || c.Fragment.Packets == "" {
+		return
+	}
+	wrapper, ok := ob.Options.(option.OutboundTLSOptionsWrapper)
+	if !ok {
+		return
+	}
+	tls := wrapper.TakeOutboundTLSOptions()
+	if tls == nil || !tls.Enabled {
+		return
+	}
+	tls.Fragment = true
+	// The TLS clients only wrap the conn when record fragmentation is on.
+	tls.RecordFragment = true
+}
+
 type FakeInstance struct {
 }
 
@@ -133,10 +187,23 @@ func (c *Core) SetInbound(inbound protocol.Protocol) error {
 	return nil
 }
 
+// craftOutbound crafts the options of out for an instance being made,
+// fetching what the link only points to, like an ECH config in DNS.
+func (c *Core) craftOutbound(ctx context.Context, out Protocol) (*option.Outbound, error) {
+	opts, err := out.CraftOutboundOptions(c.AllowInsecure)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveECH(ctx, out, opts); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
 func (c *Core) MakeInstance(ctx context.Context, outbound protocol.Protocol) (protocol.Instance, error) {
 	out := outbound.(Protocol)
 
-	outOpts, err := out.CraftOutboundOptions(c.AllowInsecure)
+	outOpts, err := c.craftOutbound(ctx, out)
 	if err != nil {
 		return nil, err
 	}
@@ -163,10 +230,12 @@ func (c *Core) MakeInstance(ctx context.Context, outbound protocol.Protocol) (pr
 	}
 
 	c.applyBind(&opts)
+	c.applyFragment(&opts)
+	c.applyMux(&opts)
 
 	singboxInstance, err := box.New(box.Options{
 		Options: opts,
-		Context: ctx,
+		Context: boxContext(ctx),
 	})
 
 	if err != nil {
@@ -176,20 +245,18 @@ func (c *Core) MakeInstance(ctx context.Context, outbound protocol.Protocol) (pr
 	return singboxInstance, nil
 }
 
-func (c *Core) MakeHttpClient(ctx context.Context, outbound protocol.Protocol, maxDelay time.Duration) (*http.Client, protocol.Instance, error) {
-	out := outbound.(Protocol)
-
-	outOpts, err := out.CraftOutboundOptions(c.AllowInsecure)
-	if err != nil {
-		return nil, nil, err
+// MakeServerInstance builds an instance serving the inbound set with
+// SetInbound and sending its traffic out directly, i.e. a server for the
+// inbound's protocol.
+func (c *Core) MakeServerInstance(ctx context.Context) (protocol.Instance, error) {
+	if c.Inbound == nil {
+		return nil, fmt.Errorf("no inbound set")
 	}
-	outboundTag := "http_client_outbound"
-	outOpts.Tag = outboundTag
 
 	opts := option.Options{
-		Inbounds: []option.Inbound{},
+		Inbounds: []option.Inbound{*c.Inbound},
 		Outbounds: []option.Outbound{
-			*outOpts,
+			{Type: "direct", Tag: "direct", Options: &option.DirectOutboundOptions{}},
 		},
 		Log: &option.LogOptions{
 			Disabled: true,
@@ -202,23 +269,78 @@ func (c *Core) MakeHttpClient(ctx context.Context, outbound protocol.Protocol, m
 		}
 	}
 
-	c.applyBind(&opts)
+	return box.New(box.Options{
+		Options: opts,
+		Context: boxContext(ctx),
+	})
+}
 
+// boxContext returns ctx carrying the registries of the inbounds and
+// outbounds xray-knife builds, which sing-box looks them up in.
+func boxContext(ctx context.Context) context.Context {
 	ctx = service.ContextWithDefaultRegistry(ctx)
+
+	inboundRegistry := inbound.NewRegistry()
+	hysteria2.RegisterInbound(inboundRegistry)
+	mixed.RegisterInbound(inboundRegistry)
+	shadowsocks.RegisterInbound(inboundRegistry)
+	socks.RegisterInbound(inboundRegistry)
+	trojan.RegisterInbound(inboundRegistry)
+	tuic.RegisterInbound(inboundRegistry)
+	vless.RegisterInbound(inboundRegistry)
+	vmess.RegisterInbound(inboundRegistry)
+
 	outboundRegistry := boxOutbound.NewRegistry()
+	direct.RegisterOutbound(outboundRegistry)
 	hysteria2.RegisterOutbound(outboundRegistry)
 	shadowsocks.RegisterOutbound(outboundRegistry)
 	socks.RegisterOutbound(outboundRegistry)
 	trojan.RegisterOutbound(outboundRegistry)
+	tuic.RegisterOutbound(outboundRegistry)
 	vless.RegisterOutbound(outboundRegistry)
 	vmess.RegisterOutbound(outboundRegistry)
 	wireguard.RegisterOutbound(outboundRegistry)
 
-	ctx = box.Context(ctx, inbound.NewRegistry(), outboundRegistry, endpoint.NewRegistry(), dns.NewTransportRegistry(), boxService.NewRegistry())
+	return box.Context(ctx, inboundRegistry, outboundRegistry, endpoint.NewRegistry(), dns.NewTransportRegistry(), boxService.NewRegistry())
+}
+
+// httpClientOutboundTag tags the outbound of the instances MakeHttpClient
+// builds.
+const httpClientOutboundTag = "http_client_outbound"
+
+func (c *Core) MakeHttpClient(ctx context.Context, outbound protocol.Protocol, maxDelay time.Duration) (*http.Client, protocol.Instance, error) {
+	out := outbound.(Protocol)
+
+	outOpts, err := c.craftOutbound(ctx, out)
+	if err != nil {
+		return nil, nil, err
+	}
+	outboundTag := httpClientOutboundTag
+	outOpts.Tag = outboundTag
+
+	opts := option.Options{
+		Inbounds: []option.Inbound{},
+		Outbounds: []option.Outbound{
+			*outOpts,
+		},
+		Log: &option.LogOptions{
+			Disabled: true,
+		},
+	}
+	if c.Verbose {
+		opts.Log = &option.LogOptions{
+			Disabled: false,
+			Level:    "trace",
+		}
+	}
+
+	c.applyBind(&opts)
+	c.applyFragment(&opts)
+	c.applyMux(&opts)
 
 	instance, err := box.New(box.Options{
 		Options: opts,
-		Context: ctx,
+		Context: boxContext(ctx),
 	})
 	if err != nil {
 		return nil, nil, err
@@ -268,6 +390,24 @@ func (c *Core) MakeHttpClient(ctx context.Context, outbound protocol.Protocol, m
 	}, instance, nil
 }
 
+// ListenPacket opens a UDP connection to destination (host:port) through
+// the outbound of instance, which MakeHttpClient built. Some outbounds tie
+// the connection to destination, so packets should only be sent there.
+func ListenPacket(ctx context.Context, instance protocol.Instance, destination string) (net.PacketConn, error) {
+	b, ok := instance.(*box.Box)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a sing-box instance", instance)
+	}
+	out, ok := b.Outbound().Outbound(httpClientOutboundTag)
+	if !ok {
+		return nil, fmt.Errorf("outbound adapter not found for tag: %s", httpClientOutboundTag)
+	}
+	if !slices.Contains(out.Network(), N.NetworkUDP) {
+		return nil, protocol.ErrUDPUnsupported
+	}
+	return out.ListenPacket(ctx, M.ParseSocksaddr(destination))
+}
+
 //
 //func (c *Core) MakeDial() func(ctx context.Context, v *Instance, dest net.Destination) (net.Conn, error) {
 //
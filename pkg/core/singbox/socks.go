@@ -88,6 +88,9 @@ func (s *Socks) ConvertToGeneralConfig() (g protocol.GeneralConfig) {
 	g.Protocol = s.Name()
 	g.Address = s.Address
 	g.Port = fmt.Sprintf("%v", s.Port)
+	if s.Username != "" {
+		g.ID = s.Username + ":" + s.Password
+	}
 	g.Remark = s.Remark
 
 	g.OrigLink = s.GetLink()
@@ -162,7 +165,7 @@ func (s *Socks) CraftInboundOptions() *option.Inbound {
 
 	return &option.Inbound{
 		Type:    s.Name(),
-		Options: opts,
+		Options: &opts,
 	}
 }
 
@@ -82,6 +82,12 @@ func (t *Trojan) Parse() error {
 	t.QuicSecurity = query.Get("quicSecurity") // For QUIC transport
 	t.Key = query.Get("key")                   // For QUIC transport
 	// t.Authority = query.Get("authority") // Not a standard Trojan query param
+	if t.Mux, err = protocol.ParseMuxParams(query); err != nil {
+		return err
+	}
+	if t.ECH, err = protocol.ParseECHParam(query); err != nil {
+		return err
+	}
 
 	unescapedRemark, err := url.PathUnescape(uri.Fragment)
 	if err != nil {
@@ -170,6 +176,9 @@ func (t *Trojan) DetailsStr() string {
 			color.RedString("SNI"), copyV.SNI,
 			color.RedString("ALPN"), copyV.ALPN,
 			color.RedString("Fingerprint"), copyV.TlsFingerprint)
+		if t.ECH != "" {
+			info += fmt.Sprintf("%s: %s\n", color.RedString("ECH"), t.ECH)
+		}
 
 		if t.AllowInsecure != "" {
 			info += fmt.Sprintf("%s: %v\n",
@@ -178,6 +187,9 @@ func (t *Trojan) DetailsStr() string {
 	} else {
 		info += fmt.Sprintf("%s: none\n", color.RedString("TLS"))
 	}
+	if t.Mux != nil {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("Mux"), t.Mux)
+	}
 	return info
 }
 
@@ -201,6 +213,7 @@ func (t *Trojan) ConvertToGeneralConfig() (g protocol.GeneralConfig) {
 		g.TLS = t.Security
 	}
 	g.TlsFingerprint = t.TlsFingerprint
+	g.ECH = t.ECH
 	g.ServiceName = t.ServiceName
 	g.Mode = t.Mode
 	g.Type = t.Type
@@ -213,27 +226,6 @@ func (t *Trojan) CraftInboundOptions() *option.Inbound {
 	port, _ := strconv.Atoi(t.Port)
 	addr, _ := netip.ParseAddr(t.Address)
 
-	// TODO: Inbound TLS requires certificates, which are not available from a client link.
-	// Therefore, TLS is not configured for the inbound.
-
-	var transport = &option.V2RayTransportOptions{
-		Type: t.Type,
-	}
-
-	switch t.Type {
-	case "ws":
-		transport.WebsocketOptions = option.V2RayWebsocketOptions{
-			Path: t.Path,
-		}
-	case "grpc":
-		if len(t.ServiceName) > 0 && t.ServiceName[0] == '/' {
-			t.ServiceName = t.ServiceName[1:]
-		}
-		transport.GRPCOptions = option.V2RayGRPCOptions{
-			ServiceName: t.ServiceName,
-		}
-	}
-
 	tapAddr := badoption.Addr(addr)
 	opts := option.TrojanInboundOptions{
 		ListenOptions: option.ListenOptions{
@@ -246,13 +238,16 @@ func (t *Trojan) CraftInboundOptions() *option.Inbound {
 				Password: t.Password,
 			},
 		},
-		Transport: transport,
+		InboundTLSOptionsContainer: option.InboundTLSOptionsContainer{
+			TLS: inboundTLS(t.Security, t.CertFile, t.KeyFile, t.SNI, t.ALPN),
+		},
+		Transport: inboundTransport(t.Type, t.Path, t.Host, t.ServiceName),
 	}
 
 	return &option.Inbound{
 		Type:    t.Name(),
 		Tag:     "trojan-in",
-		Options: opts,
+		Options: &opts,
 	}
 }
 
@@ -289,8 +284,12 @@ func (t *Trojan) CraftOutboundOptions(allowInsecure bool) (*option.Outbound, err
 	}
 
 	switch t.Type {
-	case "tcp":
-		break
+	case "tcp", "raw":
+		if t.HeaderType == "http" {
+			return nil, errors.New("tcp http header obfuscation not supported")
+		}
+		// Plain TCP has no v2ray transport in sing-box
+		transport = nil
 	case "ws":
 		transport.WebsocketOptions = option.V2RayWebsocketOptions{
 			Path:                t.Path,
@@ -359,6 +358,14 @@ func (t *Trojan) CraftOutboundOptions(allowInsecure bool) (*option.Outbound, err
 				Insecure: insecure,
 			},
 		},
+		Multiplex: multiplexOptions(t.Mux),
+	}
+	if t.Security == "tls" {
+		ech, err := echOptions(t.ECH)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLS.ECH = ech
 	}
 	if t.Security == "reality" {
 		opts.TLS.Reality = &option.OutboundRealityOptions{
@@ -0,0 +1,216 @@
+package singbox
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+
+	"github.com/fatih/color"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	sing_tuic "github.com/sagernet/sing-box/protocol/tuic"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/service"
+)
+
+func NewTuic(link string) Protocol {
+	return &Tuic{OrigLink: link}
+}
+
+func (t *Tuic) Name() string {
+	return protocol.TuicIdentifier
+}
+
+func (t *Tuic) Parse() error {
+	uri, err := url.Parse(t.OrigLink)
+	if err != nil {
+		return fmt.Errorf("failed to parse TUIC link: %w", err)
+	}
+
+	if uri.Scheme != protocol.TuicIdentifier {
+		return fmt.Errorf("tuic unrecognized scheme: %s", uri.Scheme)
+	}
+
+	if uri.User == nil {
+		return fmt.Errorf("tuic link is missing uuid and password")
+	}
+	t.UUID = uri.User.Username()
+	t.Password, _ = uri.User.Password()
+	if t.UUID == "" {
+		return fmt.Errorf("tuic link is missing uuid")
+	}
+
+	t.Address, t.Port, err = net.SplitHostPort(uri.Host)
+	if err != nil {
+		return fmt.Errorf("failed to split host and port for TUIC link: %w", err)
+	}
+
+	query := uri.Query()
+
+	t.CongestionControl = query.Get("congestion_control")
+	if t.CongestionControl == "" {
+		// Some clients (e.g. NekoBox) export the older parameter name
+		t.CongestionControl = query.Get("congestion_controller")
+	}
+	t.UDPRelayMode = query.Get("udp_relay_mode")
+	t.ALPN = query.Get("alpn")
+	t.SNI = query.Get("sni")
+	t.DisableSNI = query.Get("disable_sni")
+	t.AllowInsecure = query.Get("allow_insecure")
+	if t.AllowInsecure == "" {
+		t.AllowInsecure = query.Get("insecure")
+	}
+
+	unescapedRemark, err := url.PathUnescape(uri.Fragment)
+	if err != nil {
+		t.Remark = uri.Fragment
+	} else {
+		t.Remark = unescapedRemark
+	}
+
+	// TUIC runs over QUIC, so TLS always needs a server name to verify against.
+	if t.SNI == "" {
+		t.SNI = t.Address
+	}
+
+	return nil
+}
+
+func (t *Tuic) DetailsStr() string {
+	info := fmt.Sprintf("%s: %s\n%s: %s\n%s: %s\n%s: %s\n%s: %s\n%s: %s\n%s: %s\n",
+		color.RedString("Protocol"), t.Name(),
+		color.RedString("Remark"), t.Remark,
+		color.RedString("Address"), t.Address,
+		color.RedString("Port"), t.Port,
+		color.RedString("UUID"), t.UUID,
+		color.RedString("Password"), t.Password,
+		color.RedString("SNI"), t.SNI)
+
+	if t.ALPN != "" {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("ALPN"), t.ALPN)
+	}
+	if t.CongestionControl != "" {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("Congestion Control"), t.CongestionControl)
+	}
+	if t.UDPRelayMode != "" {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("UDP Relay Mode"), t.UDPRelayMode)
+	}
+	if t.AllowInsecure != "" {
+		info += fmt.Sprintf("%s: %v\n", color.RedString("Insecure"), t.AllowInsecure)
+	}
+	return info
+}
+
+func (t *Tuic) GetLink() string {
+	if t.OrigLink != "" {
+		return t.OrigLink
+	}
+
+	baseURL := url.URL{
+		Scheme: protocol.TuicIdentifier,
+		User:   url.UserPassword(t.UUID, t.Password),
+		Host:   net.JoinHostPort(t.Address, t.Port),
+	}
+
+	params := url.Values{}
+	addQueryParam := func(key, value string) {
+		if value != "" {
+			params.Add(key, value)
+		}
+	}
+	addQueryParam("congestion_control", t.CongestionControl)
+	addQueryParam("udp_relay_mode", t.UDPRelayMode)
+	addQueryParam("alpn", t.ALPN)
+	addQueryParam("sni", t.SNI)
+	addQueryParam("disable_sni", t.DisableSNI)
+	addQueryParam("allow_insecure", t.AllowInsecure)
+	baseURL.RawQuery = params.Encode()
+	baseURL.Fragment = t.Remark
+
+	return baseURL.String()
+}
+
+func (t *Tuic) ConvertToGeneralConfig() (g protocol.GeneralConfig) {
+	g.Protocol = t.Name()
+	g.Address = t.Address
+	g.Port = t.Port
+	g.ID = t.UUID
+	g.Remark = t.Remark
+	g.SNI = t.SNI
+	g.ALPN = t.ALPN
+	g.TLS = "tls"
+	g.Network = "udp"
+
+	g.OrigLink = t.GetLink()
+
+	return g
+}
+
+func (t *Tuic) CraftOutboundOptions(allowInsecure bool) (*option.Outbound, error) {
+	port, err := strconv.Atoi(t.Port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TUIC port %q: %w", t.Port, err)
+	}
+
+	insecure := allowInsecure
+	if t.AllowInsecure == "1" || t.AllowInsecure == "true" {
+		insecure = true
+	}
+
+	alpn := []string{"h3"}
+	if t.ALPN != "" && t.ALPN != "none" {
+		alpn = strings.Split(t.ALPN, ",")
+	}
+
+	opts := option.TUICOutboundOptions{
+		DialerOptions: option.DialerOptions{},
+		ServerOptions: option.ServerOptions{
+			Server:     t.Address,
+			ServerPort: uint16(port),
+		},
+		UUID:              t.UUID,
+		Password:          t.Password,
+		CongestionControl: t.CongestionControl,
+		UDPRelayMode:      t.UDPRelayMode,
+		OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{
+			TLS: &option.OutboundTLSOptions{
+				Enabled:    true,
+				ServerName: t.SNI,
+				DisableSNI: t.DisableSNI == "1" || t.DisableSNI == "true",
+				Insecure:   insecure,
+				ALPN:       alpn,
+			},
+		},
+	}
+
+	return &option.Outbound{
+		Type:    t.Name(),
+		Options: &opts,
+	}, nil
+}
+
+func (t *Tuic) CraftInboundOptions() *option.Inbound {
+	return &option.Inbound{
+		Type: t.Name(),
+	}
+}
+
+func (t *Tuic) CraftOutbound(ctx context.Context, l logger.ContextLogger, allowInsecure bool) (adapter.Outbound, error) {
+	options, err := t.CraftOutboundOptions(allowInsecure)
+	if err != nil {
+		return nil, err
+	}
+
+	tuicOptions, _ := options.Options.(*option.TUICOutboundOptions)
+	out, err := sing_tuic.NewOutbound(ctx, service.FromContext[adapter.Router](ctx), l, "out_tuic", *tuicOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating tuic outbound: %w", err)
+	}
+
+	return out, nil
+}
@@ -80,6 +80,13 @@ func (v *Vless) Parse() error {
 		}
 	}
 
+	if v.Mux, err = protocol.ParseMuxParams(uri.Query()); err != nil {
+		return err
+	}
+	if v.ECH, err = protocol.ParseECHParam(uri.Query()); err != nil {
+		return err
+	}
+
 	v.Remark, err = url.PathUnescape(uri.Fragment)
 	if err != nil {
 		v.Remark = uri.Fragment
@@ -159,6 +166,9 @@ func (v *Vless) DetailsStr() string {
 			color.RedString("SNI"), copyV.SNI,
 			color.RedString("ALPN"), copyV.ALPN,
 			color.RedString("Fingerprint"), copyV.TlsFingerprint)
+		if v.ECH != "" {
+			info += fmt.Sprintf("%s: %s\n", color.RedString("ECH"), v.ECH)
+		}
 
 		if v.AllowInsecure != "" {
 			info += fmt.Sprintf("%s: %v\n",
@@ -167,6 +177,9 @@ func (v *Vless) DetailsStr() string {
 	} else {
 		info += fmt.Sprintf("%s: none\n", color.RedString("TLS"))
 	}
+	if v.Mux != nil {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("Mux"), v.Mux)
+	}
 	return info
 }
 
@@ -190,6 +203,7 @@ func (v *Vless) ConvertToGeneralConfig() (g protocol.GeneralConfig) {
 	g.SNI = v.SNI
 	g.ALPN = v.ALPN
 	g.TlsFingerprint = v.TlsFingerprint
+	g.ECH = v.ECH
 	g.ServiceName = v.ServiceName
 	g.Mode = v.Mode
 	g.Type = v.Type
@@ -202,27 +216,6 @@ func (v *Vless) CraftInboundOptions() *option.Inbound {
 	port, _ := strconv.Atoi(v.Port)
 	addr, _ := netip.ParseAddr(v.Address)
 
-	// TODO: Inbound TLS requires certificates, which are not available from a client link.
-	// Therefore, TLS is not configured for the inbound.
-
-	var transport = &option.V2RayTransportOptions{
-		Type: v.Type,
-	}
-
-	switch v.Type {
-	case "ws":
-		transport.WebsocketOptions = option.V2RayWebsocketOptions{
-			Path: v.Path,
-		}
-	case "grpc":
-		if len(v.ServiceName) > 0 && v.ServiceName[0] == '/' {
-			v.ServiceName = v.ServiceName[1:]
-		}
-		transport.GRPCOptions = option.V2RayGRPCOptions{
-			ServiceName: v.ServiceName,
-		}
-	}
-
 	tapAddr := badoption.Addr(addr)
 	opts := option.VLESSInboundOptions{
 		ListenOptions: option.ListenOptions{
@@ -236,13 +229,16 @@ func (v *Vless) CraftInboundOptions() *option.Inbound {
 				Flow: v.Flow,
 			},
 		},
-		Transport: transport,
+		InboundTLSOptionsContainer: option.InboundTLSOptionsContainer{
+			TLS: inboundTLS(v.Security, v.CertFile, v.KeyFile, v.SNI, v.ALPN),
+		},
+		Transport: inboundTransport(v.Type, v.Path, v.Host, v.ServiceName),
 	}
 
 	return &option.Inbound{
 		Type:    v.Name(),
 		Tag:     "vless-in",
-		Options: opts,
+		Options: &opts,
 	}
 }
 
@@ -279,8 +275,12 @@ func (v *Vless) CraftOutboundOptions(allowInsecure bool) (*option.Outbound, erro
 	}
 
 	switch v.Type {
-	case "tcp":
-		return nil, errors.New("tcp transport not supported")
+	case "tcp", "raw":
+		if v.HeaderType == "http" {
+			return nil, errors.New("tcp http header obfuscation not supported")
+		}
+		// Plain TCP has no v2ray transport in sing-box
+		transport = nil
 	case "ws":
 		transport.WebsocketOptions = option.V2RayWebsocketOptions{
 			Path:                v.Path,
@@ -348,7 +348,15 @@ func (v *Vless) CraftOutboundOptions(allowInsecure bool) (*option.Outbound, erro
 				Insecure: insecure,
 			},
 		},
-		Flow: v.Flow,
+		Flow:      v.Flow,
+		Multiplex: multiplexOptions(v.Mux),
+	}
+	if v.Security == "tls" {
+		ech, err := echOptions(v.ECH)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLS.ECH = ech
 	}
 	if v.Security == "reality" {
 		opts.TLS.Reality = &option.OutboundRealityOptions{
@@ -1,6 +1,10 @@
 package singbox
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+)
 
 func TestNewVless(t *testing.T) {
 	link := "vless://0090bbba-1118-46ca-87a1-52599cee74ab@laser.kafsabtaheri.com:8085?encryption=none&security=none&sni=laser.kafsabtaheri.com&alpn=h3%2Ch2%2Chttp%2F1.1&fp=chrome&type=ws&host=laser.kafsabtaheri.com&path=%2Firan-mci-irancell-ir#vlessWS"
@@ -14,3 +18,25 @@ func TestNewVless(t *testing.T) {
 
 	t.Logf("%s\n", vless.DetailsStr())
 }
+
+func TestVless_PlainTCP(t *testing.T) {
+	vless := NewVless("vless://0090bbba-1118-46ca-87a1-52599cee74ab@127.0.0.1:443?encryption=none&security=tls&sni=localhost&type=tcp#tcp").(*Vless)
+	if err := vless.Parse(); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	out, err := vless.CraftOutboundOptions(false)
+	if err != nil {
+		t.Fatalf("CraftOutboundOptions() error = %v", err)
+	}
+	if tr := out.Options.(*option.VLESSOutboundOptions).Transport; tr != nil {
+		t.Errorf("plain TCP got transport %+v", tr)
+	}
+
+	vless = NewVless("vless://0090bbba-1118-46ca-87a1-52599cee74ab@127.0.0.1:80?type=tcp&headerType=http#http").(*Vless)
+	if err := vless.Parse(); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if _, err := vless.CraftOutboundOptions(false); err == nil {
+		t.Error("expected an error for the tcp http header")
+	}
+}
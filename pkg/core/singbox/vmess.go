@@ -143,6 +143,11 @@ func (v *Vmess) Parse() error {
 			v.Path = "/"
 		}
 	}
+	if v.ECH != "" {
+		if err = protocol.ValidateECH(v.ECH); err != nil {
+			return err
+		}
+	}
 
 	return err
 }
@@ -202,6 +207,9 @@ func (v *Vmess) DetailsStr() string {
 			color.RedString("SNI"), copyV.SNI,
 			color.RedString("ALPN"), copyV.ALPN,
 			color.RedString("Fingerprint"), copyV.TlsFingerprint)
+		if v.ECH != "" {
+			info += fmt.Sprintf("%s: %s\n", color.RedString("ECH"), v.ECH)
+		}
 	}
 	return info
 }
@@ -229,6 +237,7 @@ func (v *Vmess) ConvertToGeneralConfig() (g protocol.GeneralConfig) {
 	g.SNI = v.SNI
 	g.ALPN = v.ALPN
 	g.TlsFingerprint = v.TlsFingerprint
+	g.ECH = v.ECH
 	g.Type = v.Type
 	g.OrigLink = v.GetLink()
 
@@ -263,24 +272,6 @@ func (v *Vmess) CraftInboundOptions() *option.Inbound {
 		}
 	}
 
-	var transport = &option.V2RayTransportOptions{
-		Type: v.Network,
-	}
-
-	switch v.Network {
-	case "ws":
-		transport.WebsocketOptions = option.V2RayWebsocketOptions{
-			Path: v.Path,
-		}
-	case "grpc":
-		if len(v.Path) > 0 && v.Path[0] == '/' {
-			v.Path = v.Path[1:]
-		}
-		transport.GRPCOptions = option.V2RayGRPCOptions{
-			ServiceName: v.Path,
-		}
-	}
-
 	tapAddr := badoption.Addr(addr)
 	opts := option.VMessInboundOptions{
 		ListenOptions: option.ListenOptions{
@@ -294,13 +285,16 @@ func (v *Vmess) CraftInboundOptions() *option.Inbound {
 				AlterId: aid,
 			},
 		},
-		Transport: transport,
+		InboundTLSOptionsContainer: option.InboundTLSOptionsContainer{
+			TLS: inboundTLS(v.TLS, v.CertFile, v.KeyFile, v.SNI, v.ALPN),
+		},
+		Transport: inboundTransport(v.Network, v.Path, v.Host, v.Path),
 	}
 
 	return &option.Inbound{
 		Type:    v.Name(),
 		Tag:     "vmess-in",
-		Options: opts,
+		Options: &opts,
 	}
 }
 
@@ -366,8 +360,12 @@ func (v *Vmess) CraftOutboundOptions(allowInsecure bool) (*option.Outbound, erro
 	}
 
 	switch v.Network {
-	case "tcp":
-		break
+	case "tcp", "raw":
+		if v.Type == "http" {
+			return nil, errors.New("tcp http header obfuscation not supported")
+		}
+		// Plain TCP has no v2ray transport in sing-box
+		transport = nil
 	case "ws":
 		transport.WebsocketOptions = option.V2RayWebsocketOptions{
 			Path:    v.Path,
@@ -437,6 +435,13 @@ func (v *Vmess) CraftOutboundOptions(allowInsecure bool) (*option.Outbound, erro
 			},
 		},
 	}
+	if tls {
+		ech, err := echOptions(v.ECH)
+		if err != nil {
+			return nil, err
+		}
+		opts.TLS.ECH = ech
+	}
 
 	return &option.Outbound{
 		Type:    v.Name(),
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+	"github.com/lilendian0x00/xray-knife/v10/utils/customlog"
 
 	"github.com/fatih/color"
 	"github.com/sagernet/sing-box/adapter"
@@ -79,6 +80,18 @@ func (w *Wireguard) Parse() error {
 		}
 	}
 
+	if _, err := protocol.ParseWireguardReserved(w.Reserved); err != nil {
+		return err
+	}
+
+	for _, raw := range uri.Query()[protocol.WireguardPeerParam] {
+		peer, err := protocol.ParseWireguardPeer(raw)
+		if err != nil {
+			return err
+		}
+		w.Peers = append(w.Peers, peer)
+	}
+
 	w.Remark, err = url.PathUnescape(uri.Fragment)
 	if err != nil {
 		w.Remark = uri.Fragment
@@ -98,16 +111,72 @@ func (w *Wireguard) DetailsStr() string {
 		color.RedString("Secret Key"), w.SecretKey,
 	)
 
+	if w.Reserved != "" {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("Reserved"), w.Reserved)
+	}
+	if w.AllowedIPs != "" {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("Allowed IPs"), w.AllowedIPs)
+	}
+	if w.KeepAlive != 0 {
+		info += fmt.Sprintf("%s: %ds\n", color.RedString("Keepalive"), w.KeepAlive)
+	}
+	if w.Workers != 0 {
+		info += fmt.Sprintf("%s: %d\n", color.RedString("Workers"), w.Workers)
+	}
+	for i, peer := range w.Peers {
+		info += fmt.Sprintf("%s: %s (%s)\n", color.RedString("Peer %d", i+2), peer.Endpoint, peer.PublicKey)
+	}
+
 	return info
 }
 
+// GetLink returns the original link, or builds one carrying every field
+// (extra peers included) when the config wasn't parsed from a link.
 func (w *Wireguard) GetLink() string {
-	return w.OrigLink
+	if w.OrigLink != "" {
+		return w.OrigLink
+	}
+
+	params := url.Values{}
+	addQueryParam := func(key, value string) {
+		if value != "" {
+			params.Add(key, value)
+		}
+	}
+	addQueryParamInt := func(key string, value int32) {
+		if value != 0 {
+			params.Add(key, strconv.FormatInt(int64(value), 10))
+		}
+	}
+
+	addQueryParam("publickey", w.PublicKey)
+	addQueryParam("presharedkey", w.PreSharedKey)
+	addQueryParam("address", w.LocalAddress)
+	addQueryParam("allowedips", w.AllowedIPs)
+	addQueryParam("reserved", w.Reserved)
+	addQueryParamInt("keepalive", w.KeepAlive)
+	addQueryParamInt("workers", w.Workers)
+	addQueryParamInt("mtu", w.Mtu)
+	for _, peer := range w.Peers {
+		params.Add(protocol.WireguardPeerParam, peer.String())
+	}
+
+	u := url.URL{
+		Scheme:   protocol.WireguardIdentifier,
+		User:     url.User(w.SecretKey),
+		Host:     w.Endpoint,
+		RawQuery: params.Encode(),
+		Fragment: w.Remark,
+	}
+	return u.String()
 }
 
 func (w *Wireguard) ConvertToGeneralConfig() (g protocol.GeneralConfig) {
 	g.Protocol = w.Name()
 	g.Address = w.Endpoint
+	g.ID = w.SecretKey
+	g.Remark = w.Remark
+	g.OrigLink = w.GetLink()
 
 	return g
 }
@@ -131,14 +200,9 @@ func (w *Wireguard) CraftOutboundOptions(allowInsecure bool) (*option.Outbound,
 
 	var reserved = []uint8{0, 0, 0}
 	if w.Reserved != "" {
-		reservedList := strings.Split(w.Reserved, ",")
-		for i, v := range reservedList {
-			num, err := strconv.ParseUint(v, 10, 8)
-			if err != nil {
-				fmt.Println(err)
-				return nil, errors.New("invalid reserved value")
-			}
-			reserved[i] = uint8(num)
+		reserved, err = protocol.ParseWireguardReserved(w.Reserved)
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -163,9 +227,39 @@ func (w *Wireguard) CraftOutboundOptions(allowInsecure bool) (*option.Outbound,
 		//},
 		PeerPublicKey: w.PublicKey,
 		PrivateKey:    w.SecretKey,
-		//PreSharedKey: w.SecretKey,
-		Reserved: reserved,
-		MTU:      uint32(w.Mtu),
+		PreSharedKey:  w.PreSharedKey,
+		Reserved:      reserved,
+		Workers:       int(w.Workers),
+		MTU:           uint32(w.Mtu),
+	}
+
+	// The legacy outbound has no keepalive setting, and the endpoint that has
+	// one can't stand in for an outbound in the instances made here.
+	keepalive := w.KeepAlive != 0
+	for _, peer := range w.Peers {
+		keepalive = keepalive || peer.KeepAlive != 0
+	}
+	if keepalive {
+		customlog.Printf(customlog.Warning, "sing-box wireguard ignores keepalive (%s)\n", w.Endpoint)
+	}
+
+	// The inline peer always routes everything; custom allowed IPs and extra
+	// peers need the peers list.
+	if w.AllowedIPs != "" || len(w.Peers) > 0 {
+		first := protocol.WireguardPeer{
+			PublicKey:    w.PublicKey,
+			PreSharedKey: w.PreSharedKey,
+			Endpoint:     w.Endpoint,
+			AllowedIPs:   w.AllowedIPs,
+			Reserved:     w.Reserved,
+		}
+		for _, peer := range append([]protocol.WireguardPeer{first}, w.Peers...) {
+			p, err := legacyWireguardPeer(peer)
+			if err != nil {
+				return nil, err
+			}
+			opts.Peers = append(opts.Peers, p)
+		}
 	}
 
 	localAddresses := strings.Split(w.LocalAddress, ",")
@@ -187,6 +281,43 @@ func (w *Wireguard) CraftOutboundOptions(allowInsecure bool) (*option.Outbound,
 	}, nil
 }
 
+func legacyWireguardPeer(peer protocol.WireguardPeer) (option.LegacyWireGuardPeer, error) {
+	host, portS, err := net.SplitHostPort(peer.Endpoint)
+	if err != nil {
+		return option.LegacyWireGuardPeer{}, fmt.Errorf("invalid peer endpoint %q: %w", peer.Endpoint, err)
+	}
+	port, err := strconv.ParseUint(portS, 10, 16)
+	if err != nil {
+		return option.LegacyWireGuardPeer{}, errors.New("invalid port number")
+	}
+	reserved, err := protocol.ParseWireguardReserved(peer.Reserved)
+	if err != nil {
+		return option.LegacyWireGuardPeer{}, err
+	}
+
+	p := option.LegacyWireGuardPeer{
+		ServerOptions: option.ServerOptions{
+			Server:     host,
+			ServerPort: uint16(port),
+		},
+		PublicKey:    peer.PublicKey,
+		PreSharedKey: peer.PreSharedKey,
+		Reserved:     reserved,
+	}
+	allowedIPs := peer.AllowedIPs
+	if allowedIPs == "" {
+		allowedIPs = "0.0.0.0/0,::/0"
+	}
+	for _, v := range strings.Split(allowedIPs, ",") {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(v))
+		if err != nil {
+			return option.LegacyWireGuardPeer{}, err
+		}
+		p.AllowedIPs = append(p.AllowedIPs, prefix)
+	}
+	return p, nil
+}
+
 func (w *Wireguard) CraftOutbound(ctx context.Context, l logger.ContextLogger, allowInsecure bool) (adapter.Outbound, error) {
 
 	options, err := w.CraftOutboundOptions(allowInsecure)
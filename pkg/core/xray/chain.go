@@ -49,13 +49,18 @@ func (c *Core) MakeChainedInstance(ctx context.Context, hops []protocol.Protocol
 		}
 
 		ob.Tag = fmt.Sprintf("chain-%d", i)
+		c.applyMux(out, ob)
 
 		// For all hops except the last, set ProxySettings to route through
-		// the next hop in the chain.
+		// the next hop in the chain. The last hop is the one reaching the
+		// network, so that's where fragmentation applies.
+		var fragment *core.OutboundHandlerConfig
 		if i < len(hops)-1 {
 			ob.ProxySettings = &conf.ProxyConfig{
 				Tag: fmt.Sprintf("chain-%d", i+1),
 			}
+		} else if fragment, err = c.applyFragment(ob); err != nil {
+			return nil, fmt.Errorf("chain hop %d: %w", i, err)
 		}
 
 		built, err := ob.Build()
@@ -63,6 +68,9 @@ func (c *Core) MakeChainedInstance(ctx context.Context, hops []protocol.Protocol
 			return nil, fmt.Errorf("chain hop %d: failed to build outbound handler: %w", i, err)
 		}
 		clientConfig.Outbound = append(clientConfig.Outbound, built)
+		if fragment != nil {
+			clientConfig.Outbound = append(clientConfig.Outbound, fragment)
+		}
 	}
 
 	// Add inbound if configured.
@@ -0,0 +1,69 @@
+package xray
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf"
+)
+
+// fragmentOutboundTag tags the freedom outbound that does the fragmenting.
+const fragmentOutboundTag = "fragment"
+
+// applyFragment points ob's dialer at a freedom outbound carrying the
+// configured fragment/noises settings and returns that outbound, built and
+// ready to be appended after ob. It returns nil when fragmentation is off.
+func (c *Core) applyFragment(ob *conf.OutboundDetourConfig) (*core.OutboundHandlerConfig, error) {
+	if c.Fragment == nil {
+		return nil, nil
+	}
+
+	settings := map[string]any{}
+	if c.Fragment.Packets != "" {
+		fragment := map[string]any{"packets": c.Fragment.Packets}
+		if c.Fragment.Length != "" {
+			fragment["length"] = c.Fragment.Length
+		}
+		if c.Fragment.Interval != "" {
+			fragment["interval"] = c.Fragment.Interval
+		}
+		settings["fragment"] = fragment
+	}
+	if len(c.Fragment.Noises) > 0 {
+		noises := make([]map[string]any, 0, len(c.Fragment.Noises))
+		for _, n := range c.Fragment.Noises {
+			noise := map[string]any{"type": n.Type, "packet": n.Packet}
+			if n.Delay != "" {
+				noise["delay"] = n.Delay
+			}
+			noises = append(noises, noise)
+		}
+		settings["noises"] = noises
+	}
+
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		return nil, err
+	}
+	rawMsg := json.RawMessage(raw)
+	freedom := &conf.OutboundDetourConfig{
+		Protocol: "freedom",
+		Tag:      fragmentOutboundTag,
+		Settings: &rawMsg,
+	}
+	built, err := freedom.Build()
+	if err != nil {
+		return nil, fmt.Errorf("invalid fragment settings: %w", err)
+	}
+
+	if ob.StreamSetting == nil {
+		ob.StreamSetting = &conf.StreamConfig{}
+	}
+	if ob.StreamSetting.SocketSettings == nil {
+		ob.StreamSetting.SocketSettings = &conf.SocketConfig{}
+	}
+	ob.StreamSetting.SocketSettings.DialerProxy = fragmentOutboundTag
+
+	return built, nil
+}
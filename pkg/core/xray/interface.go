@@ -31,6 +31,8 @@ type Vmess struct {
 	SNI            string      `json:"sni"`  // Server name indication
 	ALPN           string      `json:"alpn"` // Application-Layer Protocol Negotiation
 	TlsFingerprint string      `json:"fp"`   // TLS fingerprint
+	ECH            string      `json:"ech"`  // ECHConfigList (base64) or a DNS server to fetch it from
+	PinnedCert     string      `json:"pcs"`  // SHA-256 of the server certificate
 	Type           string      `json:"type"` // XHTTP - Used for HTTP Obfuscation
 
 	//// It's also possible for Vmess to have REALITY...
@@ -72,6 +74,10 @@ type Vless struct {
 	CertFile       string `json:"-"`
 	KeyFile        string `json:"-"`
 	OrigLink       string `json:"-"` // Original link
+
+	ECH        string               `json:"ech"` // ECHConfigList (base64) or a DNS server to fetch it from
+	PinnedCert string               `json:"pcs"` // SHA-256 of the server certificate
+	Mux        *protocol.MuxOptions `json:"-"`   // Multiplexing, from the mux* parameters
 }
 
 type Shadowsocks struct {
@@ -79,6 +85,9 @@ type Shadowsocks struct {
 	Port       string
 	Encryption string
 	Password   string
+	Plugin     string // SIP003 plugin name (obfs-local, v2ray-plugin, ...)
+	PluginOpts string // SIP003 plugin options, e.g. "obfs=http;obfs-host=example.com"
+	Mux        *protocol.MuxOptions
 	Remark     string
 	OrigLink   string // Original link
 }
@@ -104,12 +113,18 @@ type Trojan struct {
 	Authority      string `json:"authority"`   // GRPC
 	ServiceName    string `json:"serviceName"` // GRPC
 	Mode           string `json:"mode"`        // XHTTP, GRPC
+	CertFile       string `json:"-"`
+	KeyFile        string `json:"-"`
 
 	// Yes, Trojan can have reality too xD
 	PublicKey string `json:"pbk"`
 	ShortIds  string `json:"sid"` // Mandatory, the shortId list available to the client, which can be used to distinguish different clients
 	SpiderX   string `json:"spx"` // Reality path
 
+	ECH        string               `json:"ech"` // ECHConfigList (base64) or a DNS server to fetch it from
+	PinnedCert string               `json:"pcs"` // SHA-256 of the server certificate
+	Mux        *protocol.MuxOptions `json:"-"`   // Multiplexing, from the mux* parameters
+
 	OrigLink string `json:"-"` // Original link
 }
 
@@ -119,9 +134,15 @@ type Wireguard struct {
 	SecretKey    string `json:"secretkey"`
 	PreSharedKey string `json:"presharedkey"`
 	Endpoint     string
-	LocalAddress string `json:"address"` // Local address IPv4/IPv6 seperated by commas
+	LocalAddress string `json:"address"`    // Local address IPv4/IPv6 seperated by commas
+	AllowedIPs   string `json:"allowedips"` // CIDRs routed to the peer seperated by commas
+	Reserved     string `json:"reserved"`   // Three bytes seperated by commas (WARP)
+	KeepAlive    int32  `json:"keepalive"`
+	Workers      int32  `json:"workers"`
 	Mtu          int32  `json:"mtu"`
 
+	Peers []protocol.WireguardPeer `json:"-"` // Additional peers
+
 	OrigLink string `json:"-"` // Original link
 }
 
@@ -0,0 +1,66 @@
+package xray
+
+import (
+	"fmt"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+
+	"github.com/xtls/xray-core/transport/internet/tls"
+)
+
+// KnownFingerprint reports whether xray-core has a uTLS fingerprint called
+// name. The randomized ones have no fixed ClientHello, so GetFingerprint
+// returns nil for them.
+func KnownFingerprint(name string) bool {
+	switch name {
+	case "random", "randomized", "randomizednoalpn", "unsafe":
+		return true
+	}
+	return tls.GetFingerprint(name) != nil
+}
+
+func (v *Vless) LintFields() protocol.LintFields {
+	return protocol.LintFields{
+		Protocol:         v.Name(),
+		Address:          v.Address,
+		Port:             v.Port,
+		ID:               v.ID,
+		Security:         v.Security,
+		Transport:        v.Type,
+		Flow:             v.Flow,
+		PublicKey:        v.PublicKey,
+		ShortID:          v.ShortIds,
+		Fingerprint:      v.TlsFingerprint,
+		KnownFingerprint: KnownFingerprint,
+		MapsNonUUID:      true,
+	}
+}
+
+func (t *Trojan) LintFields() protocol.LintFields {
+	return protocol.LintFields{
+		Protocol:         t.Name(),
+		Address:          t.Address,
+		Port:             t.Port,
+		Security:         t.Security,
+		Transport:        t.Type,
+		Flow:             t.Flow,
+		PublicKey:        t.PublicKey,
+		ShortID:          t.ShortIds,
+		Fingerprint:      t.TlsFingerprint,
+		KnownFingerprint: KnownFingerprint,
+	}
+}
+
+func (v *Vmess) LintFields() protocol.LintFields {
+	return protocol.LintFields{
+		Protocol:         v.Name(),
+		Address:          v.Address,
+		Port:             fmt.Sprintf("%v", v.Port),
+		ID:               v.ID,
+		Security:         v.TLS,
+		Transport:        v.Network,
+		Fingerprint:      v.TlsFingerprint,
+		KnownFingerprint: KnownFingerprint,
+		MapsNonUUID:      true,
+	}
+}
@@ -0,0 +1,47 @@
+package xray
+
+import (
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+
+	"github.com/xtls/xray-core/infra/conf"
+)
+
+// muxConfig converts m into xray's outbound mux settings; nil stays nil.
+// XTLS flows (Vision) can't run over Mux.Cool, so with a flow only UDP is
+// multiplexed (over XUDP).
+func muxConfig(m *protocol.MuxOptions, flow string) *conf.MuxConfig {
+	if m == nil {
+		return nil
+	}
+	if !m.Enabled {
+		return &conf.MuxConfig{Enabled: false}
+	}
+	mux := &conf.MuxConfig{
+		Enabled:         true,
+		Concurrency:     int16(m.Concurrency),
+		XudpConcurrency: int16(m.XudpConcurrency),
+	}
+	if flow != "" {
+		mux.Concurrency = -1
+	}
+	return mux
+}
+
+// applyMux replaces the link's mux settings on ob with the core-wide
+// override, if any. Protocols without mux support are left alone.
+func (c *Core) applyMux(out Protocol, ob *conf.OutboundDetourConfig) {
+	if c.Mux == nil {
+		return
+	}
+	flow := ""
+	switch o := out.(type) {
+	case *Vless:
+		flow = o.Flow
+	case *Trojan:
+		flow = o.Flow
+	case *Vmess, *Shadowsocks:
+	default:
+		return
+	}
+	ob.MuxSettings = muxConfig(c.Mux, flow)
+}
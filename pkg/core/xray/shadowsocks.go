@@ -74,6 +74,11 @@ func (s *Shadowsocks) Parse() error {
 		s.Address = "[" + s.Address + "]"
 	}
 
+	s.Plugin, s.PluginOpts = protocol.SplitSIP003Plugin(uri.Query().Get("plugin"))
+	if s.Mux, err = protocol.ParseMuxParams(uri.Query()); err != nil {
+		return err
+	}
+
 	s.Remark, err = url.PathUnescape(uri.Fragment)
 	if err != nil {
 		s.Remark = uri.Fragment
@@ -102,6 +107,16 @@ func (s *Shadowsocks) DetailsStr() string {
 		color.RedString("Port"), s.Port,
 		color.RedString("Encryption"), s.Encryption,
 		color.RedString("Password"), s.Password)
+
+	if s.Plugin != "" {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("Plugin"), s.Plugin)
+		if s.PluginOpts != "" {
+			info += fmt.Sprintf("%s: %s\n", color.RedString("Plugin Options"), s.PluginOpts)
+		}
+	}
+	if s.Mux != nil {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("Mux"), s.Mux)
+	}
 	return info
 }
 
@@ -116,6 +131,14 @@ func (s *Shadowsocks) GetLink() string {
 		// We construct the final URL string manually as url.URL doesn't handle this specific format directly
 		hostPart := net.JoinHostPort(s.Address, s.Port)
 		link := fmt.Sprintf("ss://%s@%s", encodedCreds, hostPart)
+		query := url.Values{}
+		if s.Plugin != "" {
+			query.Set("plugin", protocol.JoinSIP003Plugin(s.Plugin, s.PluginOpts))
+		}
+		s.Mux.AddParams(query)
+		if len(query) > 0 {
+			link += "/?" + query.Encode()
+		}
 		if s.Remark != "" {
 			link += "#" + url.PathEscape(s.Remark)
 		}
@@ -127,8 +150,11 @@ func (s *Shadowsocks) ConvertToGeneralConfig() (g protocol.GeneralConfig) {
 	g.Protocol = s.Name()
 	g.Address = s.Address
 	g.ID = s.Password
+	g.Security = s.Encryption
 	g.Port = s.Port
 	g.Remark = s.Remark
+	g.Plugin = s.Plugin
+	g.PluginOpts = s.PluginOpts
 	g.OrigLink = s.GetLink()
 
 	return g
@@ -140,6 +166,13 @@ func (s *Shadowsocks) BuildOutboundDetourConfig(allowInsecure bool) (*conf.Outbo
 	out.Protocol = s.Name()
 
 	streamConf := &conf.StreamConfig{}
+	if s.Plugin != "" {
+		var err error
+		streamConf, err = s.pluginStreamConfig(allowInsecure)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	out.StreamSetting = streamConf
 	oset := json.RawMessage([]byte(fmt.Sprintf(`{
@@ -154,6 +187,7 @@ func (s *Shadowsocks) BuildOutboundDetourConfig(allowInsecure bool) (*conf.Outbo
   ]
 }`, s.Address, s.Port, s.Password, s.Encryption)))
 	out.Settings = &oset
+	out.MuxSettings = muxConfig(s.Mux, "")
 	return out, nil
 }
 
@@ -188,3 +222,47 @@ func (s *Shadowsocks) BuildInboundDetourConfig() (*conf.InboundDetourConfig, err
 	}
 	return in, nil
 }
+
+// pluginStreamConfig maps a SIP003 plugin onto the equivalent xray transport.
+// Only v2ray-plugin in websocket mode without mux has one; the plugin's mux
+// wraps the already-encrypted stream, which xray's outbound mux cannot reproduce.
+func (s *Shadowsocks) pluginStreamConfig(allowInsecure bool) (*conf.StreamConfig, error) {
+	if s.Plugin != protocol.PluginV2rayPlugin {
+		return nil, fmt.Errorf("xray core cannot carry shadowsocks plugin %q, use the sing-box core instead", s.Plugin)
+	}
+
+	opts := protocol.ParseSIP003Options(s.PluginOpts)
+	if mode, ok := opts["mode"]; ok && mode != "websocket" {
+		return nil, fmt.Errorf("xray core cannot carry v2ray-plugin mode %q, use the sing-box core instead", mode)
+	}
+	if mux, ok := opts["mux"]; !ok || mux != "0" {
+		return nil, errors.New("xray core cannot carry v2ray-plugin with mux enabled (set mux=0 or use the sing-box core)")
+	}
+
+	host := "cloudfront.com" // v2ray-plugin's default
+	if h, ok := opts["host"]; ok && h != "" {
+		host = h
+	}
+	path := "/"
+	if p, ok := opts["path"]; ok && p != "" {
+		path = p
+	}
+
+	network := conf.TransportProtocol("ws")
+	streamConf := &conf.StreamConfig{
+		Network: &network,
+		WSSettings: &conf.WebSocketConfig{
+			Host: host,
+			Path: path,
+		},
+	}
+	if _, ok := opts["tls"]; ok {
+		streamConf.Security = "tls"
+		streamConf.TLSSettings = &conf.TLSConfig{
+			ServerName:    host,
+			AllowInsecure: allowInsecure,
+		}
+	}
+
+	return streamConf, nil
+}
@@ -81,3 +81,44 @@ func TestShadowsocks_GetLink(t *testing.T) {
 		})
 	}
 }
+
+func TestShadowsocks_Plugin(t *testing.T) {
+	link := "ss://YWVzLTI1Ni1nY206RXhhbXBsZUAxMjM0@example.com:443/?plugin=v2ray-plugin%3Btls%3Bhost%3Dcdn.example.com%3Bpath%3D%2Fws%3Bmux%3D0#ws"
+
+	ss := &Shadowsocks{OrigLink: link}
+	if err := ss.Parse(); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if ss.Plugin != "v2ray-plugin" || ss.PluginOpts != "tls;host=cdn.example.com;path=/ws;mux=0" {
+		t.Fatalf("plugin = %q / %q", ss.Plugin, ss.PluginOpts)
+	}
+
+	out, err := ss.BuildOutboundDetourConfig(false)
+	if err != nil {
+		t.Fatalf("BuildOutboundDetourConfig() error = %v", err)
+	}
+	if out.StreamSetting.WSSettings == nil || out.StreamSetting.WSSettings.Path != "/ws" {
+		t.Errorf("expected websocket transport on /ws, got %+v", out.StreamSetting.WSSettings)
+	}
+	if out.StreamSetting.Security != "tls" || out.StreamSetting.TLSSettings.ServerName != "cdn.example.com" {
+		t.Errorf("expected tls with SNI cdn.example.com, got %q %+v", out.StreamSetting.Security, out.StreamSetting.TLSSettings)
+	}
+
+	regenerated := *ss
+	regenerated.OrigLink = ""
+	reparsed := &Shadowsocks{OrigLink: regenerated.GetLink()}
+	if err := reparsed.Parse(); err != nil {
+		t.Fatalf("Parse() of regenerated link error = %v", err)
+	}
+	if reparsed.Plugin != ss.Plugin || reparsed.PluginOpts != ss.PluginOpts {
+		t.Errorf("plugin lost in GetLink(): %q", regenerated.GetLink())
+	}
+
+	obfs := &Shadowsocks{OrigLink: "ss://YWVzLTI1Ni1nY206RXhhbXBsZUAxMjM0@example.com:443/?plugin=obfs-local%3Bobfs%3Dhttp#obfs"}
+	if err := obfs.Parse(); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if _, err := obfs.BuildOutboundDetourConfig(false); err == nil {
+		t.Error("expected obfs-local to be rejected by the xray core")
+	}
+}
@@ -102,6 +102,9 @@ func (s *Socks) ConvertToGeneralConfig() (g protocol.GeneralConfig) {
 	g.Protocol = s.Name()
 	g.Address = s.Address
 	g.Port = fmt.Sprintf("%v", s.Port)
+	if s.Username != "" {
+		g.ID = s.Username + ":" + s.Password
+	}
 	g.Remark = s.Remark
 
 	g.OrigLink = s.GetLink()
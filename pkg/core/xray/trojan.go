@@ -76,6 +76,15 @@ func (t *Trojan) Parse() error {
 	t.QuicSecurity = query.Get("quicSecurity")
 	t.Key = query.Get("key")
 	t.Authority = query.Get("authority")
+	if t.Mux, err = protocol.ParseMuxParams(query); err != nil {
+		return err
+	}
+	if t.ECH, err = protocol.ParseECHParam(query); err != nil {
+		return err
+	}
+	if t.PinnedCert, err = protocol.ParsePinnedCertParam(query); err != nil {
+		return err
+	}
 
 	unescapedRemark, err := url.PathUnescape(uri.Fragment)
 	if err != nil {
@@ -163,6 +172,12 @@ func (t *Trojan) DetailsStr() string {
 			color.RedString("SNI"), copyV.SNI,
 			color.RedString("ALPN"), copyV.ALPN,
 			color.RedString("Fingerprint"), copyV.TlsFingerprint)
+		if t.ECH != "" {
+			info += fmt.Sprintf("%s: %s\n", color.RedString("ECH"), t.ECH)
+		}
+		if t.PinnedCert != "" {
+			info += fmt.Sprintf("%s: %s\n", color.RedString("Pinned SHA256"), t.PinnedCert)
+		}
 
 		if t.AllowInsecure != "" {
 			info += fmt.Sprintf("%s: %v\n",
@@ -171,6 +186,9 @@ func (t *Trojan) DetailsStr() string {
 	} else {
 		info += fmt.Sprintf("%s: none\n", color.RedString("TLS"))
 	}
+	if t.Mux != nil {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("Mux"), t.Mux)
+	}
 	return info
 }
 
@@ -196,6 +214,8 @@ func (t *Trojan) GetLink() string {
 		addQueryParam("sni", t.SNI)
 		addQueryParam("alpn", t.ALPN)
 		addQueryParam("fp", t.TlsFingerprint)
+		addQueryParam("ech", t.ECH)
+		addQueryParam(protocol.PinnedCertParam, t.PinnedCert)
 		addQueryParam("type", t.Type)
 		addQueryParam("host", t.Host)
 		addQueryParam("path", t.Path)
@@ -209,6 +229,7 @@ func (t *Trojan) GetLink() string {
 		addQueryParam("quicSecurity", t.QuicSecurity)
 		addQueryParam("key", t.Key)
 		addQueryParam("authority", t.Authority)
+		t.Mux.AddParams(params)
 
 		baseURL.RawQuery = params.Encode()
 
@@ -236,6 +257,7 @@ func (t *Trojan) ConvertToGeneralConfig() (g protocol.GeneralConfig) {
 		g.TLS = t.Security
 	}
 	g.TlsFingerprint = t.TlsFingerprint
+	g.ECH = t.ECH
 	g.ServiceName = t.ServiceName
 	g.Mode = t.Mode
 	g.Type = t.Type
@@ -354,6 +376,11 @@ func (t *Trojan) BuildOutboundDetourConfig(allowInsecure bool) (*conf.OutboundDe
 				insecure = true
 			}
 		}
+		// A pin checks the certificate itself, and xray refuses
+		// allowInsecure next to it
+		if t.PinnedCert != "" {
+			insecure = false
+		}
 
 		if t.TlsFingerprint == "" {
 			t.TlsFingerprint = "chrome"
@@ -371,6 +398,8 @@ func (t *Trojan) BuildOutboundDetourConfig(allowInsecure bool) (*conf.OutboundDe
 		if t.ALPN != "" {
 			s.TLSSettings.ALPN = &conf.StringList{t.ALPN}
 		}
+		s.TLSSettings.ECHConfigList = t.ECH
+		s.TLSSettings.PinnedPeerCertSha256 = t.PinnedCert
 	} else if t.Security == "reality" {
 		s.REALITYSettings = &conf.REALITYConfig{
 			Show:        false,
@@ -408,6 +437,7 @@ func (t *Trojan) BuildOutboundDetourConfig(allowInsecure bool) (*conf.OutboundDe
 	}
 	oset := json.RawMessage(settingsBytes)
 	out.Settings = &oset
+	out.MuxSettings = muxConfig(t.Mux, t.Flow)
 	return out, nil
 }
 
@@ -517,8 +547,22 @@ func (t *Trojan) BuildInboundDetourConfig() (*conf.InboundDetourConfig, error) {
 		}
 	}
 
-	// Inbound TLS/REALITY requires certs, which aren't in the link. Fallback to none.
-	if streamConfig.Security == "tls" || streamConfig.Security == "reality" {
+	if t.Security == "tls" && t.CertFile != "" && t.KeyFile != "" {
+		streamConfig.TLSSettings = &conf.TLSConfig{
+			ServerName: t.SNI,
+			Certs: []*conf.TLSCertConfig{
+				{
+					KeyFile:  t.KeyFile,
+					CertFile: t.CertFile,
+				},
+			},
+		}
+		if t.ALPN != "" {
+			alpns := conf.StringList(strings.Split(t.ALPN, ","))
+			streamConfig.TLSSettings.ALPN = &alpns
+		}
+	} else if streamConfig.Security == "tls" || streamConfig.Security == "reality" {
+		// Inbound TLS/REALITY requires certs, which aren't in the link. Fallback to none.
 		streamConfig.Security = "none"
 	}
 
@@ -27,6 +27,10 @@ func TestTrojan_GetLink(t *testing.T) {
 			name: "TCP HTTP Header Obfuscation",
 			link: "trojan://pass@1.2.3.4:80?security=none&type=tcp&headerType=http&host=some.cdn.com&path=%2F#TCP%2FHTTP",
 		},
+		{
+			name: "Pinned certificate",
+			link: "trojan://secret@1.2.3.4:443?security=tls&sni=self.example.com&type=tcp&pcs=ba884517a1297bf33ca845310cc634440aa41b4ad850711c4cdf3d5c4b3d9e50#Pinned",
+		},
 		{
 			name: "No query params or remark",
 			link: "trojan://password@test.com:1234",
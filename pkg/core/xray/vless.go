@@ -106,6 +106,15 @@ func (v *Vless) Parse() error {
 	v.QuicSecurity = query.Get("quicSecurity")   // QUIC security: "none", "aes-128-gcm", etc.
 	v.Key = query.Get("key")                     // QUIC key
 	v.Authority = query.Get("authority")         // GRPC authority
+	if v.Mux, err = protocol.ParseMuxParams(query); err != nil {
+		return err
+	}
+	if v.ECH, err = protocol.ParseECHParam(query); err != nil {
+		return err
+	}
+	if v.PinnedCert, err = protocol.ParsePinnedCertParam(query); err != nil {
+		return err
+	}
 
 	unescapedRemark, err := url.PathUnescape(uri.Fragment)
 	if err != nil {
@@ -194,6 +203,12 @@ func (v *Vless) DetailsStr() string {
 			color.RedString("SNI"), copyV.SNI,
 			color.RedString("ALPN"), copyV.ALPN,
 			color.RedString("Fingerprint"), copyV.TlsFingerprint)
+		if v.ECH != "" {
+			info += fmt.Sprintf("%s: %s\n", color.RedString("ECH"), v.ECH)
+		}
+		if v.PinnedCert != "" {
+			info += fmt.Sprintf("%s: %s\n", color.RedString("Pinned SHA256"), v.PinnedCert)
+		}
 
 		if v.AllowInsecure != "" {
 			info += fmt.Sprintf("%s: %v\n",
@@ -209,6 +224,9 @@ func (v *Vless) DetailsStr() string {
 		info += fmt.Sprintf("%s: none\n", color.RedString("Encryption"))
   }
 
+	if v.Mux != nil {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("Mux"), v.Mux)
+	}
 	return info
 }
 
@@ -235,6 +253,8 @@ func (v *Vless) GetLink() string {
 		addQueryParam("sni", v.SNI)
 		addQueryParam("alpn", v.ALPN)
 		addQueryParam("fp", v.TlsFingerprint)
+		addQueryParam("ech", v.ECH)
+		addQueryParam(protocol.PinnedCertParam, v.PinnedCert)
 		addQueryParam("type", v.Type)
 		addQueryParam("host", v.Host)
 		addQueryParam("path", v.Path)
@@ -249,6 +269,7 @@ func (v *Vless) GetLink() string {
 		addQueryParam("quicSecurity", v.QuicSecurity)
 		addQueryParam("key", v.Key)
 		addQueryParam("authority", v.Authority)
+		v.Mux.AddParams(params)
 
 		baseURL.RawQuery = params.Encode()
 
@@ -276,6 +297,7 @@ func (v *Vless) ConvertToGeneralConfig() (g protocol.GeneralConfig) {
 	g.SNI = v.SNI
 	g.ALPN = v.ALPN
 	g.TlsFingerprint = v.TlsFingerprint
+	g.ECH = v.ECH
 	g.Authority = v.Authority
 	g.ServiceName = v.ServiceName
 	g.Mode = v.Mode
@@ -407,6 +429,11 @@ func (v *Vless) BuildOutboundDetourConfig(allowInsecure bool) (*conf.OutboundDet
 		if v.AllowInsecure == "1" || v.AllowInsecure == "true" {
 			insecureFlag = true
 		}
+		// A pin checks the certificate itself, and xray refuses
+		// allowInsecure next to it
+		if v.PinnedCert != "" {
+			insecureFlag = false
+		}
 
 		fp := v.TlsFingerprint
 		if fp == "" {
@@ -425,6 +452,8 @@ func (v *Vless) BuildOutboundDetourConfig(allowInsecure bool) (*conf.OutboundDet
 			alpns := conf.StringList(strings.Split(v.ALPN, ","))
 			s.TLSSettings.ALPN = &alpns
 		}
+		s.TLSSettings.ECHConfigList = v.ECH
+		s.TLSSettings.PinnedPeerCertSha256 = v.PinnedCert
 	} else if v.Security == "reality" {
 		fp := v.TlsFingerprint
 		if fp == "" {
@@ -475,6 +504,7 @@ func (v *Vless) BuildOutboundDetourConfig(allowInsecure bool) (*conf.OutboundDet
 	}
 	oset := json.RawMessage(settingsBytes)
 	out.Settings = &oset
+	out.MuxSettings = muxConfig(v.Mux, v.Flow)
 	return out, nil
 }
 
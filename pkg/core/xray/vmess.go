@@ -158,6 +158,16 @@ func (v *Vmess) Parse() error {
 			v.Path = "/"
 		}
 	}
+	if v.ECH != "" {
+		if err = protocol.ValidateECH(v.ECH); err != nil {
+			return err
+		}
+	}
+	if v.PinnedCert != "" {
+		if err = protocol.ValidatePinnedCert(v.PinnedCert); err != nil {
+			return err
+		}
+	}
 
 	return err
 }
@@ -219,6 +229,12 @@ func (v *Vmess) DetailsStr() string {
 			color.RedString("SNI"), copyV.SNI,
 			color.RedString("ALPN"), copyV.ALPN,
 			color.RedString("Fingerprint"), copyV.TlsFingerprint)
+		if v.ECH != "" {
+			info += fmt.Sprintf("%s: %s\n", color.RedString("ECH"), v.ECH)
+		}
+		if v.PinnedCert != "" {
+			info += fmt.Sprintf("%s: %s\n", color.RedString("Pinned SHA256"), v.PinnedCert)
+		}
 
 		if v.AllowInsecure != "" {
 			info += fmt.Sprintf("%s: %v\n",
@@ -251,6 +267,7 @@ func (v *Vmess) ConvertToGeneralConfig() (g protocol.GeneralConfig) {
 	g.SNI = v.SNI
 	g.ALPN = v.ALPN
 	g.TlsFingerprint = v.TlsFingerprint
+	g.ECH = v.ECH
 	g.Type = v.Type
 	g.OrigLink = v.GetLink()
 
@@ -366,8 +383,11 @@ func (v *Vmess) BuildOutboundDetourConfig(allowInsecure bool) (*conf.OutboundDet
 			v.TlsFingerprint = "chrome"
 		}
 		s.TLSSettings = &conf.TLSConfig{
-			Fingerprint:   v.TlsFingerprint,
-			AllowInsecure: allowInsecure,
+			Fingerprint: v.TlsFingerprint,
+			// A pin checks the certificate itself, and xray refuses
+			// allowInsecure next to it
+			AllowInsecure:        allowInsecure && v.PinnedCert == "",
+			PinnedPeerCertSha256: v.PinnedCert,
 		}
 		if v.SNI != "" {
 			s.TLSSettings.ServerName = v.SNI
@@ -377,6 +397,7 @@ func (v *Vmess) BuildOutboundDetourConfig(allowInsecure bool) (*conf.OutboundDet
 		if v.ALPN != "" {
 			s.TLSSettings.ALPN = &conf.StringList{v.ALPN}
 		}
+		s.TLSSettings.ECHConfigList = v.ECH
 	}
 
 	if v.Aid == nil {
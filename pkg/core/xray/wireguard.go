@@ -69,6 +69,18 @@ func (w *Wireguard) Parse() error {
 		}
 	}
 
+	if _, err := protocol.ParseWireguardReserved(w.Reserved); err != nil {
+		return err
+	}
+
+	for _, raw := range uri.Query()[protocol.WireguardPeerParam] {
+		peer, err := protocol.ParseWireguardPeer(raw)
+		if err != nil {
+			return err
+		}
+		w.Peers = append(w.Peers, peer)
+	}
+
 	w.Remark, err = url.PathUnescape(uri.Fragment)
 	if err != nil {
 		w.Remark = uri.Fragment
@@ -88,6 +100,22 @@ func (w *Wireguard) DetailsStr() string {
 		color.RedString("Secret Key"), w.SecretKey,
 	)
 
+	if w.Reserved != "" {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("Reserved"), w.Reserved)
+	}
+	if w.AllowedIPs != "" {
+		info += fmt.Sprintf("%s: %s\n", color.RedString("Allowed IPs"), w.AllowedIPs)
+	}
+	if w.KeepAlive != 0 {
+		info += fmt.Sprintf("%s: %ds\n", color.RedString("Keepalive"), w.KeepAlive)
+	}
+	if w.Workers != 0 {
+		info += fmt.Sprintf("%s: %d\n", color.RedString("Workers"), w.Workers)
+	}
+	for i, peer := range w.Peers {
+		info += fmt.Sprintf("%s: %s (%s)\n", color.RedString("Peer %d", i+2), peer.Endpoint, peer.PublicKey)
+	}
+
 	return info
 }
 
@@ -117,7 +145,14 @@ func (w *Wireguard) GetLink() string {
 		addQueryParam("publickey", w.PublicKey)
 		addQueryParam("presharedkey", w.PreSharedKey)
 		addQueryParam("address", w.LocalAddress)
+		addQueryParam("allowedips", w.AllowedIPs)
+		addQueryParam("reserved", w.Reserved)
+		addQueryParamInt("keepalive", w.KeepAlive)
+		addQueryParamInt("workers", w.Workers)
 		addQueryParamInt("mtu", w.Mtu)
+		for _, peer := range w.Peers {
+			params.Add(protocol.WireguardPeerParam, peer.String())
+		}
 
 		baseURL.RawQuery = params.Encode()
 
@@ -132,15 +167,19 @@ func (w *Wireguard) GetLink() string {
 func (w *Wireguard) ConvertToGeneralConfig() (g protocol.GeneralConfig) {
 	g.Protocol = w.Name()
 	g.Address = w.Endpoint
+	g.ID = w.SecretKey
+	g.Remark = w.Remark
 	g.OrigLink = w.GetLink()
 
 	return g
 }
 
 type Peer struct {
-	Endpoint     string `json:"endpoint"`
-	PublicKey    string `json:"publicKey"`
-	PreSharedKey string `json:"preSharedKey"`
+	Endpoint     string   `json:"endpoint"`
+	PublicKey    string   `json:"publicKey"`
+	PreSharedKey string   `json:"preSharedKey"`
+	KeepAlive    int32    `json:"keepAlive,omitempty"`
+	AllowedIPs   []string `json:"allowedIPs,omitempty"`
 }
 
 type Config struct {
@@ -148,6 +187,8 @@ type Config struct {
 	Address   []string `json:"address"`
 	Peers     []Peer   `json:"peers"`
 	MTU       int      `json:"mtu"`
+	Workers   int32    `json:"workers,omitempty"`
+	Reserved  []int    `json:"reserved,omitempty"` // Numbers, as []byte would marshal to base64
 }
 
 func (w *Wireguard) BuildOutboundDetourConfig(allowInsecure bool) (*conf.OutboundDetourConfig, error) {
@@ -198,9 +239,31 @@ func (w *Wireguard) BuildOutboundDetourConfig(allowInsecure bool) (*conf.Outboun
 				Endpoint:     w.Endpoint,
 				PublicKey:    w.PublicKey,
 				PreSharedKey: w.PreSharedKey,
+				KeepAlive:    w.KeepAlive,
+				AllowedIPs:   splitCommaList(w.AllowedIPs),
 			},
 		},
-		MTU: int(w.Mtu),
+		MTU:     int(w.Mtu),
+		Workers: w.Workers,
+	}
+
+	// xray-core only has device-wide reserved bytes, so those of additional peers are dropped
+	reserved, err := protocol.ParseWireguardReserved(w.Reserved)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range reserved {
+		cfg.Reserved = append(cfg.Reserved, int(b))
+	}
+
+	for _, peer := range w.Peers {
+		cfg.Peers = append(cfg.Peers, Peer{
+			Endpoint:     peer.Endpoint,
+			PublicKey:    peer.PublicKey,
+			PreSharedKey: peer.PreSharedKey,
+			KeepAlive:    peer.KeepAlive,
+			AllowedIPs:   splitCommaList(peer.AllowedIPs),
+		})
 	}
 
 	jsonData, err := json.Marshal(cfg)
@@ -216,6 +279,17 @@ func (w *Wireguard) BuildOutboundDetourConfig(allowInsecure bool) (*conf.Outboun
 	return out, nil
 }
 
+// splitCommaList splits "a, b,c" into its non-empty, trimmed elements.
+func splitCommaList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func (w *Wireguard) BuildInboundDetourConfig() (*conf.InboundDetourConfig, error) {
 	return nil, fmt.Errorf("creating a WireGuard inbound from a client link is not supported")
 }
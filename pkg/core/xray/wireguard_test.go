@@ -1,9 +1,12 @@
 package xray
 
 import (
+	"encoding/json"
 	"net/url"
 	"reflect"
 	"testing"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
 )
 
 func TestWireguard_GetLink(t *testing.T) {
@@ -72,3 +75,44 @@ func TestWireguard_GetLink(t *testing.T) {
 		})
 	}
 }
+
+func TestWireguard_FullFidelityLink(t *testing.T) {
+	peer := protocol.WireguardPeer{PublicKey: "SECOND_PUB", Endpoint: "[2001:db8::1]:51820", AllowedIPs: "10.10.0.0/16", KeepAlive: 10}
+	link := "wireguard://SECRET_KEY@162.159.192.1:2408?address=172.16.0.2%2F32&publickey=PUBLIC_KEY&reserved=1%2C2%2C3&keepalive=25&workers=2&mtu=1280&peer=" +
+		url.QueryEscape(peer.String()) + "#WARP"
+
+	w := &Wireguard{OrigLink: link}
+	if err := w.Parse(); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if w.Reserved != "1,2,3" || w.KeepAlive != 25 || w.Workers != 2 || len(w.Peers) != 1 || w.Peers[0] != peer {
+		t.Fatalf("unexpected parse result: %+v", w)
+	}
+
+	// Rebuild the link from the fields alone and parse it again
+	rebuilt := *w
+	rebuilt.OrigLink = ""
+	again := &Wireguard{OrigLink: rebuilt.GetLink()}
+	if err := again.Parse(); err != nil {
+		t.Fatalf("Parse() of rebuilt link error = %v", err)
+	}
+	again.OrigLink = ""
+	if !reflect.DeepEqual(&rebuilt, again) {
+		t.Errorf("round trip mismatch:\n got  %+v\n want %+v", again, &rebuilt)
+	}
+
+	out, err := w.BuildOutboundDetourConfig(false)
+	if err != nil {
+		t.Fatalf("BuildOutboundDetourConfig() error = %v", err)
+	}
+	var settings Config
+	if err := json.Unmarshal(*out.Settings, &settings); err != nil {
+		t.Fatalf("settings are not valid JSON: %v", err)
+	}
+	if !reflect.DeepEqual(settings.Reserved, []int{1, 2, 3}) || settings.Workers != 2 {
+		t.Errorf("reserved/workers not set: %+v", settings)
+	}
+	if len(settings.Peers) != 2 || settings.Peers[0].KeepAlive != 25 || settings.Peers[1].Endpoint != peer.Endpoint {
+		t.Errorf("peers not set: %+v", settings.Peers)
+	}
+}
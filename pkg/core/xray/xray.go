@@ -2,6 +2,7 @@ package xray
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/http"
@@ -19,6 +20,7 @@ import (
 	xraynet "github.com/xtls/xray-core/common/net"
 	"github.com/xtls/xray-core/common/serial"
 	"github.com/xtls/xray-core/core"
+	"github.com/xtls/xray-core/infra/conf"
 	"github.com/xtls/xray-core/transport/internet"
 
 	// The following deps are necessary as they register handlers in their init functions.
@@ -85,6 +87,13 @@ type Core struct {
 	// named OS interface (e.g. "eth0"). Registered as a process-global
 	// dial controller on the first NewXrayService call.
 	BindInterface string
+
+	// Fragment, when set, routes outbound dials through a freedom
+	// outbound that fragments the TLS ClientHello and sends noises.
+	Fragment *protocol.FragmentOptions
+
+	// Mux, when set, overrides the mux settings of every outbound.
+	Mux *protocol.MuxOptions
 }
 
 func (c *Core) Name() string {
@@ -115,6 +124,22 @@ func WithBindInterface(iface string) ServiceOption {
 	}
 }
 
+// WithFragment configures TLS fragmentation and noise packets for all
+// outbounds. nil disables it.
+func WithFragment(fragment *protocol.FragmentOptions) ServiceOption {
+	return func(c *Core) {
+		c.Fragment = fragment
+	}
+}
+
+// WithMux overrides the mux settings of all outbounds, whatever their
+// links say. nil leaves mux to the links.
+func WithMux(mux *protocol.MuxOptions) ServiceOption {
+	return func(c *Core) {
+		c.Mux = mux
+	}
+}
+
 func NewXrayService(verbose bool, allowInsecure bool, opts ...ServiceOption) *Core {
 	s := &Core{
 		Inbound:       nil,
@@ -162,6 +187,11 @@ func (c *Core) MakeInstance(ctx context.Context, outbound protocol.Protocol) (pr
 	if err != nil {
 		return nil, err
 	}
+	c.applyMux(out, ob)
+	fragment, err := c.applyFragment(ob)
+	if err != nil {
+		return nil, err
+	}
 	built, err1 := ob.Build()
 	if err1 != nil {
 		return nil, err1
@@ -192,6 +222,9 @@ func (c *Core) MakeInstance(ctx context.Context, outbound protocol.Protocol) (pr
 		clientConfig.Inbound = []*core.InboundHandlerConfig{ibcBuilt}
 	}
 	clientConfig.Outbound = []*core.OutboundHandlerConfig{built}
+	if fragment != nil {
+		clientConfig.Outbound = append(clientConfig.Outbound, fragment)
+	}
 
 	server, err2 := core.New(clientConfig)
 	if err2 != nil {
@@ -200,6 +233,50 @@ func (c *Core) MakeInstance(ctx context.Context, outbound protocol.Protocol) (pr
 	return server, nil
 }
 
+// MakeServerInstance builds an instance serving the inbound set with
+// SetInbound and sending its traffic out through freedom, i.e. a server
+// for the inbound's protocol.
+func (c *Core) MakeServerInstance(ctx context.Context) (protocol.Instance, error) {
+	if c.Inbound == nil {
+		return nil, fmt.Errorf("no inbound set")
+	}
+	ibc, err := c.Inbound.BuildInboundDetourConfig()
+	if err != nil {
+		return nil, err
+	}
+	ibcBuilt, err := ibc.Build()
+	if err != nil {
+		return nil, err
+	}
+	settings := json.RawMessage(`{}`)
+	freedom := &conf.OutboundDetourConfig{
+		Protocol: "freedom",
+		Tag:      "direct",
+		Settings: &settings,
+	}
+	freedomBuilt, err := freedom.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	serverConfig := &core.Config{
+		App: []*serial.TypedMessage{
+			serial.ToTypedMessage(&applog.Config{
+				ErrorLogType:  c.LogType,
+				AccessLogType: c.LogType,
+				ErrorLogLevel: c.LogLevel,
+				EnableDnsLog:  false,
+			}),
+			serial.ToTypedMessage(&dispatcher.Config{}),
+			serial.ToTypedMessage(&proxyman.InboundConfig{}),
+			serial.ToTypedMessage(&proxyman.OutboundConfig{}),
+		},
+		Inbound:  []*core.InboundHandlerConfig{ibcBuilt},
+		Outbound: []*core.OutboundHandlerConfig{freedomBuilt},
+	}
+	return core.New(serverConfig)
+}
+
 func (c *Core) MakeHttpClient(ctx context.Context, outbound protocol.Protocol, maxDelay time.Duration) (*http.Client, protocol.Instance, error) {
 	out := outbound.(Protocol)
 	instance, err := c.MakeInstance(ctx, out)
@@ -226,6 +303,18 @@ func (c *Core) MakeHttpClient(ctx context.Context, outbound protocol.Protocol, m
 	}, instance, nil
 }
 
+// ListenPacket opens a UDP connection through instance, which MakeInstance
+// or MakeHttpClient built. Packets go out through its outbound to the
+// address they are written to, which must be an IP address. The
+// connection ignores deadlines; close it to unblock a read.
+func ListenPacket(ctx context.Context, instance protocol.Instance) (net.PacketConn, error) {
+	inst, ok := instance.(*core.Instance)
+	if !ok {
+		return nil, fmt.Errorf("%T is not an xray-core instance", instance)
+	}
+	return core.DialUDP(ctx, inst)
+}
+
 //func (c *Core) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
 //	dest, err := xraynet.ParseDestination(fmt.Sprintf("%s:%s", network, addr))
 //	if err != nil {
@@ -116,6 +116,8 @@ func TestProtocol_BuildConfigs(t *testing.T) {
 	}{
 		{"VLESS-WS", "vless://a1a1-b2b2-c3c3@1.2.3.4:80?type=ws&host=my.host.com&path=%2F#VLESS+WS", false},
 		{"Trojan-GRPC", "trojan://password@example.com:443?security=tls&sni=sub.domain.com&type=grpc&serviceName=my-service#Trojan+GRPC", false},
+		// xray refuses allowInsecure, which the pin overrides
+		{"Trojan-Pinned", "trojan://password@example.com:443?security=tls&sni=sub.domain.com&type=tcp&allowInsecure=1&pcs=ba884517a1297bf33ca845310cc634440aa41b4ad850711c4cdf3d5c4b3d9e50#Pinned", false},
 		{"Shadowsocks", "ss://YWVzLTI1Ni1nY206cGFzc3dvcmQ=@example.com:443#SS", false},
 		{"SOCKS5-Auth", "socks://dXNlcjpwYXNzd29yZA==@example.com:1080#SOCKS", false},
 		{"WireGuard", "wireguard://SECRET_KEY@1.2.3.4:51820?address=10.0.0.2%2F32&publickey=PUBLIC_KEY#WG", true}, // Inbound from client link not supported
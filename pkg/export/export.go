@@ -0,0 +1,210 @@
+// Package export renders a set of share links as a ready-to-use client
+// configuration for xray-core, sing-box or Clash/Mihomo.
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/convert"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core"
+	"github.com/lilendian0x00/xray-knife/v10/utils/customlog"
+)
+
+// Format is an output format accepted by Build.
+type Format string
+
+const (
+	FormatLinks   Format = "links"
+	FormatXray    Format = "xray"
+	FormatSingbox Format = "singbox"
+	FormatClash   Format = "clash"
+)
+
+// Formats lists every supported format, in the order shown in help texts.
+var Formats = []Format{FormatLinks, FormatXray, FormatSingbox, FormatClash}
+
+// Tag of the single outbound (or the selector/balancer in front of several).
+const proxyTag = "proxy"
+
+// ParseFormat validates a user supplied format name.
+func ParseFormat(name string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == strings.ToLower(name) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q. Available formats: %s", name, FormatNames())
+}
+
+// FormatNames returns the supported formats as "links, xray, ...".
+func FormatNames() string {
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Ext returns the usual file extension for the format.
+func (f Format) Ext() string {
+	switch f {
+	case FormatXray, FormatSingbox:
+		return ".json"
+	case FormatClash:
+		return ".yaml"
+	default:
+		return ".txt"
+	}
+}
+
+// Build renders links in the given format. Links the target can't express
+// are skipped with a warning; an error is returned only if none are left.
+func Build(format Format, links []string) ([]byte, error) {
+	var cleaned []string
+	for _, l := range links {
+		if l = strings.TrimSpace(l); l != "" {
+			cleaned = append(cleaned, l)
+		}
+	}
+	if len(cleaned) == 0 {
+		return nil, errors.New("no config links to export")
+	}
+
+	switch format {
+	case FormatLinks:
+		return buildLinks(cleaned)
+	case FormatXray:
+		return buildXray(cleaned)
+	case FormatSingbox:
+		return buildSingbox(cleaned)
+	case FormatClash:
+		out, skipped, err := convert.LinksToClash(cleaned)
+		warnSkipped(skipped)
+		return out, err
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// buildLinks keeps the links every core can parse, one per line.
+func buildLinks(links []string) ([]byte, error) {
+	c := core.NewAutomaticCore(false, false)
+	var valid []string
+	var skipped []error
+	for _, link := range links {
+		p, err := c.CreateProtocol(link)
+		if err == nil {
+			err = p.Parse()
+		}
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("%s: %w", link, err))
+			continue
+		}
+		valid = append(valid, link)
+	}
+	warnSkipped(skipped)
+	if len(valid) == 0 {
+		return nil, errors.New("none of the config links could be parsed")
+	}
+	return []byte(strings.Join(valid, "\n") + "\n"), nil
+}
+
+func warnSkipped(skipped []error) {
+	for _, err := range skipped {
+		customlog.Printf(customlog.Warning, "Skipping config: %v\n", err)
+	}
+}
+
+// marshalClean re-indents verbose JSON without its nil, false, zero and empty
+// values, so the output only contains what was actually configured.
+func marshalClean(verbose []byte) ([]byte, error) {
+	var generic map[string]interface{}
+	if err := json.Unmarshal(verbose, &generic); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal verbose JSON to map: %w", err)
+	}
+	return json.MarshalIndent(removeEmptyValues(generic), "", "  ")
+}
+
+// removeEmptyValues recursively traverses a map or slice and removes keys/elements
+// that are nil, false, 0, empty strings, or empty collections.
+func removeEmptyValues(data interface{}) interface{} {
+	if data == nil {
+		return nil
+	}
+
+	val := reflect.ValueOf(data)
+
+	switch val.Kind() {
+	case reflect.Map:
+		// Create a new map to hold the non-empty values
+		cleanMap := make(map[string]interface{})
+		for _, key := range val.MapKeys() {
+			v := val.MapIndex(key)
+			// Recurse on the value
+			cleanedValue := removeEmptyValues(v.Interface())
+			// Check if the cleaned value is non-empty before adding it
+			if cleanedValue != nil {
+				cleanMap[key.String()] = cleanedValue
+			}
+		}
+		// If the cleaned map is empty, return nil to remove it from parent
+		if len(cleanMap) == 0 {
+			return nil
+		}
+		return cleanMap
+
+	case reflect.Slice:
+		// If the slice is empty, return nil
+		if val.Len() == 0 {
+			return nil
+		}
+		// Create a new slice to hold non-empty elements
+		var cleanSlice []interface{}
+		for i := 0; i < val.Len(); i++ {
+			cleanedElement := removeEmptyValues(val.Index(i).Interface())
+			if cleanedElement != nil {
+				cleanSlice = append(cleanSlice, cleanedElement)
+			}
+		}
+		// If the cleaned slice is empty, return nil
+		if len(cleanSlice) == 0 {
+			return nil
+		}
+		return cleanSlice
+
+	case reflect.Ptr, reflect.Interface:
+		if val.IsNil() {
+			return nil
+		}
+		// Recurse on the element pointed to by the pointer/interface
+		return removeEmptyValues(val.Elem().Interface())
+
+	case reflect.String:
+		if val.String() == "" {
+			return nil
+		}
+	case reflect.Bool:
+		if !val.Bool() {
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if val.Int() == 0 {
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if val.Uint() == 0 {
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if val.Float() == 0 {
+			return nil
+		}
+	}
+
+	// If the value is not considered empty, return it
+	return data
+}
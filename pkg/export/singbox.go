@@ -0,0 +1,112 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/convert"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/singbox"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+const (
+	singboxURLTestTag = "auto"
+	singboxTestURL    = "https://www.gstatic.com/generate_204"
+)
+
+// buildSingbox assembles a sing-box client config with a mixed (HTTP+SOCKS)
+// inbound on 127.0.0.1:2080. Several outbounds get a urltest group and a
+// selector in front of them.
+func buildSingbox(links []string) ([]byte, error) {
+	singboxCore := singbox.NewSingboxService(false, false)
+
+	var outbounds []option.Outbound
+	var tags []string
+	var skipped []error
+	taken := map[string]bool{proxyTag: true, singboxURLTestTag: true}
+	for _, link := range links {
+		out, remark, err := singboxOutbound(singboxCore, link)
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("%s: %w", link, err))
+			continue
+		}
+		out.Tag = convert.UniqueName(remark, len(outbounds)+1, taken)
+		outbounds = append(outbounds, *out)
+		tags = append(tags, out.Tag)
+	}
+	warnSkipped(skipped)
+	if len(outbounds) == 0 {
+		return nil, errors.New("none of the config links is supported by sing-box")
+	}
+
+	if len(outbounds) == 1 {
+		outbounds[0].Tag = proxyTag
+	} else {
+		outbounds = append([]option.Outbound{
+			{
+				Type: "selector",
+				Tag:  proxyTag,
+				Options: &option.SelectorOutboundOptions{
+					Outbounds: append([]string{singboxURLTestTag}, tags...),
+					Default:   singboxURLTestTag,
+				},
+			},
+			{
+				Type: "urltest",
+				Tag:  singboxURLTestTag,
+				Options: &option.URLTestOutboundOptions{
+					Outbounds: tags,
+					URL:       singboxTestURL,
+				},
+			},
+		}, outbounds...)
+	}
+
+	listen := badoption.Addr(netip.MustParseAddr("127.0.0.1"))
+	opts := option.Options{
+		Log: &option.LogOptions{Level: "warn"},
+		Inbounds: []option.Inbound{{
+			Type: "mixed",
+			Tag:  "mixed-in",
+			Options: &option.HTTPMixedInboundOptions{
+				ListenOptions: option.ListenOptions{
+					Listen:     &listen,
+					ListenPort: 2080,
+				},
+			},
+		}},
+		Outbounds: outbounds,
+		Route:     &option.RouteOptions{Final: proxyTag},
+	}
+
+	// Inbound/Outbound options only marshal through sing's context-aware JSON.
+	verboseBytes, err := json.MarshalContext(context.Background(), &opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config to verbose JSON: %w", err)
+	}
+	return marshalClean(verboseBytes)
+}
+
+func singboxOutbound(c *singbox.Core, link string) (*option.Outbound, string, error) {
+	p, err := c.CreateProtocol(link)
+	if err != nil {
+		return nil, "", err
+	}
+	sp, ok := p.(singbox.Protocol)
+	if !ok {
+		return nil, "", errors.New("not a supported sing-box protocol")
+	}
+	if err := sp.Parse(); err != nil {
+		return nil, "", err
+	}
+	out, err := sp.CraftOutboundOptions(false)
+	if err != nil {
+		return nil, "", err
+	}
+	return out, sp.ConvertToGeneralConfig().Remark, nil
+}
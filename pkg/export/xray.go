@@ -0,0 +1,108 @@
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/convert"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/xray"
+
+	"github.com/xtls/xray-core/infra/conf"
+	"github.com/xtls/xray-core/infra/conf/cfgcommon/duration"
+)
+
+// xrayProbeURL is the observatory probe used to pick the fastest outbound.
+const xrayProbeURL = "https://www.gstatic.com/generate_204"
+
+// buildXray assembles an xray-core client config with a SOCKS inbound on
+// 127.0.0.1:1080. Several outbounds are put behind a leastPing balancer.
+func buildXray(links []string) ([]byte, error) {
+	xrayCore := xray.NewXrayService(false, false)
+
+	var outbounds []conf.OutboundDetourConfig
+	var tags []string
+	var skipped []error
+	taken := map[string]bool{proxyTag: true}
+	for _, link := range links {
+		out, remark, err := xrayOutbound(xrayCore, link)
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("%s: %w", link, err))
+			continue
+		}
+		out.Tag = convert.UniqueName(remark, len(outbounds)+1, taken)
+		outbounds = append(outbounds, *out)
+		tags = append(tags, out.Tag)
+	}
+	warnSkipped(skipped)
+	if len(outbounds) == 0 {
+		return nil, errors.New("none of the config links is supported by xray-core")
+	}
+
+	// Create a default SOCKS inbound
+	defaultInbound := &xray.Socks{
+		Address: "127.0.0.1",
+		Port:    "1080",
+	}
+	inboundDetour, err := defaultInbound.BuildInboundDetourConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build default inbound detour: %w", err)
+	}
+
+	finalConfig := &conf.Config{
+		LogConfig: &conf.LogConfig{
+			LogLevel: "warning",
+		},
+		InboundConfigs:  []conf.InboundDetourConfig{*inboundDetour},
+		OutboundConfigs: outbounds,
+	}
+
+	if len(outbounds) == 1 {
+		finalConfig.OutboundConfigs[0].Tag = proxyTag
+	} else {
+		rule, _ := json.Marshal(map[string]string{
+			"type":        "field",
+			"network":     "tcp,udp",
+			"balancerTag": proxyTag,
+		})
+		finalConfig.RouterConfig = &conf.RouterConfig{
+			RuleList: []json.RawMessage{rule},
+			Balancers: []*conf.BalancingRule{{
+				Tag:       proxyTag,
+				Selectors: tags,
+				Strategy:  conf.StrategyConfig{Type: "leastPing"},
+			}},
+		}
+		finalConfig.Observatory = &conf.ObservatoryConfig{
+			SubjectSelector: tags,
+			ProbeURL:        xrayProbeURL,
+			ProbeInterval:   duration.Duration(5 * time.Minute),
+		}
+	}
+
+	verboseBytes, err := json.Marshal(finalConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config to verbose JSON: %w", err)
+	}
+	return marshalClean(verboseBytes)
+}
+
+func xrayOutbound(c *xray.Core, link string) (*conf.OutboundDetourConfig, string, error) {
+	p, err := c.CreateProtocol(link)
+	if err != nil {
+		return nil, "", err
+	}
+	xp, ok := p.(xray.Protocol)
+	if !ok {
+		return nil, "", errors.New("not a supported xray-core protocol")
+	}
+	if err := xp.Parse(); err != nil {
+		return nil, "", err
+	}
+	out, err := xp.BuildOutboundDetourConfig(false)
+	if err != nil {
+		return nil, "", err
+	}
+	return out, xp.ConvertToGeneralConfig().Remark, nil
+}
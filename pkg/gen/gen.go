@@ -0,0 +1,318 @@
+// Package gen generates server configs for xray-core and sing-box together
+// with the share links clients connect to them with.
+package gen
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+)
+
+// Server protocols Generate can set up.
+const (
+	VlessReality = "vless-reality"
+	TrojanTLS    = "trojan-tls"
+	Hysteria2    = "hysteria2"
+	SS2022       = "ss2022"
+)
+
+// Protocols lists the server protocols in the order they are documented.
+var Protocols = []string{VlessReality, TrojanTLS, Hysteria2, SS2022}
+
+// SS2022Methods maps the Shadowsocks 2022 methods to their key length.
+// 2022-blake3-chacha20-poly1305 is left out: both cores serve it to a
+// single user only, and the configs are always multi-user.
+var SS2022Methods = map[string]int{
+	"2022-blake3-aes-128-gcm": 16,
+	"2022-blake3-aes-256-gcm": 32,
+}
+
+// Options configures Generate.
+type Options struct {
+	Core     string // "xray" or "singbox"
+	Protocol string // one of Protocols
+	// Server is the address clients dial (public IP or domain).
+	Server string
+	Port   int
+	// Listen is the address the server binds; defaults to all interfaces.
+	Listen string
+	// SNI is the site REALITY impersonates (VlessReality), or the TLS
+	// server name of the certificate (TrojanTLS, Hysteria2). Defaults to
+	// www.microsoft.com for REALITY and to Server otherwise.
+	SNI string
+	// CertFile and KeyFile are the TLS certificate paths written into the
+	// server config. When empty a self-signed certificate is generated,
+	// referred to as server.crt and server.key in CertDir. Trojan links pin
+	// it and hysteria2 links skip verification.
+	CertFile, KeyFile string
+	CertDir           string
+	Method            string // Shadowsocks 2022 method, defaults to 2022-blake3-aes-128-gcm
+	Users             int    // number of users, one client link each; defaults to 1
+	Remark            string // client link remark; user numbers are appended when Users > 1
+}
+
+// Result is a generated server config and its client links.
+type Result struct {
+	ServerConfig []byte   // JSON config of the chosen core
+	Links        []string // one share link per user
+	// CertFile and KeyFile are the certificate paths the server config uses.
+	CertFile, KeyFile string
+	// Certificate and Key hold a generated self-signed certificate in PEM,
+	// to be saved at CertFile and KeyFile. Nil when the options named
+	// existing files.
+	Certificate, Key []byte
+	// PublicKey is the REALITY public key clients need (VlessReality).
+	PublicKey string
+}
+
+// user holds the credentials of one client.
+type user struct {
+	ID       string // VLESS UUID, or Trojan/Hysteria2 password
+	ShortID  string // REALITY short ID
+	Password string // Shadowsocks 2022 user key
+}
+
+// Generate builds a server config for opts and the links of its clients.
+func Generate(opts Options) (*Result, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, err
+	}
+
+	res := &Result{}
+	var certPin string
+	if opts.Protocol == TrojanTLS || opts.Protocol == Hysteria2 {
+		if opts.CertFile == "" {
+			var err error
+			if res.Certificate, res.Key, err = SelfSignedCert(opts.SNI); err != nil {
+				return nil, err
+			}
+			if certPin, err = CertPin(res.Certificate); err != nil {
+				return nil, err
+			}
+			opts.CertFile = filepath.Join(opts.CertDir, "server.crt")
+			opts.KeyFile = filepath.Join(opts.CertDir, "server.key")
+		}
+		res.CertFile, res.KeyFile = opts.CertFile, opts.KeyFile
+	}
+
+	var s server
+	switch opts.Core {
+	case "xray":
+		s = &xrayServer{opts: opts}
+	case "singbox":
+		s = &singboxServer{opts: opts}
+	}
+
+	users := make([]user, opts.Users)
+	var err error
+	switch opts.Protocol {
+	case VlessReality:
+		var priv string
+		if priv, res.PublicKey, err = GenerateX25519(); err != nil {
+			return nil, err
+		}
+		for i := range users {
+			users[i].ID = uuid.NewString()
+			if users[i].ShortID, err = RandomHex(8); err != nil {
+				return nil, err
+			}
+		}
+		s.vlessReality(priv, users)
+	case TrojanTLS, Hysteria2:
+		for i := range users {
+			if users[i].ID, err = RandomHex(16); err != nil {
+				return nil, err
+			}
+		}
+		if opts.Protocol == TrojanTLS {
+			s.trojan(users)
+		} else {
+			s.hysteria2(users)
+		}
+	case SS2022:
+		var serverKey string
+		if serverKey, err = RandomKey(SS2022Methods[opts.Method]); err != nil {
+			return nil, err
+		}
+		for i := range users {
+			if users[i].Password, err = RandomKey(SS2022Methods[opts.Method]); err != nil {
+				return nil, err
+			}
+		}
+		s.shadowsocks(serverKey, users)
+		// Multi-user 2022 links carry "serverKey:userKey" as the password
+		for i := range users {
+			users[i].Password = serverKey + ":" + users[i].Password
+		}
+	}
+
+	if res.ServerConfig, err = json.MarshalIndent(s.config(), "", "  "); err != nil {
+		return nil, fmt.Errorf("failed to encode server config: %w", err)
+	}
+	for i, u := range users {
+		res.Links = append(res.Links, clientLink(opts, u, res.PublicKey, certPin, i))
+	}
+	return res, nil
+}
+
+func (o *Options) normalize() error {
+	switch strings.ToLower(o.Core) {
+	case "xray":
+		o.Core = "xray"
+	case "singbox", "sing-box":
+		o.Core = "singbox"
+	default:
+		return fmt.Errorf("unknown core %q (want xray or sing-box)", o.Core)
+	}
+
+	known := false
+	for _, p := range Protocols {
+		known = known || o.Protocol == p
+	}
+	if !known {
+		return fmt.Errorf("unknown protocol %q (want one of %s)", o.Protocol, strings.Join(Protocols, ", "))
+	}
+
+	o.Server = strings.Trim(strings.TrimSpace(o.Server), "[]")
+	if o.Server == "" {
+		return fmt.Errorf("the server address clients dial is required")
+	}
+	if o.Port == 0 {
+		o.Port = 443
+	}
+	if o.Port < 1 || o.Port > 65535 {
+		return fmt.Errorf("invalid port %d", o.Port)
+	}
+	if o.Listen == "" {
+		o.Listen = "0.0.0.0"
+		if o.Core == "singbox" {
+			o.Listen = "::"
+		}
+	}
+	if o.SNI == "" {
+		if o.Protocol == VlessReality {
+			o.SNI = "www.microsoft.com"
+		} else {
+			o.SNI = o.Server
+		}
+	}
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return fmt.Errorf("the certificate and key files must be given together")
+	}
+	if o.Protocol == SS2022 {
+		if o.Method == "" {
+			o.Method = "2022-blake3-aes-128-gcm"
+		}
+		if _, ok := SS2022Methods[o.Method]; !ok {
+			return fmt.Errorf("unknown Shadowsocks 2022 method %q", o.Method)
+		}
+	}
+	if o.Users <= 0 {
+		o.Users = 1
+	}
+	if o.Remark == "" {
+		o.Remark = o.Protocol
+	}
+	return nil
+}
+
+// clientLink returns the share link of user i. certPin is the pin of the
+// self-signed certificate, "" when the server has a real one.
+func clientLink(o Options, u user, publicKey, certPin string, i int) string {
+	remark := o.Remark
+	if o.Users > 1 {
+		remark = fmt.Sprintf("%s-%d", remark, i+1)
+	}
+	host := net.JoinHostPort(o.Server, strconv.Itoa(o.Port))
+	q := url.Values{}
+
+	link := url.URL{Host: host, Fragment: remark}
+	switch o.Protocol {
+	case VlessReality:
+		link.Scheme = protocol.VlessIdentifier
+		link.User = url.User(u.ID)
+		q.Set("encryption", "none")
+		q.Set("flow", "xtls-rprx-vision")
+		q.Set("security", "reality")
+		q.Set("sni", o.SNI)
+		q.Set("fp", "chrome")
+		q.Set("pbk", publicKey)
+		q.Set("sid", u.ShortID)
+		q.Set("type", "tcp")
+	case TrojanTLS:
+		link.Scheme = protocol.TrojanIdentifier
+		link.User = url.User(u.ID)
+		q.Set("security", "tls")
+		q.Set("sni", o.SNI)
+		q.Set("type", "tcp")
+		// xray-core no longer accepts allowInsecure, only a pin
+		if certPin != "" {
+			q.Set(protocol.PinnedCertParam, certPin)
+		}
+	case Hysteria2:
+		link.Scheme = protocol.Hysteria2Identifier
+		link.User = url.User(u.ID)
+		q.Set("sni", o.SNI)
+		// Hysteria2 runs on sing-box, which can't check a certificate pin
+		if certPin != "" {
+			q.Set("insecure", "1")
+		}
+	case SS2022:
+		// The parsers want base64 userinfo; URL encoding keeps "/" and "+"
+		// of the keys out of the authority.
+		creds := base64.URLEncoding.EncodeToString([]byte(o.Method + ":" + u.Password))
+		return "ss://" + creds + "@" + host + "#" + url.PathEscape(remark)
+	}
+	link.RawQuery = q.Encode()
+	return link.String()
+}
+
+// GenerateX25519 returns a new REALITY key pair, base64url encoded like
+// `xray x25519` prints them.
+func GenerateX25519() (privateKey, publicKey string, err error) {
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		return "", "", fmt.Errorf("failed to generate x25519 key: %w", err)
+	}
+	// Clamp as xray does, so the key is stored in its canonical form
+	seed[0] &= 248
+	seed[31] &= 127
+	seed[31] |= 64
+
+	key, err := ecdh.X25519().NewPrivateKey(seed)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate x25519 key: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(key.Bytes()),
+		base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()), nil
+}
+
+// RandomHex returns n random bytes as hex.
+func RandomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// RandomKey returns a base64 key of n bytes, as Shadowsocks 2022 uses.
+func RandomKey(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,172 @@
+package gen
+
+import (
+	"crypto/ecdh"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-shadowsocks/shadowaead_2022"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core"
+)
+
+func TestGenerateX25519(t *testing.T) {
+	priv, pub, err := GenerateX25519()
+	if err != nil {
+		t.Fatal(err)
+	}
+	privBytes, err := base64.RawURLEncoding.DecodeString(priv)
+	if err != nil {
+		t.Fatalf("private key %q is not base64url: %v", priv, err)
+	}
+	key, err := ecdh.X25519().NewPrivateKey(privBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()); got != pub {
+		t.Errorf("public key = %s, want %s derived from the private key", pub, got)
+	}
+}
+
+func TestGenerateLinksParse(t *testing.T) {
+	c := core.NewAutomaticCore(false, false)
+	for _, coreName := range []string{"xray", "sing-box"} {
+		for _, proto := range Protocols {
+			res, err := Generate(Options{
+				Core:     coreName,
+				Protocol: proto,
+				Server:   "203.0.113.7",
+				Port:     8443,
+				Users:    2,
+				Remark:   "my server",
+			})
+			if err != nil {
+				t.Fatalf("%s/%s: %v", coreName, proto, err)
+			}
+
+			var cfg map[string]any
+			if err := json.Unmarshal(res.ServerConfig, &cfg); err != nil {
+				t.Errorf("%s/%s: server config is not JSON: %v", coreName, proto, err)
+			}
+			if len(res.Links) != 2 {
+				t.Fatalf("%s/%s: got %d links, want 2", coreName, proto, len(res.Links))
+			}
+			for _, link := range res.Links {
+				p, err := c.CreateProtocol(link)
+				if err == nil {
+					err = p.Parse()
+				}
+				if err != nil {
+					t.Errorf("%s/%s: link %s does not parse: %v", coreName, proto, link, err)
+					continue
+				}
+				g := p.ConvertToGeneralConfig()
+				if g.Address != "203.0.113.7" || g.Port != "8443" {
+					t.Errorf("%s/%s: link dials %s:%s, want 203.0.113.7:8443", coreName, proto, g.Address, g.Port)
+				}
+				if !strings.HasPrefix(g.Remark, "my server-") {
+					t.Errorf("%s/%s: remark = %q", coreName, proto, g.Remark)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateSelfSignedCert(t *testing.T) {
+	res, err := Generate(Options{Core: "xray", Protocol: TrojanTLS, Server: "example.com", CertDir: "/etc/xray"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Certificate == nil || res.Key == nil {
+		t.Fatal("no self-signed certificate generated")
+	}
+	if res.CertFile != "/etc/xray/server.crt" || res.KeyFile != "/etc/xray/server.key" {
+		t.Errorf("certificate paths = %s, %s", res.CertFile, res.KeyFile)
+	}
+	if !strings.Contains(string(res.ServerConfig), `"/etc/xray/server.crt"`) {
+		t.Error("server config does not refer to the generated certificate")
+	}
+	pin, err := CertPin(res.Certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(res.Links[0], "pcs="+pin) || strings.Contains(res.Links[0], "allowInsecure") {
+		t.Errorf("link %s does not pin the self-signed certificate", res.Links[0])
+	}
+
+	res, err = Generate(Options{Core: "sing-box", Protocol: Hysteria2, Server: "example.com", CertFile: "a.crt", KeyFile: "a.key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Certificate != nil || strings.Contains(res.Links[0], "insecure=1") {
+		t.Error("a certificate was generated although one was given")
+	}
+}
+
+func TestGenerateRejectsBadOptions(t *testing.T) {
+	for name, opts := range map[string]Options{
+		"core":     {Core: "v2ray", Protocol: VlessReality, Server: "1.1.1.1"},
+		"protocol": {Core: "xray", Protocol: "vmess", Server: "1.1.1.1"},
+		"server":   {Core: "xray", Protocol: VlessReality},
+		"port":     {Core: "xray", Protocol: VlessReality, Server: "1.1.1.1", Port: 70000},
+		"method":   {Core: "xray", Protocol: SS2022, Server: "1.1.1.1", Method: "aes-128-gcm"},
+		"cert":     {Core: "xray", Protocol: TrojanTLS, Server: "1.1.1.1", CertFile: "a.crt"},
+	} {
+		if _, err := Generate(opts); err == nil {
+			t.Errorf("%s: Generate() = nil error", name)
+		}
+	}
+}
+
+func TestGenerateSS2022MethodsServe(t *testing.T) {
+	for method := range SS2022Methods {
+		for _, coreName := range []string{"xray", "sing-box"} {
+			res, err := Generate(Options{Core: coreName, Protocol: SS2022, Server: "1.1.1.1", Method: method, Users: 2})
+			if err != nil {
+				t.Fatalf("%s/%s: %v", coreName, method, err)
+			}
+
+			// Both cores serve several users with the same service
+			var cfg struct {
+				Inbounds []struct {
+					Method   string `json:"method"`
+					Password string `json:"password"`
+					Users    []struct {
+						Password string `json:"password"`
+					} `json:"users"`
+					Settings struct {
+						Method   string `json:"method"`
+						Password string `json:"password"`
+						Clients  []struct {
+							Password string `json:"password"`
+						} `json:"clients"`
+					} `json:"settings"`
+				} `json:"inbounds"`
+			}
+			if err := json.Unmarshal(res.ServerConfig, &cfg); err != nil || len(cfg.Inbounds) != 1 {
+				t.Fatalf("%s/%s: unexpected server config: %v", coreName, method, err)
+			}
+			in := cfg.Inbounds[0]
+			var passwords []string
+			for _, u := range in.Users {
+				passwords = append(passwords, u.Password)
+			}
+			if coreName == "xray" {
+				in.Method, in.Password = in.Settings.Method, in.Settings.Password
+				for _, c := range in.Settings.Clients {
+					passwords = append(passwords, c.Password)
+				}
+			}
+
+			service, err := shadowaead_2022.NewMultiServiceWithPassword[int](in.Method, in.Password, 300, nil, nil)
+			if err == nil {
+				err = service.UpdateUsersWithPasswords([]int{0, 1}, passwords)
+			}
+			if err != nil {
+				t.Errorf("%s/%s: server config is not served: %v", coreName, method, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package gen
+
+import (
+	"fmt"
+	"net"
+)
+
+// server builds the inbound of one core for the generated users.
+type server interface {
+	vlessReality(privateKey string, users []user)
+	trojan(users []user)
+	hysteria2(users []user)
+	shadowsocks(serverKey string, users []user)
+	config() map[string]any
+}
+
+// userName names user i in the server config, so its traffic can be told
+// apart in the logs.
+func userName(i int) string {
+	return fmt.Sprintf("user%d", i+1)
+}
+
+// xrayServer builds an xray-core server config.
+type xrayServer struct {
+	opts    Options
+	inbound map[string]any
+}
+
+func (x *xrayServer) setInbound(proto string, settings, stream map[string]any) {
+	x.inbound = map[string]any{
+		"tag":      proto + "-in",
+		"listen":   x.opts.Listen,
+		"port":     x.opts.Port,
+		"protocol": proto,
+		"settings": settings,
+		"sniffing": map[string]any{
+			"enabled":      true,
+			"destOverride": []string{"http", "tls", "quic"},
+		},
+	}
+	if stream != nil {
+		x.inbound["streamSettings"] = stream
+	}
+}
+
+func (x *xrayServer) tlsSettings(alpn ...string) map[string]any {
+	tls := map[string]any{
+		"serverName": x.opts.SNI,
+		"certificates": []map[string]any{{
+			"certificateFile": x.opts.CertFile,
+			"keyFile":         x.opts.KeyFile,
+		}},
+	}
+	if len(alpn) > 0 {
+		tls["alpn"] = alpn
+	}
+	return tls
+}
+
+func (x *xrayServer) vlessReality(privateKey string, users []user) {
+	clients := make([]map[string]any, 0, len(users))
+	shortIDs := make([]string, 0, len(users))
+	for i, u := range users {
+		clients = append(clients, map[string]any{"id": u.ID, "flow": "xtls-rprx-vision", "email": userName(i)})
+		shortIDs = append(shortIDs, u.ShortID)
+	}
+	x.setInbound("vless", map[string]any{
+		"clients":    clients,
+		"decryption": "none",
+	}, map[string]any{
+		"network":  "tcp",
+		"security": "reality",
+		"realitySettings": map[string]any{
+			"target":      net.JoinHostPort(x.opts.SNI, "443"),
+			"serverNames": []string{x.opts.SNI},
+			"privateKey":  privateKey,
+			"shortIds":    shortIDs,
+		},
+	})
+}
+
+func (x *xrayServer) trojan(users []user) {
+	clients := make([]map[string]any, 0, len(users))
+	for i, u := range users {
+		clients = append(clients, map[string]any{"password": u.ID, "email": userName(i)})
+	}
+	x.setInbound("trojan", map[string]any{"clients": clients}, map[string]any{
+		"network":     "tcp",
+		"security":    "tls",
+		"tlsSettings": x.tlsSettings(),
+	})
+}
+
+func (x *xrayServer) hysteria2(users []user) {
+	clients := make([]map[string]any, 0, len(users))
+	for i, u := range users {
+		clients = append(clients, map[string]any{"auth": u.ID, "email": userName(i)})
+	}
+	x.setInbound("hysteria", map[string]any{"version": 2, "clients": clients}, map[string]any{
+		"network":          "hysteria",
+		"security":         "tls",
+		"tlsSettings":      x.tlsSettings("h3"),
+		"hysteriaSettings": map[string]any{"version": 2},
+	})
+}
+
+func (x *xrayServer) shadowsocks(serverKey string, users []user) {
+	clients := make([]map[string]any, 0, len(users))
+	for i, u := range users {
+		clients = append(clients, map[string]any{"password": u.Password, "email": userName(i)})
+	}
+	x.setInbound("shadowsocks", map[string]any{
+		"method":   x.opts.Method,
+		"password": serverKey,
+		"clients":  clients,
+		"network":  "tcp,udp",
+	}, nil)
+}
+
+func (x *xrayServer) config() map[string]any {
+	return map[string]any{
+		"log":      map[string]any{"loglevel": "warning"},
+		"inbounds": []map[string]any{x.inbound},
+		"outbounds": []map[string]any{
+			{"tag": "direct", "protocol": "freedom"},
+			{"tag": "block", "protocol": "blackhole"},
+		},
+	}
+}
+
+// singboxServer builds a sing-box server config.
+type singboxServer struct {
+	opts    Options
+	inbound map[string]any
+}
+
+func (s *singboxServer) setInbound(typ string, fields map[string]any) {
+	s.inbound = map[string]any{
+		"type":        typ,
+		"tag":         typ + "-in",
+		"listen":      s.opts.Listen,
+		"listen_port": s.opts.Port,
+	}
+	for k, v := range fields {
+		s.inbound[k] = v
+	}
+}
+
+func (s *singboxServer) tls(alpn ...string) map[string]any {
+	tls := map[string]any{
+		"enabled":          true,
+		"server_name":      s.opts.SNI,
+		"certificate_path": s.opts.CertFile,
+		"key_path":         s.opts.KeyFile,
+	}
+	if len(alpn) > 0 {
+		tls["alpn"] = alpn
+	}
+	return tls
+}
+
+func (s *singboxServer) vlessReality(privateKey string, users []user) {
+	list := make([]map[string]any, 0, len(users))
+	shortIDs := make([]string, 0, len(users))
+	for i, u := range users {
+		list = append(list, map[string]any{"name": userName(i), "uuid": u.ID, "flow": "xtls-rprx-vision"})
+		shortIDs = append(shortIDs, u.ShortID)
+	}
+	s.setInbound("vless", map[string]any{
+		"users": list,
+		"tls": map[string]any{
+			"enabled":     true,
+			"server_name": s.opts.SNI,
+			"reality": map[string]any{
+				"enabled": true,
+				"handshake": map[string]any{
+					"server":      s.opts.SNI,
+					"server_port": 443,
+				},
+				"private_key": privateKey,
+				"short_id":    shortIDs,
+			},
+		},
+	})
+}
+
+func (s *singboxServer) trojan(users []user) {
+	s.setInbound("trojan", map[string]any{
+		"users": s.passwordUsers(users),
+		"tls":   s.tls(),
+	})
+}
+
+func (s *singboxServer) hysteria2(users []user) {
+	s.setInbound("hysteria2", map[string]any{
+		"users": s.passwordUsers(users),
+		"tls":   s.tls("h3"),
+	})
+}
+
+func (s *singboxServer) shadowsocks(serverKey string, users []user) {
+	list := make([]map[string]any, 0, len(users))
+	for i, u := range users {
+		list = append(list, map[string]any{"name": userName(i), "password": u.Password})
+	}
+	s.setInbound("shadowsocks", map[string]any{
+		"method":   s.opts.Method,
+		"password": serverKey,
+		"users":    list,
+	})
+}
+
+func (s *singboxServer) passwordUsers(users []user) []map[string]any {
+	list := make([]map[string]any, 0, len(users))
+	for i, u := range users {
+		list = append(list, map[string]any{"name": userName(i), "password": u.ID})
+	}
+	return list
+}
+
+func (s *singboxServer) config() map[string]any {
+	return map[string]any{
+		"log":       map[string]any{"level": "warn", "timestamp": true},
+		"inbounds":  []map[string]any{s.inbound},
+		"outbounds": []map[string]any{{"type": "direct", "tag": "direct"}},
+	}
+}
@@ -0,0 +1,168 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/alitto/pond/v2"
+	"github.com/gocarina/gocsv"
+
+	"github.com/lilendian0x00/xray-knife/v10/database"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core"
+	"github.com/lilendian0x00/xray-knife/v10/utils"
+)
+
+// CoreComparison is the outcome of testing one link on both xray and
+// sing-box.
+type CoreComparison struct {
+	ConfigLink string `json:"link"`
+	Xray       Result `json:"xray"`
+	Singbox    Result `json:"singbox"`
+	// Disagree is set when the config gets through on one core only, which
+	// points at a core mis-handling its parameters rather than a dead server.
+	Disagree bool `json:"disagree"`
+}
+
+// Reachable reports whether a result's status means the proxy carried the
+// test request, however slowly.
+func Reachable(status string) bool {
+	return status == "passed" || status == "semi-passed" || status == "timeout"
+}
+
+// CoreComparer runs configs through an xray and a sing-box examiner built
+// from the same options.
+type CoreComparer struct {
+	xray    *Examiner
+	singbox *Examiner
+}
+
+// NewCoreComparer creates the examiners of both cores. opts.Core and
+// opts.CorePolicy are ignored.
+func NewCoreComparer(opts Options) (*CoreComparer, error) {
+	opts.CorePolicy = nil
+
+	opts.Core = core.XrayCoreName
+	xray, err := NewExaminer(opts)
+	if err != nil {
+		return nil, err
+	}
+	opts.Core = core.SingboxCoreName
+	singbox, err := NewExaminer(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &CoreComparer{xray: xray, singbox: singbox}, nil
+}
+
+// Supports returns nil when both cores can parse link, otherwise an error
+// naming the core that can't.
+func (c *CoreComparer) Supports(link string) (err error) {
+	defer func() {
+		// Malformed links must not crash the caller
+		if r := recover(); r != nil {
+			err = fmt.Errorf("failed to parse config: %v", r)
+		}
+	}()
+
+	for _, e := range []*Examiner{c.xray, c.singbox} {
+		p, err := e.Core.CreateProtocol(strings.TrimSpace(link))
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.Core.Name(), err)
+		}
+		if err := p.Parse(); err != nil {
+			return fmt.Errorf("%s: %w", e.Core.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Compare tests link on xray, then on sing-box. The cores run one after the
+// other so they don't skew each other's delay.
+func (c *CoreComparer) Compare(ctx context.Context, link string) CoreComparison {
+	cmp := CoreComparison{ConfigLink: link}
+	cmp.Xray, _ = c.xray.ExamineConfigWithRetries(ctx, link)
+	cmp.Singbox, _ = c.singbox.ExamineConfigWithRetries(ctx, link)
+	cmp.Xray.Core, cmp.Singbox.Core = core.XrayCoreName, core.SingboxCoreName
+	cmp.Disagree = Reachable(cmp.Xray.Status) != Reachable(cmp.Singbox.Status)
+	return cmp
+}
+
+// Run compares links concurrently on threadCount workers, sending each
+// comparison to results. onProgress, when non-nil, is fired after each link.
+func (c *CoreComparer) Run(ctx context.Context, links []string, threadCount uint16, results chan<- CoreComparison, onProgress func()) {
+	pool := pond.NewPool(int(threadCount))
+	defer pool.Stop()
+	group := pool.NewGroupContext(ctx)
+
+	for _, link := range links {
+		linkToTest := link
+		group.Submit(func() {
+			cmp := c.Compare(group.Context(), linkToTest)
+			select {
+			case results <- cmp:
+			case <-group.Context().Done():
+			}
+
+			if onProgress != nil {
+				onProgress()
+			}
+		})
+	}
+
+	group.Wait()
+}
+
+// comparisonRow is the CSV form of a CoreComparison.
+type comparisonRow struct {
+	ConfigLink    string `csv:"link"`
+	Disagree      bool   `csv:"disagree"`
+	XrayStatus    string `csv:"xray_status"`
+	XrayDelay     int64  `csv:"xray_delay"`
+	XrayReason    string `csv:"xray_reason"`
+	SingboxStatus string `csv:"singbox_status"`
+	SingboxDelay  int64  `csv:"singbox_delay"`
+	SingboxReason string `csv:"singbox_reason"`
+}
+
+// SaveComparisonsCSV writes comparisons side by side to a CSV file.
+func SaveComparisonsCSV(filePath string, comparisons []CoreComparison) error {
+	rows := make([]comparisonRow, 0, len(comparisons))
+	for _, cmp := range comparisons {
+		rows = append(rows, comparisonRow{
+			ConfigLink:    cmp.ConfigLink,
+			Disagree:      cmp.Disagree,
+			XrayStatus:    cmp.Xray.Status,
+			XrayDelay:     cmp.Xray.Delay,
+			XrayReason:    cmp.Xray.Reason,
+			SingboxStatus: cmp.Singbox.Status,
+			SingboxDelay:  cmp.Singbox.Delay,
+			SingboxReason: cmp.Singbox.Reason,
+		})
+	}
+
+	out, err := gocsv.MarshalString(&rows)
+	if err != nil {
+		return fmt.Errorf("failed to marshal CSV: %w", err)
+	}
+	if err := utils.WriteIntoFile(filePath, []byte(out)); err != nil {
+		return fmt.Errorf("failed to save CSV results: %w", err)
+	}
+	return nil
+}
+
+// SaveComparisonsToDB stores both results of every comparison in the test
+// run runID, one row per core.
+func SaveComparisonsToDB(runID int64, comparisons []CoreComparison) error {
+	dbResults := make([]database.HttpTestResult, 0, 2*len(comparisons))
+	for i := range comparisons {
+		dbResults = append(dbResults, newDBResult(runID, &comparisons[i].Xray), newDBResult(runID, &comparisons[i].Singbox))
+	}
+	if len(dbResults) == 0 {
+		return nil
+	}
+	if err := database.InsertHttpTestResultsBatch(runID, dbResults); err != nil {
+		return fmt.Errorf("failed to save results to database: %w", err)
+	}
+	return nil
+}
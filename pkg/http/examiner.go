@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httptrace"
 	"net/url"
@@ -35,7 +36,8 @@ type Result struct {
 	ProtocolInfo  ProtocolInfo      `csv:"-" json:"protocol"`        // Serializable info for the frontend
 	Status        string            `csv:"status" json:"status"`     // passed, semi-passed, failed, broken
 	Reason        string            `csv:"reason" json:"reason"`     // reason of the error
-	TLS           string            `csv:"tls" json:"tls"`           // none, tls, reality
+	TLS           string            `csv:"tls" json:"tls"`           // none, tls, reality; "+ech" when ECH is used
+	Core          string            `csv:"core" json:"core"`         // core the config was tested on: xray or singbox
 	RealIPAddr    string            `csv:"ip" json:"ip"`             // Real ip address (req to cloudflare.com/cdn-cgi/trace)
 	Delay         int64             `csv:"delay" json:"delay"`       // millisecond
 	HTTPCode      int               `csv:"code" json:"code"`         // HTTP status code of the tested URL
@@ -44,6 +46,14 @@ type Result struct {
 	IpAddrLoc     string            `csv:"location" json:"location"`       // IP address location
 	TTFB          int64             `csv:"ttfb" json:"ttfb"`               // Time to first byte (ms)
 	ConnectTime   int64             `csv:"connect_time" json:"connectTime"` // Connection time (ms)
+	Targets       []TargetResult    `csv:"-" json:"targets,omitempty"`      // Per-target results when testing with a profile
+	UDP           string            `csv:"udp" json:"udp"`                  // ok, fail, unsupported; empty when not tested
+	Samples       int               `csv:"samples" json:"samples"`          // Requests sent in sampling mode, 0 otherwise
+	DelayMin      int64             `csv:"delay_min" json:"delayMin"`       // Fastest sample (ms)
+	DelayP50      int64             `csv:"delay_p50" json:"delayP50"`       // Median sample (ms)
+	DelayP95      int64             `csv:"delay_p95" json:"delayP95"`       // 95th percentile sample (ms)
+	Jitter        int64             `csv:"jitter" json:"jitter"`            // Mean difference between consecutive samples (ms)
+	Loss          float32           `csv:"loss" json:"loss"`                // Percentage of samples that failed
 }
 
 type Examiner struct {
@@ -65,16 +75,44 @@ type Examiner struct {
 
 	DoSpeedtest bool
 	DoIPInfo    bool
+	// SpeedTester is the backend speed tests run against.
+	SpeedTester SpeedTester
+	// Throughput sets the speed test duration, streams and ramp-up. The
+	// zero value transfers SpeedtestKbAmount once.
+	Throughput ThroughputOptions
+
+	// DoUDPTest probes UDP through the config with a DNS query to
+	// UDPResolver (ip:port) and, when UDPHTTP3URL is set, an HTTP/3
+	// request.
+	DoUDPTest   bool
+	UDPResolver string
+	UDPHTTP3URL string
 
 	TestEndpoint           string
 	TestEndpointHttpMethod string
 	SpeedtestKbAmount      uint64
 	Retries                uint8
 
+	// Samples, when above 1, sends that many requests through one core
+	// instance and records their latency stats.
+	Samples uint16
+
+	// Profile, when set, replaces TestEndpoint with its targets and pass
+	// policy.
+	Profile *Profile
+
 	// BindInterface pins outbound core dials to a specific OS interface.
 	// Empty disables binding.
 	BindInterface string
 
+	// Fragment enables TLS fragmentation/noises on the core. nil disables it.
+	Fragment *protocol.FragmentOptions
+	// Mux overrides the links' multiplexing settings. nil leaves it to the links.
+	Mux *protocol.MuxOptions
+	// CorePolicy picks xray or sing-box per link for the automatic core.
+	// nil uses the default rules.
+	CorePolicy *core.CorePolicy
+
 	Logger *log.Logger `json:"-"`
 }
 
@@ -91,11 +129,25 @@ type Options struct {
 	InsecureTLS            bool   `json:"insecureTLS"`
 	DoSpeedtest            bool   `json:"speedtest"`
 	DoIPInfo               bool   `json:"doIPInfo"`
+	DoUDPTest              bool   `json:"udpTest"`
+	UDPResolver            string `json:"udpResolver,omitempty"` // ip[:port], 1.1.1.1:53 by default
+	UDPHTTP3URL            string `json:"udpHttp3URL,omitempty"`
 	TestEndpoint           string `json:"destURL"`
 	TestEndpointHttpMethod string `json:"httpMethod"`
 	SpeedtestKbAmount      uint64 `json:"speedtestAmount"`
+	SpeedtestBackend       SpeedtestBackend `json:"speedtestBackend"`
+	SpeedtestDuration      uint16           `json:"speedtestDuration,omitempty"` // seconds; 0 transfers SpeedtestKbAmount
+	SpeedtestStreams       uint8            `json:"speedtestStreams,omitempty"`
+	SpeedtestRampUp        uint16           `json:"speedtestRampUp,omitempty"` // ms left out of the measurement
 	Retries                uint8  `json:"retries"`
+	Samples                uint16 `json:"samples,omitempty"`
 	BindInterface          string `json:"bindInterface,omitempty"`
+	Fragment               *protocol.FragmentOptions `json:"fragment,omitempty"`
+	Mux                    *protocol.MuxOptions      `json:"mux,omitempty"`
+	CorePolicy             *core.CorePolicy          `json:"corePolicy,omitempty"`
+	// Profile tests the configs against several targets instead of
+	// TestEndpoint. A profile with only a name selects a built-in one.
+	Profile                *Profile                  `json:"profile,omitempty"`
 	Logger                 *log.Logger `json:"-"`
 }
 
@@ -107,6 +159,8 @@ func NewExaminer(opts Options) (*Examiner, error) {
 		InsecureTLS:            opts.InsecureTLS,
 		DoSpeedtest:            opts.DoSpeedtest,
 		DoIPInfo:               opts.DoIPInfo,
+		DoUDPTest:              opts.DoUDPTest,
+		UDPHTTP3URL:            opts.UDPHTTP3URL,
 		TestEndpoint:           "https://cloudflare.com/cdn-cgi/trace",
 		TestEndpointHttpMethod: "GET",
 		MaxDelay:               5000,
@@ -135,7 +189,40 @@ func NewExaminer(opts Options) (*Examiner, error) {
 	}
 
 	e.Retries = opts.Retries
+	e.Samples = opts.Samples
 	e.BindInterface = opts.BindInterface
+	e.Fragment = opts.Fragment
+	e.Mux = opts.Mux
+	e.CorePolicy = opts.CorePolicy
+	if e.DoUDPTest {
+		resolver, err := normalizeResolver(opts.UDPResolver)
+		if err != nil {
+			return nil, fmt.Errorf("examiner: %w", err)
+		}
+		e.UDPResolver = resolver
+		if e.UDPHTTP3URL != "" {
+			if u, err := url.Parse(e.UDPHTTP3URL); err != nil || u.Scheme != "https" || u.Host == "" {
+				return nil, fmt.Errorf("examiner: invalid HTTP/3 URL %q", e.UDPHTTP3URL)
+			}
+		}
+	}
+	speedTester, err := NewSpeedTester(opts.SpeedtestBackend)
+	if err != nil {
+		return nil, fmt.Errorf("examiner: %w", err)
+	}
+	e.SpeedTester = speedTester
+	e.Throughput = ThroughputOptions{
+		Duration: time.Duration(opts.SpeedtestDuration) * time.Second,
+		Streams:  int(opts.SpeedtestStreams),
+		RampUp:   time.Duration(opts.SpeedtestRampUp) * time.Millisecond,
+	}
+	if opts.Profile != nil {
+		profile, err := ResolveProfile(opts.Profile)
+		if err != nil {
+			return nil, fmt.Errorf("examiner: %w", err)
+		}
+		e.Profile = profile
+	}
 	if e.BindInterface != "" {
 		if _, err := netbind.New(e.BindInterface); err != nil {
 			return nil, fmt.Errorf("examiner: %w", err)
@@ -153,6 +240,9 @@ func NewExaminer(opts Options) (*Examiner, error) {
 		InsecureTLS:   e.InsecureTLS,
 		Verbose:       e.Verbose,
 		BindInterface: e.BindInterface,
+		Fragment:      e.Fragment,
+		Mux:           e.Mux,
+		CorePolicy:    e.CorePolicy,
 	}
 	switch opts.Core {
 	case "xray":
@@ -236,16 +326,34 @@ func (e *Examiner) ExamineConfig(ctx context.Context, link string) (Result, erro
 		Port:     generalConfig.Port,
 	}
 	r.TLS = generalConfig.TLS
+	if generalConfig.ECH != "" {
+		r.TLS += "+ech"
+	}
 
-	client, instance, err := e.Core.MakeHttpClient(ctx, proto, time.Duration(e.Timeout)*time.Millisecond)
+	timeout := time.Duration(e.Timeout) * time.Millisecond
+	clientTimeout := timeout
+	if e.Profile != nil {
+		clientTimeout = e.Profile.maxTimeout(timeout)
+	}
+	client, instance, err := e.Core.MakeHttpClient(ctx, proto, clientTimeout)
 	if err != nil {
 		r.Status = "broken"
 		r.Reason = err.Error()
 		return r, err
 	}
 	defer instance.Close()
-
-	delayResult, err := MeasureDelayDetailed(ctx, client, e.TestEndpoint, e.TestEndpointHttpMethod)
+	r.Core = core.UsedCore(e.Core, instance)
+
+	endpoint, method := e.TestEndpoint, e.TestEndpointHttpMethod
+	var delayResult *MeasureDelayResult
+	if e.Profile != nil {
+		var target *Target
+		if delayResult, target, err = e.examineProfile(ctx, client, timeout, &r); err == nil {
+			endpoint, method = target.URL, target.Method
+		}
+	} else {
+		delayResult, err = MeasureDelayDetailed(ctx, client, e.TestEndpoint, e.TestEndpointHttpMethod)
+	}
 	if err != nil {
 		r.Status = "failed"
 		r.Reason = err.Error()
@@ -260,20 +368,31 @@ func (e *Examiner) ExamineConfig(ctx context.Context, link string) (Result, erro
 	r.ConnectTime = delayResult.ConnectTime
 	body := delayResult.Body
 
-	if r.Delay > int64(e.MaxDelay) {
+	delay := r.Delay
+	if e.Samples > 1 {
+		e.sampleDelays(ctx, client, endpoint, method, r.Delay, &r)
+		delay = r.DelayP50
+	}
+	if delay > int64(e.MaxDelay) {
 		r.Status = "timeout"
 		r.Reason = "config delay is more than the maximum allowed delay"
 		return r, errors.New(r.Reason)
 	}
 
+	if e.DoUDPTest {
+		e.probeUDP(ctx, func(ctx context.Context, destination string) (net.PacketConn, error) {
+			return core.ListenPacket(ctx, e.Core, instance, proto, destination)
+		}, &r)
+	}
+
 	if e.DoIPInfo {
 		// If the latency test URL was already the trace endpoint, use its body.
-		if strings.Contains(e.TestEndpoint, "/cdn-cgi/trace") {
+		if strings.Contains(endpoint, "/cdn-cgi/trace") {
 			parseTraceBody(body, &r)
 		} else {
 			// Otherwise, make a dedicated request for the IP info.
 			// Use a standard, reliable trace endpoint.
-			req, reqErr := http.NewRequestWithContext(ctx, "GET", "https://cloudflare.com/cdn-cgi/trace", nil)
+			req, reqErr := http.NewRequestWithContext(ctx, "GET", cloudflareTraceURL, nil)
 			if reqErr != nil {
 				if r.Reason != "" {
 					r.Reason += "; "
@@ -296,25 +415,44 @@ func (e *Examiner) ExamineConfig(ctx context.Context, link string) (Result, erro
 	}
 
 	if e.DoSpeedtest {
-		downloadStartTime := time.Now()
-		_, _, bytesRead, dlErr := CoreHTTPRequestCustom(ctx, client, 20*time.Second, speedtest.MakeDownloadHTTPRequest(false, e.SpeedtestKbAmount*1000))
-		if dlErr == nil {
-			downloadTime := time.Since(downloadStartTime).Milliseconds()
-			// Use actual bytes received for accurate speed calculation
-			r.DownloadSpeed = (float32(bytesRead*8) / (float32(downloadTime) / float32(1000.0))) / float32(1000000.0)
-		}
+		r.DownloadSpeed = e.measureSpeed(ctx, client, MeasureDownload)
+		r.UploadSpeed = e.measureSpeed(ctx, client, MeasureUpload)
+	}
+
+	return r, nil
+}
 
-		uploadStartTime := time.Now()
-		byteAmount := e.SpeedtestKbAmount * 1000
-		_, _, _, ulErr := CoreHTTPRequestCustom(ctx, client, 20*time.Second, speedtest.MakeUploadHTTPRequest(false, byteAmount))
-		if ulErr == nil {
-			uploadTime := time.Since(uploadStartTime).Milliseconds()
-			// For upload, use intended byte amount (request body is locally generated)
-			r.UploadSpeed = (float32(byteAmount*8) / (float32(uploadTime) / float32(1000.0))) / float32(1000000.0)
+// examineProfile checks every target of the profile and applies its pass
+// policy. The first target that passed provides the measurement, and the
+// target the rest of the examination uses.
+func (e *Examiner) examineProfile(ctx context.Context, client *http.Client, timeout time.Duration, r *Result) (*MeasureDelayResult, *Target, error) {
+	checks := e.Profile.checkTargets(ctx, client, timeout)
+
+	primary := -1
+	var failures []string
+	passed := 0
+	r.Targets = make([]TargetResult, 0, len(checks))
+	for i := range checks {
+		c := &checks[i]
+		r.Targets = append(r.Targets, c.TargetResult)
+		if !c.Passed {
+			failures = append(failures, fmt.Sprintf("%s: %s", c.Name, c.Reason))
+			continue
+		}
+		passed++
+		if primary < 0 {
+			primary = i
 		}
 	}
 
-	return r, nil
+	if passed < e.Profile.Required() {
+		return nil, nil, fmt.Errorf("%d/%d targets of profile %q passed, %d required (%s)",
+			passed, len(checks), e.Profile.Name, e.Profile.Required(), strings.Join(failures, "; "))
+	}
+	if len(failures) > 0 {
+		r.Reason = strings.Join(failures, "; ")
+	}
+	return checks[primary].measure, &e.Profile.Targets[primary], nil
 }
 
 // ExamineConfigWithRetries runs ExamineConfig up to 1+Retries times, keeping the best result.
@@ -330,7 +468,7 @@ func (e *Examiner) ExamineConfigWithRetries(ctx context.Context, link string) (R
 		}
 		res, retryErr := e.ExamineConfig(ctx, link)
 		// Keep the best result: prefer passed, then lowest delay
-		if res.Status == "passed" && (best.Status != "passed" || (res.Delay >= 0 && res.Delay < best.Delay)) {
+		if res.Status == "passed" && (best.Status != "passed" || (res.RankDelay() >= 0 && res.RankDelay() < best.RankDelay())) {
 			best = res
 			err = retryErr
 		}
@@ -440,72 +578,3 @@ func CoreHTTPRequestCustom(ctx context.Context, client *http.Client, timeout tim
 	b, _ := io.ReadAll(resp.Body)
 	return resp.StatusCode, b, int64(len(b)), nil
 }
-
-type SpeedTester struct {
-	SNI              string
-	DownloadEndpoint string
-	UploadEndpoint   string
-	DebugEndpoint    string
-}
-
-var speedtest = &SpeedTester{
-	SNI:              "speed.cloudflare.com",
-	DebugEndpoint:    "/cdn-cgi/trace",
-	DownloadEndpoint: "/__down",
-	UploadEndpoint:   "/__up",
-}
-
-func (c *SpeedTester) MakeDownloadHTTPRequest(noTLS bool, amount uint64) *http.Request {
-	scheme := "https"
-	if noTLS {
-		scheme = "http"
-	}
-	return &http.Request{
-		Method: "GET",
-		URL: &url.URL{
-			Path:     c.DownloadEndpoint,
-			RawQuery: fmt.Sprintf("bytes=%d", amount),
-			Scheme:   scheme,
-			Host:     c.SNI,
-		},
-		Header: make(http.Header),
-		Host:   c.SNI,
-	}
-}
-
-func (c *SpeedTester) MakeUploadHTTPRequest(noTLS bool, amount uint64) *http.Request {
-	scheme := "https"
-	if noTLS {
-		scheme = "http"
-	}
-	// Use io.LimitReader to avoid allocating a massive string for the body.
-	// This is more memory-efficient and avoids int overflow on 32-bit systems.
-	bodyReader := io.LimitReader(zeroReader{}, int64(amount))
-	req := &http.Request{
-		Method: "POST",
-		URL: &url.URL{
-			Path:   c.UploadEndpoint,
-			Scheme: scheme,
-			Host:   c.SNI,
-		},
-		Header:        make(http.Header),
-		Host:          c.SNI,
-		Body:          io.NopCloser(bodyReader),
-		ContentLength: int64(amount),
-	}
-	req.Header.Set("Content-Type", "application/octet-stream")
-	return req
-}
-
-func (c *SpeedTester) MakeDebugRequest() *http.Request {
-	return &http.Request{
-		Method: "GET",
-		URL: &url.URL{
-			Path:   c.DebugEndpoint,
-			Scheme: "https",
-			Host:   c.SNI,
-		},
-		Header: make(http.Header),
-		Host:   c.SNI,
-	}
-}
@@ -15,6 +15,8 @@ import (
 	"github.com/alitto/pond/v2"
 	"github.com/gocarina/gocsv"
 	"github.com/lilendian0x00/xray-knife/v10/database"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/export"
 	"github.com/lilendian0x00/xray-knife/v10/utils"
 	"github.com/lilendian0x00/xray-knife/v10/utils/customlog"
 )
@@ -57,7 +59,7 @@ func NewResultProcessor(opts ResultProcessorOptions) *ResultProcessor {
 func (cr ConfigResults) Len() int { return len(cr) }
 func (cr ConfigResults) Less(i, j int) bool {
 	// delay=-1 means failed; treat as infinity so they sort to the end
-	di, dj := cr[i].Delay, cr[j].Delay
+	di, dj := cr[i].RankDelay(), cr[j].RankDelay()
 	if di < 0 {
 		di = math.MaxInt64
 	}
@@ -144,26 +146,7 @@ func (rp *ResultProcessor) SaveResults(results ConfigResults) error {
 	if rp.runID > 0 {
 		dbResults := make([]database.HttpTestResult, 0, len(results))
 		for _, res := range results {
-			dbRes := database.HttpTestResult{
-				RunID:        rp.runID,
-				ConfigLink:   res.ConfigLink,
-				Status:       res.Status,
-				Reason:       sql.NullString{String: res.Reason, Valid: res.Reason != ""},
-				DelayMs:      -1, // Default for non-passed tests
-				DownloadMbps: 0,
-				UploadMbps:   0,
-			}
-
-			if res.Status == "passed" || res.Status == "semi-passed" {
-				dbRes.DelayMs = res.Delay
-				dbRes.DownloadMbps = float64(res.DownloadSpeed)
-				dbRes.UploadMbps = float64(res.UploadSpeed)
-				dbRes.IPAddress = sql.NullString{String: res.RealIPAddr, Valid: res.RealIPAddr != "" && res.RealIPAddr != "null"}
-				dbRes.IPLocation = sql.NullString{String: res.IpAddrLoc, Valid: res.IpAddrLoc != "" && res.IpAddrLoc != "null"}
-				dbRes.TTFBMs = res.TTFB
-				dbRes.ConnectTimeMs = res.ConnectTime
-			}
-			dbResults = append(dbResults, dbRes)
+			dbResults = append(dbResults, newDBResult(rp.runID, res))
 		}
 
 		if len(dbResults) > 0 {
@@ -183,6 +166,38 @@ func (rp *ResultProcessor) SaveResults(results ConfigResults) error {
 	return nil
 }
 
+// newDBResult converts a result to its http_test_results row.
+func newDBResult(runID int64, res *Result) database.HttpTestResult {
+	dbRes := database.HttpTestResult{
+		RunID:        runID,
+		ConfigLink:   res.ConfigLink,
+		Status:       res.Status,
+		Reason:       sql.NullString{String: res.Reason, Valid: res.Reason != ""},
+		Core:         sql.NullString{String: res.Core, Valid: res.Core != ""},
+		UDP:          sql.NullString{String: res.UDP, Valid: res.UDP != ""},
+		DelayMs:      -1, // Default for non-passed tests
+		DownloadMbps: 0,
+		UploadMbps:   0,
+	}
+
+	if res.Status == "passed" || res.Status == "semi-passed" {
+		dbRes.DelayMs = res.Delay
+		dbRes.DownloadMbps = float64(res.DownloadSpeed)
+		dbRes.UploadMbps = float64(res.UploadSpeed)
+		dbRes.IPAddress = sql.NullString{String: res.RealIPAddr, Valid: res.RealIPAddr != "" && res.RealIPAddr != "null"}
+		dbRes.IPLocation = sql.NullString{String: res.IpAddrLoc, Valid: res.IpAddrLoc != "" && res.IpAddrLoc != "null"}
+		dbRes.TTFBMs = res.TTFB
+		dbRes.ConnectTimeMs = res.ConnectTime
+		dbRes.Samples = res.Samples
+		dbRes.DelayMinMs = res.DelayMin
+		dbRes.DelayP50Ms = res.DelayP50
+		dbRes.DelayP95Ms = res.DelayP95
+		dbRes.JitterMs = res.Jitter
+		dbRes.LossPct = float64(res.Loss)
+	}
+	return dbRes
+}
+
 // RewriteFileSorted overwrites the output file with results sorted by delay.
 func (rp *ResultProcessor) RewriteFileSorted(results ConfigResults) {
 	if rp.outputFile == "" {
@@ -197,7 +212,21 @@ func (rp *ResultProcessor) RewriteFileSorted(results ConfigResults) {
 		rp.saveCSVResults(sorted)
 	case "txt":
 		rp.saveTxtResults(sorted)
+	case string(export.FormatXray), string(export.FormatSingbox), string(export.FormatClash):
+		if err := rp.saveExportResults(export.Format(rp.outputType), sorted); err != nil {
+			customlog.Printf(customlog.Failure, "%v\n", err)
+		}
+	}
+}
+
+// IsExportOutputType reports whether outputType is a client config format
+// that can only be written once all results are in.
+func IsExportOutputType(outputType string) bool {
+	switch outputType {
+	case string(export.FormatXray), string(export.FormatSingbox), string(export.FormatClash):
+		return true
 	}
+	return false
 }
 
 func (rp *ResultProcessor) saveTxtResults(results ConfigResults) error {
@@ -218,6 +247,31 @@ func (rp *ResultProcessor) saveTxtResults(results ConfigResults) error {
 	return nil
 }
 
+// saveExportResults writes the passed configs as a ready-to-use client config.
+func (rp *ResultProcessor) saveExportResults(format export.Format, results ConfigResults) error {
+	var validConfigs []string
+	for _, v := range results {
+		if v.Status == "passed" {
+			validConfigs = append(validConfigs, v.ConfigLink)
+		}
+	}
+	if len(validConfigs) == 0 {
+		return nil
+	}
+
+	out, err := export.Build(format, validConfigs)
+	if err != nil {
+		return fmt.Errorf("failed to build %s config: %w", format, err)
+	}
+	if err := utils.WriteIntoFile(rp.outputFile, out); err != nil {
+		return fmt.Errorf("failed to save %s config: %w", format, err)
+	}
+
+	customlog.Printf(customlog.Finished, "%d working configurations have been exported as a %s config to %s\n",
+		len(validConfigs), format, rp.outputFile)
+	return nil
+}
+
 func (rp *ResultProcessor) saveCSVResults(results ConfigResults) error {
 	out, err := gocsv.MarshalString(&results)
 	if err != nil {
@@ -234,6 +288,8 @@ func (rp *ResultProcessor) saveCSVResults(results ConfigResults) error {
 }
 
 // DeduplicateLinks strips duplicates from links, returning the unique list and how many were removed.
+// Links pointing at the same server (same fingerprint) count as duplicates even if their remarks or
+// parameter order differ; the first one is kept. Links that can't be parsed are compared verbatim.
 func DeduplicateLinks(links []string) ([]string, int) {
 	seen := make(map[string]struct{}, len(links))
 	unique := make([]string, 0, len(links))
@@ -242,8 +298,12 @@ func DeduplicateLinks(links []string) ([]string, int) {
 		if trimmed == "" {
 			continue
 		}
-		if _, exists := seen[trimmed]; !exists {
-			seen[trimmed] = struct{}{}
+		key, err := core.LinkFingerprint(trimmed)
+		if err != nil {
+			key = trimmed
+		}
+		if _, exists := seen[key]; !exists {
+			seen[key] = struct{}{}
 			unique = append(unique, trimmed)
 		}
 	}
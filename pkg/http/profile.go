@@ -0,0 +1,304 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Target is one URL a test profile requests through the config.
+type Target struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Method string `json:"method,omitempty"` // GET when empty
+	// Status is the expected HTTP status code. 0 accepts any status
+	// below 400.
+	Status int `json:"status,omitempty"`
+	// BodyRegex, when set, must match the response body.
+	BodyRegex string `json:"bodyRegex,omitempty"`
+	// Timeout of the request (in ms). 0 uses the examiner's timeout.
+	Timeout uint16 `json:"timeout,omitempty"`
+
+	bodyRe *regexp.Regexp
+}
+
+// Profile is a named set of targets a config is tested against. A config
+// passes when at least as many targets pass as its policy requires.
+type Profile struct {
+	Name    string   `json:"name"`
+	Targets []Target `json:"targets"`
+	// Policy is "all" (the default), "any" or the minimum number of
+	// targets that must pass, e.g. "2".
+	Policy string `json:"policy,omitempty"`
+
+	required int
+}
+
+// TargetResult is the outcome of a single profile target.
+type TargetResult struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Passed bool   `json:"passed"`
+	Code   int    `json:"code"`
+	Delay  int64  `json:"delay"` // millisecond, -1 when the request failed
+	Reason string `json:"reason,omitempty"`
+}
+
+const cloudflareTraceURL = "https://cloudflare.com/cdn-cgi/trace"
+
+var builtinProfiles = []Profile{
+	{
+		Name: "default",
+		Targets: []Target{
+			{Name: "cloudflare", URL: cloudflareTraceURL, BodyRegex: `(?m)^ip=`},
+		},
+	},
+	{
+		Name: "google",
+		Targets: []Target{
+			{Name: "google", URL: "https://www.google.com/generate_204", Status: http.StatusNoContent},
+		},
+	},
+	{
+		Name:   "essentials",
+		Policy: "all",
+		Targets: []Target{
+			{Name: "cloudflare", URL: cloudflareTraceURL, BodyRegex: `(?m)^ip=`},
+			{Name: "youtube", URL: "https://www.youtube.com/generate_204", Status: http.StatusNoContent},
+			{Name: "telegram", URL: "https://telegram.org/"},
+			{Name: "github", URL: "https://github.com/", Status: http.StatusOK},
+		},
+	},
+}
+
+// BuiltinProfiles returns the profiles shipped with xray-knife.
+func BuiltinProfiles() []Profile {
+	profiles := make([]Profile, 0, len(builtinProfiles))
+	for _, p := range builtinProfiles {
+		profiles = append(profiles, p.clone())
+	}
+	return profiles
+}
+
+// BuiltinProfileNames returns the names of the built-in profiles.
+func BuiltinProfileNames() []string {
+	names := make([]string, 0, len(builtinProfiles))
+	for _, p := range builtinProfiles {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// LookupProfile returns a validated copy of the named built-in profile.
+func LookupProfile(name string) (*Profile, error) {
+	for _, p := range builtinProfiles {
+		if strings.EqualFold(p.Name, name) {
+			cp := p.clone()
+			if err := cp.Validate(); err != nil {
+				return nil, err
+			}
+			return &cp, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown test profile %q (built-in: %s)", name, strings.Join(BuiltinProfileNames(), ", "))
+}
+
+// LoadProfile returns the test profile named by arg: a built-in profile
+// name or a JSON file holding a profile. An empty arg returns nil.
+func LoadProfile(arg string) (*Profile, error) {
+	arg = strings.TrimSpace(arg)
+	if arg == "" {
+		return nil, nil
+	}
+	if _, err := os.Stat(arg); err != nil {
+		return LookupProfile(arg)
+	}
+	body, err := os.ReadFile(arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test profile file: %w", err)
+	}
+	p := &Profile{}
+	if err := json.Unmarshal(body, p); err != nil {
+		return nil, fmt.Errorf("invalid test profile %q: %w", arg, err)
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(arg), filepath.Ext(arg))
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid test profile %q: %w", arg, err)
+	}
+	return p, nil
+}
+
+// ResolveProfile returns a validated copy of p. A profile with a name but
+// no targets refers to a built-in profile.
+func ResolveProfile(p *Profile) (*Profile, error) {
+	if len(p.Targets) == 0 && p.Name != "" {
+		return LookupProfile(p.Name)
+	}
+	cp := p.clone()
+	if err := cp.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid test profile %q: %w", cp.Name, err)
+	}
+	return &cp, nil
+}
+
+// Validate fills in the defaults of the profile and its targets and
+// checks they are usable.
+func (p *Profile) Validate() error {
+	if len(p.Targets) == 0 {
+		return fmt.Errorf("profile has no targets")
+	}
+	if p.Name == "" {
+		p.Name = "custom"
+	}
+	for i := range p.Targets {
+		if err := p.Targets[i].validate(); err != nil {
+			return err
+		}
+	}
+	required, err := parsePassPolicy(p.Policy, len(p.Targets))
+	if err != nil {
+		return err
+	}
+	p.required = required
+	return nil
+}
+
+// Required returns how many targets must pass for a config to pass.
+func (p *Profile) Required() int {
+	return p.required
+}
+
+func (p *Profile) String() string {
+	policy := p.Policy
+	if policy == "" {
+		policy = "all"
+	}
+	names := make([]string, 0, len(p.Targets))
+	for _, t := range p.Targets {
+		names = append(names, t.Name)
+	}
+	return fmt.Sprintf("%s (%s, policy %s)", p.Name, strings.Join(names, ", "), policy)
+}
+
+func (p Profile) clone() Profile {
+	p.Targets = append([]Target(nil), p.Targets...)
+	return p
+}
+
+// parsePassPolicy returns the number of the n targets that must pass.
+func parsePassPolicy(policy string, n int) (int, error) {
+	switch s := strings.ToLower(strings.TrimSpace(policy)); s {
+	case "", "all":
+		return n, nil
+	case "any":
+		return 1, nil
+	default:
+		required, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid pass policy %q (want all, any or a number)", policy)
+		}
+		if required < 1 || required > n {
+			return 0, fmt.Errorf("pass policy %d is out of range for %d targets", required, n)
+		}
+		return required, nil
+	}
+}
+
+func (t *Target) validate() error {
+	u, err := url.Parse(t.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("target %q: invalid url %q", t.Name, t.URL)
+	}
+	if t.Name == "" {
+		t.Name = u.Hostname()
+	}
+	t.Method = strings.ToUpper(strings.TrimSpace(t.Method))
+	if t.Method == "" {
+		t.Method = http.MethodGet
+	}
+	if t.BodyRegex != "" {
+		if t.bodyRe, err = regexp.Compile(t.BodyRegex); err != nil {
+			return fmt.Errorf("target %q: invalid body regex: %w", t.Name, err)
+		}
+	}
+	return nil
+}
+
+// check verifies a response of the target, returning why it doesn't pass.
+func (t *Target) check(res *MeasureDelayResult) string {
+	if t.Status != 0 {
+		if res.Code != t.Status {
+			return fmt.Sprintf("status %d, want %d", res.Code, t.Status)
+		}
+	} else if res.Code >= 400 {
+		return fmt.Sprintf("status %d", res.Code)
+	}
+	if t.bodyRe != nil && !t.bodyRe.Match(res.Body) {
+		return "body does not match"
+	}
+	return ""
+}
+
+// maxTimeout returns the longest target timeout, using def for targets
+// without their own.
+func (p *Profile) maxTimeout(def time.Duration) time.Duration {
+	longest := def
+	for _, t := range p.Targets {
+		if d := time.Duration(t.Timeout) * time.Millisecond; d > longest {
+			longest = d
+		}
+	}
+	return longest
+}
+
+// targetCheck is a TargetResult along with the measurement behind it.
+type targetCheck struct {
+	TargetResult
+	measure *MeasureDelayResult
+}
+
+// checkTargets requests every target of the profile concurrently through
+// client. def is the timeout of targets without their own.
+func (p *Profile) checkTargets(ctx context.Context, client *http.Client, def time.Duration) []targetCheck {
+	checks := make([]targetCheck, len(p.Targets))
+	var wg sync.WaitGroup
+	for i := range p.Targets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			t := &p.Targets[i]
+			timeout := def
+			if t.Timeout != 0 {
+				timeout = time.Duration(t.Timeout) * time.Millisecond
+			}
+			tctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			c := targetCheck{TargetResult: TargetResult{Name: t.Name, URL: t.URL, Code: -1, Delay: FailedDelay}}
+			res, err := MeasureDelayDetailed(tctx, client, t.URL, t.Method)
+			if err != nil {
+				c.Reason = err.Error()
+			} else {
+				c.measure = res
+				c.Code = res.Code
+				c.Delay = res.Delay
+				c.Reason = t.check(res)
+				c.Passed = c.Reason == ""
+			}
+			checks[i] = c
+		}(i)
+	}
+	wg.Wait()
+	return checks
+}
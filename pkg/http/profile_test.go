@@ -0,0 +1,126 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuiltinProfilesAreValid(t *testing.T) {
+	for _, name := range BuiltinProfileNames() {
+		p, err := LookupProfile(name)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if p.Required() != len(p.Targets) {
+			t.Errorf("%s: requires %d of %d targets", name, p.Required(), len(p.Targets))
+		}
+	}
+	if _, err := LookupProfile("nope"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+}
+
+func TestParsePassPolicy(t *testing.T) {
+	tests := []struct {
+		policy string
+		want   int
+		ok     bool
+	}{
+		{"", 3, true},
+		{"all", 3, true},
+		{"ANY", 1, true},
+		{"2", 2, true},
+		{"0", 0, false},
+		{"4", 0, false},
+		{"most", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parsePassPolicy(tt.policy, 3)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parsePassPolicy(%q) = %d, %v; want %d (ok %t)", tt.policy, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLoadProfileFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocked.json")
+	body := `{"policy":"any","targets":[{"url":"https://youtube.com/","status":200},{"name":"tg","url":"https://t.me/","method":"head"}]}`
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "blocked" || p.Required() != 1 {
+		t.Errorf("got name %q requiring %d targets", p.Name, p.Required())
+	}
+	if p.Targets[0].Name != "youtube.com" || p.Targets[0].Method != http.MethodGet || p.Targets[1].Method != http.MethodHead {
+		t.Errorf("defaults not applied: %+v", p.Targets)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"targets":[{"url":"ftp://example.com"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadProfile(path); err == nil {
+		t.Error("expected an error for a non-HTTP target")
+	}
+}
+
+func TestResolveProfileByName(t *testing.T) {
+	p, err := ResolveProfile(&Profile{Name: "essentials"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Targets) == 0 {
+		t.Error("built-in profile not resolved")
+	}
+}
+
+func TestCheckTargets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/204":
+			w.WriteHeader(http.StatusNoContent)
+		case "/trace":
+			fmt.Fprint(w, "h=example.com\nip=203.0.113.7\nloc=DE\n")
+		case "/slow":
+			time.Sleep(500 * time.Millisecond)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	p := &Profile{
+		Name:   "local",
+		Policy: "2",
+		Targets: []Target{
+			{Name: "204", URL: srv.URL + "/204", Status: http.StatusNoContent},
+			{Name: "trace", URL: srv.URL + "/trace", BodyRegex: `(?m)^ip=`},
+			{Name: "wrong-body", URL: srv.URL + "/trace", BodyRegex: `^warp=on`},
+			{Name: "missing", URL: srv.URL + "/missing"},
+			{Name: "slow", URL: srv.URL + "/slow", Timeout: 50},
+		},
+	}
+	if err := p.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := p.checkTargets(context.Background(), srv.Client(), time.Second)
+	want := map[string]bool{"204": true, "trace": true, "wrong-body": false, "missing": false, "slow": false}
+	for _, c := range checks {
+		if c.Passed != want[c.Name] {
+			t.Errorf("%s: passed = %t (%s), want %t", c.Name, c.Passed, c.Reason, want[c.Name])
+		}
+	}
+	if checks[4].Delay != FailedDelay {
+		t.Errorf("timed out target has delay %d", checks[4].Delay)
+	}
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// sampleDelays sends the rest of the Samples requests to dest through
+// client, after the first one that took first ms, and records the latency
+// stats in r.
+func (e *Examiner) sampleDelays(ctx context.Context, client *http.Client, dest, method string, first int64, r *Result) {
+	delays := []int64{first}
+	lost := 0
+	for i := 1; i < int(e.Samples); i++ {
+		if ctx.Err() != nil {
+			break
+		}
+		res, err := MeasureDelayDetailed(ctx, client, dest, method)
+		if err != nil {
+			lost++
+			continue
+		}
+		delays = append(delays, res.Delay)
+	}
+	applyLatencyStats(r, delays, lost)
+}
+
+// applyLatencyStats records the stats of the delays (ms, in the order they
+// were measured) of the requests that got through, and of lost ones that
+// didn't, in r.
+func applyLatencyStats(r *Result, delays []int64, lost int) {
+	r.Samples = len(delays) + lost
+	if r.Samples == 0 {
+		return
+	}
+	r.Loss = float32(lost) * 100 / float32(r.Samples)
+	if len(delays) == 0 {
+		return
+	}
+
+	var diffs int64
+	for i := 1; i < len(delays); i++ {
+		d := delays[i] - delays[i-1]
+		if d < 0 {
+			d = -d
+		}
+		diffs += d
+	}
+	if len(delays) > 1 {
+		r.Jitter = diffs / int64(len(delays)-1)
+	}
+
+	sorted := append([]int64(nil), delays...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	r.DelayMin = sorted[0]
+	r.DelayP50 = percentile(sorted, 50)
+	r.DelayP95 = percentile(sorted, 95)
+}
+
+// percentile returns the nearest-rank p-th percentile of sorted.
+func percentile(sorted []int64, p float64) int64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// RankDelay is the delay (ms) results are ranked by, -1 for failed ones.
+// It is Delay for a single sample. With several, it is the median plus
+// the jitter, divided by the share of samples that got through: a config
+// that is fast once but loses half its requests ranks behind a steady one.
+func (r *Result) RankDelay() int64 {
+	if r.Delay < 0 {
+		return FailedDelay
+	}
+	if r.Samples <= 1 {
+		return r.Delay
+	}
+	delivered := 1 - float64(r.Loss)/100
+	if delivered <= 0 {
+		return FailedDelay
+	}
+	return int64(float64(r.DelayP50+r.Jitter) / delivered)
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestApplyLatencyStats(t *testing.T) {
+	var r Result
+	applyLatencyStats(&r, []int64{100, 120, 90, 300, 110, 100, 95, 105, 130}, 1)
+
+	if r.Samples != 10 || r.Loss != 10 {
+		t.Errorf("samples %d, loss %v; want 10, 10", r.Samples, r.Loss)
+	}
+	if r.DelayMin != 90 || r.DelayP50 != 105 || r.DelayP95 != 300 {
+		t.Errorf("min %d, p50 %d, p95 %d; want 90, 105, 300", r.DelayMin, r.DelayP50, r.DelayP95)
+	}
+	// |20|+|30|+|210|+|190|+|10|+|5|+|10|+|25| over 8 differences
+	if r.Jitter != 62 {
+		t.Errorf("jitter %d, want 62", r.Jitter)
+	}
+
+	r = Result{}
+	applyLatencyStats(&r, nil, 3)
+	if r.Samples != 3 || r.Loss != 100 || r.DelayP50 != 0 {
+		t.Errorf("all lost: %+v", r)
+	}
+}
+
+func TestRankDelayPrefersStableConfigs(t *testing.T) {
+	lucky := Result{ConfigLink: "lucky", Status: "passed", Delay: 50}
+	applyLatencyStats(&lucky, []int64{50, 60, 55, 70, 65}, 5)
+	steady := Result{ConfigLink: "steady", Status: "passed", Delay: 120}
+	applyLatencyStats(&steady, []int64{120, 125, 118, 122, 130, 119, 121, 124, 126, 120}, 0)
+	single := Result{ConfigLink: "single", Status: "passed", Delay: 130}
+	failed := Result{ConfigLink: "failed", Status: "failed", Delay: FailedDelay}
+
+	results := ConfigResults{&failed, &lucky, &single, &steady}
+	sort.Sort(results)
+	var order []string
+	for _, r := range results {
+		order = append(order, r.ConfigLink)
+	}
+	want := []string{"steady", "single", "lucky", "failed"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order %v, want %v", order, want)
+		}
+	}
+}
+
+func TestSampleDelays(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if requests.Add(1)%2 == 0 {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	e := &Examiner{Samples: 4}
+	client := &http.Client{Timeout: 100 * time.Millisecond}
+	var r Result
+	e.sampleDelays(context.Background(), client, srv.URL, http.MethodGet, 10, &r)
+
+	if n := requests.Load(); n != 3 {
+		t.Errorf("%d requests, want 3", n)
+	}
+	if r.Samples != 4 || r.Loss != 25 {
+		t.Errorf("samples %d, loss %v; want 4, 25", r.Samples, r.Loss)
+	}
+}
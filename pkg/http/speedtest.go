@@ -0,0 +1,451 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Speed test backend types, selected by SpeedtestBackend.Type.
+const (
+	SpeedtestCloudflare = "cloudflare"
+	SpeedtestURL        = "url"
+	SpeedtestLibreSpeed = "librespeed"
+)
+
+const (
+	defaultCloudflareSpeedHost = "speed.cloudflare.com"
+	// speedtestUserAgent is sent with speed test requests: Cloudflare
+	// rejects some of them without a browser one.
+	speedtestUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36"
+)
+
+// ErrNoUploadEndpoint is returned for uploads to a backend without one.
+var ErrNoUploadEndpoint = errors.New("speed test backend has no upload endpoint")
+
+// SpeedTester builds the requests a speed test sends through a config.
+type SpeedTester interface {
+	// DownloadRequest returns a request for a body of at least bytes.
+	// Only that many are read.
+	DownloadRequest(ctx context.Context, bytes int64) (*http.Request, error)
+	// UploadRequest returns a request sending body, bytes long.
+	UploadRequest(ctx context.Context, body io.Reader, bytes int64) (*http.Request, error)
+	String() string
+}
+
+// SpeedtestBackend selects the speed test backend. The zero value is
+// speed.cloudflare.com.
+type SpeedtestBackend struct {
+	// Type is cloudflare (the default), url or librespeed.
+	Type string `json:"type,omitempty"`
+	// Server is the host or base URL of a Cloudflare-compatible server,
+	// like speedtest-server, or the URL of a LibreSpeed server.
+	Server string `json:"server,omitempty"`
+	// DownloadURL and UploadURL are requested by the url backend. Without
+	// an UploadURL, only downloads are measured.
+	DownloadURL string `json:"downloadUrl,omitempty"`
+	UploadURL   string `json:"uploadUrl,omitempty"`
+}
+
+// ParseSpeedtestBackend parses a --speedtest-backend value: cloudflare,
+// cloudflare:<host or url>, librespeed:<url> or url:<download>[,<upload>].
+func ParseSpeedtestBackend(spec string) (SpeedtestBackend, error) {
+	spec = strings.TrimSpace(spec)
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch strings.ToLower(kind) {
+	case "", SpeedtestCloudflare:
+		return SpeedtestBackend{Type: SpeedtestCloudflare, Server: arg}, nil
+	case SpeedtestLibreSpeed:
+		return SpeedtestBackend{Type: SpeedtestLibreSpeed, Server: arg}, nil
+	case SpeedtestURL:
+		down, up, _ := strings.Cut(arg, ",")
+		return SpeedtestBackend{Type: SpeedtestURL, DownloadURL: strings.TrimSpace(down), UploadURL: strings.TrimSpace(up)}, nil
+	}
+	return SpeedtestBackend{}, fmt.Errorf("invalid speed test backend %q (want cloudflare[:server], librespeed:<url> or url:<download>[,<upload>])", spec)
+}
+
+// NewSpeedTester returns the SpeedTester of the backend.
+func NewSpeedTester(b SpeedtestBackend) (SpeedTester, error) {
+	switch strings.ToLower(b.Type) {
+	case "", SpeedtestCloudflare:
+		base, err := speedtestBaseURL(b.Server, defaultCloudflareSpeedHost)
+		if err != nil {
+			return nil, err
+		}
+		return &CloudflareSpeedTester{BaseURL: base}, nil
+	case SpeedtestLibreSpeed:
+		if b.Server == "" {
+			return nil, fmt.Errorf("librespeed speed test needs a server URL")
+		}
+		base, err := speedtestBaseURL(b.Server, "")
+		if err != nil {
+			return nil, err
+		}
+		return &LibreSpeedTester{BaseURL: base}, nil
+	case SpeedtestURL:
+		if err := checkSpeedtestURL(b.DownloadURL); err != nil {
+			return nil, fmt.Errorf("download url: %w", err)
+		}
+		if b.UploadURL != "" {
+			if err := checkSpeedtestURL(b.UploadURL); err != nil {
+				return nil, fmt.Errorf("upload url: %w", err)
+			}
+		}
+		return &URLSpeedTester{DownloadURL: b.DownloadURL, UploadURL: b.UploadURL}, nil
+	}
+	return nil, fmt.Errorf("unknown speed test backend %q (want cloudflare, url or librespeed)", b.Type)
+}
+
+// speedtestBaseURL returns server, a host or URL, as a URL without a
+// trailing slash. Hosts get https.
+func speedtestBaseURL(server, def string) (string, error) {
+	if server == "" {
+		server = def
+	}
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	if err := checkSpeedtestURL(server); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(server, "/"), nil
+}
+
+func checkSpeedtestURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid speed test url %q", raw)
+	}
+	return nil
+}
+
+// CloudflareSpeedTester uses the /__down and /__up endpoints of
+// speed.cloudflare.com, or of a server compatible with it.
+type CloudflareSpeedTester struct {
+	BaseURL string
+}
+
+func (c *CloudflareSpeedTester) DownloadRequest(ctx context.Context, bytes int64) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/__down?bytes=%d", c.BaseURL, bytes), nil)
+}
+
+func (c *CloudflareSpeedTester) UploadRequest(ctx context.Context, body io.Reader, bytes int64) (*http.Request, error) {
+	return newUploadRequest(ctx, c.BaseURL+"/__up", body, bytes)
+}
+
+func (c *CloudflareSpeedTester) String() string {
+	return "cloudflare (" + c.BaseURL + ")"
+}
+
+// URLSpeedTester downloads a fixed URL, e.g. a large file, and posts
+// uploads to another.
+type URLSpeedTester struct {
+	DownloadURL string
+	UploadURL   string
+}
+
+func (u *URLSpeedTester) DownloadRequest(ctx context.Context, bytes int64) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, http.MethodGet, u.DownloadURL, nil)
+}
+
+func (u *URLSpeedTester) UploadRequest(ctx context.Context, body io.Reader, bytes int64) (*http.Request, error) {
+	if u.UploadURL == "" {
+		return nil, ErrNoUploadEndpoint
+	}
+	return newUploadRequest(ctx, u.UploadURL, body, bytes)
+}
+
+func (u *URLSpeedTester) String() string {
+	if u.UploadURL == "" {
+		return "url (" + u.DownloadURL + ")"
+	}
+	return "url (" + u.DownloadURL + ", " + u.UploadURL + ")"
+}
+
+// LibreSpeedTester uses the garbage and empty endpoints of a LibreSpeed
+// server (the PHP backend or speedtest-go).
+type LibreSpeedTester struct {
+	BaseURL string
+}
+
+// libreSpeedChunk is the size of the chunks garbage.php sends.
+const libreSpeedChunk = 1 << 20
+
+func (l *LibreSpeedTester) backend() string {
+	if strings.HasSuffix(l.BaseURL, "/backend") {
+		return l.BaseURL
+	}
+	return l.BaseURL + "/backend"
+}
+
+func (l *LibreSpeedTester) DownloadRequest(ctx context.Context, bytes int64) (*http.Request, error) {
+	chunks := (bytes + libreSpeedChunk - 1) / libreSpeedChunk
+	if chunks < 1 {
+		chunks = 1
+	}
+	return http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/garbage.php?ckSize=%d", l.backend(), chunks), nil)
+}
+
+func (l *LibreSpeedTester) UploadRequest(ctx context.Context, body io.Reader, bytes int64) (*http.Request, error) {
+	return newUploadRequest(ctx, l.backend()+"/empty.php", body, bytes)
+}
+
+func (l *LibreSpeedTester) String() string {
+	return "librespeed (" + l.BaseURL + ")"
+}
+
+func newUploadRequest(ctx context.Context, dest string, body io.Reader, bytes int64) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dest, body)
+	if err != nil {
+		return nil, err
+	}
+	// Not inferred from the readers speed tests send
+	req.ContentLength = bytes
+	req.Header.Set("Content-Type", "application/octet-stream")
+	return req, nil
+}
+
+// ThroughputOptions tunes how a speed test transfers. The zero value
+// transfers the amount asked for once, on one connection.
+type ThroughputOptions struct {
+	// Duration, when set, keeps transferring for that long after the
+	// ramp-up instead of stopping after the amount.
+	Duration time.Duration
+	// Streams is the number of parallel connections, 1 when below it.
+	// A fixed amount is split between them.
+	Streams int
+	// RampUp is left out of the measurement, so TCP can reach its
+	// speed first. It defaults to a fifth of Duration.
+	RampUp time.Duration
+}
+
+// rampUp returns the ramp-up, defaulting to a fifth of the duration.
+func (o ThroughputOptions) rampUp() time.Duration {
+	if o.RampUp == 0 {
+		return o.Duration / 5
+	}
+	return o.RampUp
+}
+
+// Length returns how long a duration-based transfer takes, ramp-up
+// included, and 0 for a fixed amount.
+func (o ThroughputOptions) Length() time.Duration {
+	if o.Duration <= 0 {
+		return 0
+	}
+	return o.rampUp() + o.Duration
+}
+
+// MeasureDownload downloads bytes from t through client and returns the
+// aggregated speed of the streams in Mbps.
+func MeasureDownload(ctx context.Context, client *http.Client, t SpeedTester, bytes int64, o ThroughputOptions) (float64, error) {
+	return measureThroughput(ctx, client, bytes, o, func(ctx context.Context, client *http.Client, bytes int64, n *atomic.Int64) (bool, error) {
+		req, err := t.DownloadRequest(ctx, bytes)
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("User-Agent", speedtestUserAgent)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return false, fmt.Errorf("download: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return false, fmt.Errorf("download status: %s", resp.Status)
+		}
+		if _, err := io.Copy(countingWriter{n}, io.LimitReader(resp.Body, bytes)); err != nil {
+			return true, fmt.Errorf("download: %w", err)
+		}
+		return true, nil
+	})
+}
+
+// MeasureUpload uploads bytes to t through client and returns the
+// aggregated speed of the streams in Mbps.
+func MeasureUpload(ctx context.Context, client *http.Client, t SpeedTester, bytes int64, o ThroughputOptions) (float64, error) {
+	return measureThroughput(ctx, client, bytes, o, func(ctx context.Context, client *http.Client, bytes int64, n *atomic.Int64) (bool, error) {
+		body := &countingReader{r: io.LimitReader(zeroReader{}, bytes), n: n}
+		req, err := t.UploadRequest(ctx, body, bytes)
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("User-Agent", speedtestUserAgent)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return false, fmt.Errorf("upload: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return false, fmt.Errorf("upload status: %s", resp.Status)
+		}
+		if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+			return true, fmt.Errorf("upload response: %w", err)
+		}
+		return true, nil
+	})
+}
+
+// transferFunc moves up to bytes through client, adding what it moved to n
+// as it goes. accepted reports whether the server answered with a success
+// status, which a download cut off mid-body still did.
+type transferFunc func(ctx context.Context, client *http.Client, bytes int64, n *atomic.Int64) (accepted bool, err error)
+
+// throughputStream is one connection of a speed test.
+type throughputStream struct {
+	moved    atomic.Int64 // everything moved, accepted or not
+	atRampUp atomic.Int64 // moved when the ramp-up ended, -1 before
+	counted  int64        // moved by transfers the server accepted
+	accepted bool
+	err      error
+}
+
+// measureThroughput runs transfer on o.Streams connections, once each for
+// a fixed amount or over and over until the duration is up, and returns
+// their aggregated speed in Mbps past the ramp-up. Only transfers the
+// server accepted count, so an endpoint answering uploads with an error
+// doesn't pass for a speed.
+func measureThroughput(ctx context.Context, client *http.Client, bytes int64, o ThroughputOptions, transfer transferFunc) (float64, error) {
+	streams := make([]throughputStream, max(o.Streams, 1))
+	rampUp := o.RampUp
+	if o.Duration > 0 {
+		rampUp = o.rampUp()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Length())
+		defer cancel()
+		// The deadline ends the transfers, not the client's timeout
+		c := *client
+		c.Timeout = 0
+		client = &c
+	} else {
+		bytes = max(bytes/int64(len(streams)), 1)
+	}
+
+	if rampUp > 0 {
+		for i := range streams {
+			streams[i].atRampUp.Store(-1)
+		}
+		timer := time.AfterFunc(rampUp, func() {
+			for i := range streams {
+				streams[i].atRampUp.Store(streams[i].moved.Load())
+			}
+		})
+		defer timer.Stop()
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := range streams {
+		s := &streams[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				before := s.moved.Load()
+				accepted, err := transfer(ctx, client, bytes, &s.moved)
+				// Cut off by the deadline, as intended
+				cutOff := o.Duration > 0 && ctx.Err() != nil
+				s.accepted = s.accepted || accepted
+				// An upload cut off has no answer yet; its stream's earlier
+				// ones vouch for it
+				if accepted || (cutOff && s.accepted) {
+					s.counted += s.moved.Load() - before
+				}
+				if cutOff {
+					return
+				}
+				if err != nil {
+					s.err = err
+					return
+				}
+				if o.Duration <= 0 {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	var total, base int64
+	var errs []error
+	rampedUp := rampUp > 0 && elapsed > rampUp
+	for i := range streams {
+		s := &streams[i]
+		total += s.counted
+		if at := s.atRampUp.Load(); at >= 0 {
+			base += min(at, s.counted)
+		} else {
+			rampedUp = false
+		}
+		if s.err != nil {
+			errs = append(errs, s.err)
+		}
+	}
+	// A fixed amount has to arrive in full. Over a duration, a stream
+	// failing mid-way doesn't void what the others measured, unless all
+	// of them failed.
+	if len(errs) > 0 && (o.Duration <= 0 || len(errs) == len(streams)) {
+		return 0, errs[0]
+	}
+	if total == 0 {
+		return 0, errors.New("no data transferred")
+	}
+	if rampedUp && total > base {
+		return mbps(total-base, elapsed-rampUp), nil
+	}
+	// Finished within the ramp-up
+	return mbps(total, elapsed), nil
+}
+
+func mbps(bytes int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(bytes) * 8 / (d.Seconds() * 1e6)
+}
+
+// countingReader adds the bytes read from r to n.
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// countingWriter adds the bytes written to it to n and discards them.
+type countingWriter struct {
+	n *atomic.Int64
+}
+
+func (c countingWriter) Write(p []byte) (int, error) {
+	c.n.Add(int64(len(p)))
+	return len(p), nil
+}
+
+// measureSpeed runs one direction of the speed test and returns its speed,
+// 0 when it failed. A fixed amount gets 20s.
+func (e *Examiner) measureSpeed(ctx context.Context, client *http.Client, measure func(context.Context, *http.Client, SpeedTester, int64, ThroughputOptions) (float64, error)) float32 {
+	if e.Throughput.Duration == 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, 20*time.Second)
+		defer cancel()
+	}
+	speed, err := measure(ctx, client, e.SpeedTester, int64(e.SpeedtestKbAmount*1000), e.Throughput)
+	if err != nil {
+		return 0
+	}
+	return float32(speed)
+}
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// DefaultSpeedtestMaxBytes caps the size of a single download from the
+// speed test server.
+const DefaultSpeedtestMaxBytes = 1 << 30
+
+// NewSpeedtestHandler serves speed tests the Cloudflare (/__down?bytes=N,
+// /__up) and LibreSpeed (/backend/garbage.php?ckSize=N,
+// /backend/empty.php) backends can use. Downloads over maxBytes are
+// refused.
+func NewSpeedtestHandler(maxBytes int64) http.Handler {
+	if maxBytes <= 0 {
+		maxBytes = DefaultSpeedtestMaxBytes
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /__down", func(w http.ResponseWriter, r *http.Request) {
+		bytes, err := strconv.ParseInt(r.URL.Query().Get("bytes"), 10, 64)
+		if err != nil || bytes < 0 {
+			http.Error(w, "invalid bytes", http.StatusBadRequest)
+			return
+		}
+		serveZeros(w, bytes, maxBytes)
+	})
+	mux.HandleFunc("GET /backend/garbage.php", func(w http.ResponseWriter, r *http.Request) {
+		chunks := int64(4)
+		if s := r.URL.Query().Get("ckSize"); s != "" {
+			var err error
+			if chunks, err = strconv.ParseInt(s, 10, 64); err != nil || chunks < 1 {
+				http.Error(w, "invalid ckSize", http.StatusBadRequest)
+				return
+			}
+		}
+		// LibreSpeed clients ask for more than they read, so cap it
+		chunks = min(chunks, max(maxBytes/libreSpeedChunk, 1))
+		serveZeros(w, chunks*libreSpeedChunk, chunks*libreSpeedChunk)
+	})
+	discard := func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+	}
+	mux.HandleFunc("/__up", discard)
+	mux.HandleFunc("/backend/empty.php", discard)
+	return mux
+}
+
+func serveZeros(w http.ResponseWriter, bytes, maxBytes int64) {
+	if bytes > maxBytes {
+		http.Error(w, "requested size is over the server limit of "+strconv.FormatInt(maxBytes, 10)+" bytes", http.StatusRequestEntityTooLarge)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(bytes, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	io.Copy(w, io.LimitReader(zeroReader{}, bytes))
+}
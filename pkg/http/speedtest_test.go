@@ -0,0 +1,192 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseSpeedtestBackend(t *testing.T) {
+	tests := map[string]SpeedtestBackend{
+		"":                                     {Type: SpeedtestCloudflare},
+		"cloudflare":                           {Type: SpeedtestCloudflare},
+		"cloudflare:http://10.0.0.1:8080":      {Type: SpeedtestCloudflare, Server: "http://10.0.0.1:8080"},
+		"librespeed:https://ls.example.com":    {Type: SpeedtestLibreSpeed, Server: "https://ls.example.com"},
+		"url:https://a.example/100mb.bin":      {Type: SpeedtestURL, DownloadURL: "https://a.example/100mb.bin"},
+		"url:https://a.example/f, https://b/u": {Type: SpeedtestURL, DownloadURL: "https://a.example/f", UploadURL: "https://b/u"},
+	}
+	for spec, want := range tests {
+		got, err := ParseSpeedtestBackend(spec)
+		if err != nil || got != want {
+			t.Errorf("ParseSpeedtestBackend(%q) = %+v, %v; want %+v", spec, got, err, want)
+		}
+	}
+	if _, err := ParseSpeedtestBackend("ookla"); err == nil {
+		t.Error("expected an error for an unknown backend")
+	}
+}
+
+func TestNewSpeedTesterValidates(t *testing.T) {
+	for _, b := range []SpeedtestBackend{
+		{Type: SpeedtestLibreSpeed},
+		{Type: SpeedtestURL},
+		{Type: SpeedtestURL, DownloadURL: "ftp://example.com/file"},
+		{Type: "ookla"},
+	} {
+		if _, err := NewSpeedTester(b); err == nil {
+			t.Errorf("NewSpeedTester(%+v): expected an error", b)
+		}
+	}
+}
+
+func TestSpeedTestersAgainstServer(t *testing.T) {
+	srv := httptest.NewServer(NewSpeedtestHandler(4 << 20))
+	defer srv.Close()
+
+	backends := []SpeedtestBackend{
+		{Type: SpeedtestCloudflare, Server: srv.URL},
+		{Type: SpeedtestLibreSpeed, Server: srv.URL},
+		{Type: SpeedtestURL, DownloadURL: srv.URL + "/__down?bytes=1000000", UploadURL: srv.URL + "/__up"},
+	}
+	for _, b := range backends {
+		tester, err := NewSpeedTester(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if speed, err := MeasureDownload(context.Background(), srv.Client(), tester, 1<<20, ThroughputOptions{}); err != nil || speed <= 0 {
+			t.Errorf("%s download: %v, %v", tester, speed, err)
+		}
+		if speed, err := MeasureUpload(context.Background(), srv.Client(), tester, 1<<20, ThroughputOptions{}); err != nil || speed <= 0 {
+			t.Errorf("%s upload: %v, %v", tester, speed, err)
+		}
+	}
+
+	tester, _ := NewSpeedTester(SpeedtestBackend{Type: SpeedtestURL, DownloadURL: srv.URL + "/__down?bytes=10"})
+	if _, err := MeasureUpload(context.Background(), srv.Client(), tester, 10, ThroughputOptions{}); !errors.Is(err, ErrNoUploadEndpoint) {
+		t.Errorf("upload without an upload url: %v", err)
+	}
+}
+
+func TestMeasureThroughputStreams(t *testing.T) {
+	var mu sync.Mutex
+	requested := map[string]int{}
+	handler := NewSpeedtestHandler(0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requested[r.URL.Query().Get("bytes")]++
+		mu.Unlock()
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	tester, _ := NewSpeedTester(SpeedtestBackend{Server: srv.URL})
+
+	// A fixed amount is split between the streams
+	if _, err := MeasureDownload(context.Background(), srv.Client(), tester, 4000, ThroughputOptions{Streams: 4}); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if requested["1000"] != 4 || len(requested) != 1 {
+		t.Errorf("fixed amount on 4 streams requested %v", requested)
+	}
+	clear(requested)
+	mu.Unlock()
+
+	// A duration keeps requesting until it is up, past the client's timeout
+	client := srv.Client()
+	client.Timeout = 50 * time.Millisecond
+	o := ThroughputOptions{Duration: 300 * time.Millisecond, Streams: 2, RampUp: 100 * time.Millisecond}
+	start := time.Now()
+	speed, err := MeasureDownload(context.Background(), client, tester, 1<<20, o)
+	if err != nil || speed <= 0 {
+		t.Fatalf("duration: %v, %v", speed, err)
+	}
+	if elapsed := time.Since(start); elapsed < o.Length() || elapsed > o.Length()+time.Second {
+		t.Errorf("took %v, want about %v", elapsed, o.Length())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requested["1048576"] < 2 {
+		t.Errorf("duration on 2 streams requested %v", requested)
+	}
+}
+
+func TestMeasureThroughputFailures(t *testing.T) {
+	failing := func(context.Context, *http.Client, int64, *atomic.Int64) (bool, error) {
+		return false, errors.New("refused")
+	}
+	for _, o := range []ThroughputOptions{{}, {Duration: 50 * time.Millisecond, Streams: 2}} {
+		if _, err := measureThroughput(context.Background(), http.DefaultClient, 100, o, failing); err == nil || err.Error() != "refused" {
+			t.Errorf("%+v: got %v", o, err)
+		}
+	}
+
+	// Every stream failing is an error even when they moved data first
+	reset := func(_ context.Context, _ *http.Client, _ int64, n *atomic.Int64) (bool, error) {
+		n.Add(1000)
+		return true, errors.New("reset")
+	}
+	if _, err := measureThroughput(context.Background(), http.DefaultClient, 100, ThroughputOptions{Duration: 50 * time.Millisecond, Streams: 2}, reset); err == nil || err.Error() != "reset" {
+		t.Errorf("all streams reset: got %v", err)
+	}
+
+	// One stream failing doesn't void the other, and what the server
+	// refused doesn't count
+	var calls atomic.Int32
+	oneRejected := func(ctx context.Context, _ *http.Client, _ int64, n *atomic.Int64) (bool, error) {
+		if calls.Add(1) == 1 {
+			time.Sleep(100 * time.Millisecond)
+			n.Add(1 << 20)
+			return false, errors.New("upload status: 403 Forbidden")
+		}
+		time.Sleep(time.Millisecond)
+		n.Add(1000)
+		return true, nil
+	}
+	o := ThroughputOptions{Duration: 200 * time.Millisecond, Streams: 2, RampUp: 20 * time.Millisecond}
+	speed, err := measureThroughput(context.Background(), http.DefaultClient, 100, o, oneRejected)
+	if err != nil || speed <= 0 || speed > 30 {
+		t.Errorf("one stream rejected: %v, %v", speed, err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	tester, _ := NewSpeedTester(SpeedtestBackend{Server: srv.URL})
+	for _, o := range []ThroughputOptions{{}, {Duration: 100 * time.Millisecond, Streams: 2}} {
+		if speed, err := MeasureUpload(context.Background(), srv.Client(), tester, 1<<16, o); err == nil {
+			t.Errorf("%+v: upload answered 403 measured %v", o, speed)
+		}
+	}
+}
+
+func TestSpeedtestHandlerLimit(t *testing.T) {
+	srv := httptest.NewServer(NewSpeedtestHandler(1 << 20))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/__down?bytes=2000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("status %d, want %d", resp.StatusCode, http.StatusRequestEntityTooLarge)
+	}
+
+	// LibreSpeed clients ask for more than they read, so it is capped
+	resp, err = http.Get(srv.URL + "/backend/garbage.php?ckSize=100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ContentLength != 1<<20 {
+		t.Errorf("garbage: status %d, length %d", resp.StatusCode, resp.ContentLength)
+	}
+}
@@ -0,0 +1,231 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+)
+
+// Outcomes of the UDP probe, recorded in Result.UDP.
+const (
+	UDPOk          = "ok"
+	UDPFail        = "fail"
+	UDPUnsupported = "unsupported"
+)
+
+const (
+	DefaultUDPResolver = "1.1.1.1:53"
+	// udpProbeDomain is looked up when no HTTP/3 URL names a host to resolve.
+	udpProbeDomain = "cloudflare.com"
+)
+
+// packetListener opens a UDP connection through the tested config. Packets
+// are only sent to destination (ip:port).
+type packetListener func(ctx context.Context, destination string) (net.PacketConn, error)
+
+// normalizeResolver returns resolver as ip:port, adding port 53 when it
+// has none. It has to be an IP: xray-core can't send packets to a name.
+func normalizeResolver(resolver string) (string, error) {
+	if resolver == "" {
+		return DefaultUDPResolver, nil
+	}
+	host, port, err := net.SplitHostPort(resolver)
+	if err != nil {
+		host, port = resolver, "53"
+	}
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("udp resolver %q is not an IP address", resolver)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// probeUDP checks that UDP goes through the config with a DNS query to the
+// resolver and, when configured, an HTTP/3 request, and records the outcome
+// in r.UDP. A failed probe doesn't fail the config.
+func (e *Examiner) probeUDP(ctx context.Context, listen packetListener, r *Result) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(e.Timeout)*time.Millisecond)
+	defer cancel()
+
+	name := udpProbeDomain
+	if e.UDPHTTP3URL != "" {
+		if u, err := url.Parse(e.UDPHTTP3URL); err == nil && net.ParseIP(u.Hostname()) == nil {
+			name = u.Hostname()
+		}
+	}
+	var err error
+	if _, err = lookupUDP(ctx, listen, e.UDPResolver, name); err != nil {
+		err = fmt.Errorf("dns: %w", err)
+	} else if e.UDPHTTP3URL != "" {
+		if err = http3Get(ctx, listen, e.UDPResolver, e.UDPHTTP3URL); err != nil {
+			err = fmt.Errorf("http3: %w", err)
+		}
+	}
+
+	switch {
+	case err == nil:
+		r.UDP = UDPOk
+	case errors.Is(err, protocol.ErrUDPUnsupported):
+		r.UDP = UDPUnsupported
+	default:
+		r.UDP = UDPFail
+		if r.Reason != "" {
+			r.Reason += "; "
+		}
+		r.Reason += "udp " + err.Error()
+	}
+}
+
+// lookupUDP resolves the A records of name with a DNS query to resolver
+// (ip:port) sent over a connection listen opens.
+func lookupUDP(ctx context.Context, listen packetListener, resolver, name string) ([]net.IP, error) {
+	addr, err := net.ResolveUDPAddr("udp", resolver)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := listen(ctx, resolver)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// Not every core's connection honours deadlines
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	query := new(dns.Msg)
+	query.SetQuestion(dns.Fqdn(name), dns.TypeA)
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.WriteTo(packed, addr); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, dns.MaxMsgSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("no answer from %s: %w", resolver, ctx.Err())
+			}
+			return nil, err
+		}
+		reply := new(dns.Msg)
+		if reply.Unpack(buf[:n]) != nil || reply.Id != query.Id {
+			continue
+		}
+		if reply.Rcode != dns.RcodeSuccess {
+			return nil, fmt.Errorf("%s answered %s", resolver, dns.RcodeToString[reply.Rcode])
+		}
+		var ips []net.IP
+		for _, rr := range reply.Answer {
+			if a, ok := rr.(*dns.A); ok {
+				ips = append(ips, a.A)
+			}
+		}
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("%s has no A record", name)
+		}
+		return ips, nil
+	}
+}
+
+// http3Get makes an HTTP/3 request to rawURL over connections listen opens,
+// resolving its host through resolver the same way.
+func http3Get(ctx context.Context, listen packetListener, resolver, rawURL string) error {
+	var conns []net.PacketConn
+	defer func() {
+		for _, c := range conns {
+			c.Close()
+		}
+	}()
+
+	tr := &http3.Transport{
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (*quic.Conn, error) {
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			portNum, err := strconv.Atoi(port)
+			if err != nil {
+				return nil, err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil {
+				ips, err := lookupUDP(ctx, listen, resolver, host)
+				if err != nil {
+					return nil, err
+				}
+				ip = ips[0]
+			}
+			raddr := &net.UDPAddr{IP: ip, Port: portNum}
+			conn, err := listen(ctx, raddr.String())
+			if err != nil {
+				return nil, err
+			}
+			sc := &stoppableConn{PacketConn: conn}
+			conns = append(conns, sc)
+			return quic.DialEarly(ctx, sc, raddr, tlsCfg, cfg)
+		},
+	}
+	defer tr.Close()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(io.Discard, resp.Body)
+	return err
+}
+
+// stoppableConn closes the connection once its read deadline passes. quic-go
+// sets one to stop reading from a connection it is done with, which would
+// hang on connections that ignore deadlines, like xray-core's.
+type stoppableConn struct {
+	net.PacketConn
+
+	mu    sync.Mutex
+	timer *time.Timer // closes the connection at the read deadline
+}
+
+func (c *stoppableConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if !t.IsZero() {
+		c.timer = time.AfterFunc(time.Until(t), func() { c.PacketConn.Close() })
+	}
+	c.mu.Unlock()
+	return c.PacketConn.SetReadDeadline(t)
+}
+
+func (c *stoppableConn) Close() error {
+	c.mu.Lock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.mu.Unlock()
+	return c.PacketConn.Close()
+}
@@ -0,0 +1,120 @@
+package http
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+)
+
+// startDNSServer serves A records of 192.0.2.1 for every name on a local
+// UDP port and returns its address.
+func startDNSServer(t *testing.T) string {
+	t.Helper()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		reply := new(dns.Msg)
+		reply.SetReply(req)
+		for _, q := range req.Question {
+			rr, _ := dns.NewRR(q.Name + " 60 IN A 192.0.2.1")
+			reply.Answer = append(reply.Answer, rr)
+		}
+		w.WriteMsg(reply)
+	})}
+	go srv.ActivateAndServe()
+	t.Cleanup(func() { srv.Shutdown() })
+	return pc.LocalAddr().String()
+}
+
+func directListener(ctx context.Context, destination string) (net.PacketConn, error) {
+	return net.ListenPacket("udp", "127.0.0.1:0")
+}
+
+func TestLookupUDP(t *testing.T) {
+	resolver := startDNSServer(t)
+	ips, err := lookupUDP(context.Background(), directListener, resolver, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 1 || !ips[0].Equal(net.IPv4(192, 0, 2, 1)) {
+		t.Errorf("got %v", ips)
+	}
+}
+
+func TestProbeUDP(t *testing.T) {
+	e := &Examiner{Timeout: 300, UDPResolver: startDNSServer(t)}
+
+	var r Result
+	e.probeUDP(context.Background(), directListener, &r)
+	if r.UDP != UDPOk {
+		t.Errorf("working resolver: got %q (%s)", r.UDP, r.Reason)
+	}
+
+	r = Result{}
+	e.probeUDP(context.Background(), func(context.Context, string) (net.PacketConn, error) {
+		return nil, protocol.ErrUDPUnsupported
+	}, &r)
+	if r.UDP != UDPUnsupported || r.Reason != "" {
+		t.Errorf("tcp-only outbound: got %q (%s)", r.UDP, r.Reason)
+	}
+
+	// Nothing listens on the resolver port, so the query goes unanswered
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	e.UDPResolver = silent.LocalAddr().String()
+	r = Result{}
+	e.probeUDP(context.Background(), directListener, &r)
+	if r.UDP != UDPFail || !strings.Contains(r.Reason, "no answer") {
+		t.Errorf("silent resolver: got %q (%s)", r.UDP, r.Reason)
+	}
+}
+
+func TestNormalizeResolver(t *testing.T) {
+	tests := map[string]string{
+		"":                DefaultUDPResolver,
+		"8.8.8.8":         "8.8.8.8:53",
+		"9.9.9.9:5353":    "9.9.9.9:5353",
+		"2606:4700::1111": "[2606:4700::1111]:53",
+	}
+	for in, want := range tests {
+		if got, err := normalizeResolver(in); err != nil || got != want {
+			t.Errorf("normalizeResolver(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := normalizeResolver("dns.google"); err == nil {
+		t.Error("expected an error for a host name")
+	}
+}
+
+func TestStoppableConn(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &stoppableConn{PacketConn: pc}
+
+	// A cleared deadline doesn't close the connection later
+	c.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	c.SetReadDeadline(time.Time{})
+	time.Sleep(50 * time.Millisecond)
+	if _, err := c.WriteTo([]byte("x"), pc.LocalAddr()); err != nil {
+		t.Fatalf("connection closed after its deadline was cleared: %v", err)
+	}
+
+	c.SetReadDeadline(time.Now().Add(time.Hour))
+	c.Close()
+	if c.timer != nil {
+		t.Error("Close left the deadline timer running")
+	}
+}
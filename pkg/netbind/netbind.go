@@ -59,11 +59,28 @@ func (b *Binder) Enabled() bool {
 // Control returns a control.Func suitable for net.Dialer.Control or for
 // xray-core's transport/internet.RegisterDialerController. Returns nil
 // when binding is disabled (callers must handle nil and skip wiring).
+// Loopback destinations are left unbound, since binding them to another
+// interface makes them unreachable (e.g. the bridges of mixed-core chains).
 func (b *Binder) Control() control.Func {
 	if !b.Enabled() {
 		return nil
 	}
-	return control.BindToInterface(b.finder, b.iface, -1)
+	bindCtl := control.BindToInterface(b.finder, b.iface, -1)
+	return func(network, address string, c syscall.RawConn) error {
+		if isLoopback(address) {
+			return nil
+		}
+		return bindCtl(network, address, c)
+	}
+}
+
+func isLoopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		host = address
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
 
 // ApplyDialer copies the binder's control function onto the given dialer.
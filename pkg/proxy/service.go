@@ -107,6 +107,20 @@ type Config struct {
 	// traffic must take a specific path regardless of the kernel's
 	// default route.
 	BindInterface string `json:"bindInterface,omitempty"`
+	// Fragment enables TLS ClientHello fragmentation (and noises on
+	// xray-core) on every outbound, including the test-time ones.
+	Fragment *protocol.FragmentOptions `json:"fragment,omitempty"`
+	// Mux overrides the multiplexing settings of the outbound links.
+	// nil leaves it to the links.
+	Mux *protocol.MuxOptions `json:"mux,omitempty"`
+	// TestProfile tests the configs during rotation against several
+	// targets instead of the Cloudflare trace URL. A profile with only a
+	// name selects a built-in one.
+	TestProfile *pkghttp.Profile `json:"testProfile,omitempty"`
+	// TestSamples, when above 1, sends that many requests through each
+	// config during rotation and ranks them by median, jitter and loss
+	// rather than a single delay.
+	TestSamples uint16 `json:"testSamples,omitempty"`
 	// DNS overrides the resolver inside the app-mode tunnel.
 	// Empty = use netns.DefaultConfig (1.1.1.1).
 	DNS string `json:"dns,omitempty"`
@@ -146,6 +160,7 @@ type blacklistEntry struct {
 type Service struct {
 	config            Config
 	core              core.Core
+	chain             *core.ChainBuilder // builds chains whose hops span both cores
 	logger            *log.Logger
 	inbound           protocol.Protocol
 	activeOutbound    *pkghttp.Result
@@ -278,6 +293,8 @@ func New(config Config, logger *log.Logger) (*Service, error) {
 		InsecureTLS:   config.InsecureTLS,
 		Verbose:       config.Verbose,
 		BindInterface: config.BindInterface,
+		Fragment:      config.Fragment,
+		Mux:           config.Mux,
 	}
 	switch config.CoreType {
 	case "xray":
@@ -287,6 +304,9 @@ func New(config Config, logger *log.Logger) (*Service, error) {
 	default:
 		return nil, fmt.Errorf("allowed core types: (xray, sing-box), got: %s", config.CoreType)
 	}
+	// Chain hops only one core implements run on that core, bridged to the
+	// rest of the chain.
+	s.chain = core.NewChainBuilder(s.core.Name(), coreOpts)
 
 	inbound, err := s.createInbound()
 	if err != nil {
@@ -1229,7 +1249,11 @@ func (s *Service) findAndStartWorkingConfig(
 		if res.Status != "passed" || res.Protocol == nil {
 			continue
 		}
-		s.logf(customlog.Success, "Found working config: %s (Delay: %dms)\n", res.ConfigLink, res.Delay)
+		if res.Samples > 1 {
+			s.logf(customlog.Success, "Found working config: %s (p50: %dms, jitter: %dms, loss: %.0f%%)\n", res.ConfigLink, res.DelayP50, res.Jitter, res.Loss)
+		} else {
+			s.logf(customlog.Success, "Found working config: %s (Delay: %dms)\n", res.ConfigLink, res.Delay)
+		}
 		s.logf(customlog.Info, "==========OUTBOUND==========")
 		if s.logger != nil {
 			g := res.Protocol.ConvertToGeneralConfig()
@@ -1325,8 +1349,12 @@ func (s *Service) chainHealthCheck(ctx context.Context) bool {
 	return doHealthGET(ctx, client, timeout)
 }
 
-// makeChainedInstance delegates to the concrete core's MakeChainedInstance.
+// makeChainedInstance delegates to the concrete core's MakeChainedInstance,
+// or to the chain builder when some hops belong to the other core.
 func (s *Service) makeChainedInstance(ctx context.Context, hops []protocol.Protocol) (protocol.Instance, error) {
+	if s.isMixedChain(hops) {
+		return s.chain.MakeInstance(ctx, s.inbound, hops)
+	}
 	switch c := s.core.(type) {
 	case *pkgxray.Core:
 		return c.MakeChainedInstance(ctx, hops)
@@ -1337,8 +1365,12 @@ func (s *Service) makeChainedInstance(ctx context.Context, hops []protocol.Proto
 	}
 }
 
-// makeChainedHttpClient delegates to the concrete core's MakeChainedHttpClient.
+// makeChainedHttpClient delegates to the concrete core's MakeChainedHttpClient,
+// or to the chain builder when some hops belong to the other core.
 func (s *Service) makeChainedHttpClient(ctx context.Context, hops []protocol.Protocol, maxDelay time.Duration) (*http.Client, protocol.Instance, error) {
+	if s.isMixedChain(hops) {
+		return s.chain.MakeHttpClient(ctx, hops, maxDelay)
+	}
 	switch c := s.core.(type) {
 	case *pkgxray.Core:
 		return c.MakeChainedHttpClient(ctx, hops, maxDelay)
@@ -1349,6 +1381,17 @@ func (s *Service) makeChainedHttpClient(ctx context.Context, hops []protocol.Pro
 	}
 }
 
+// isMixedChain reports whether any hop was created by a core other than
+// s.core.
+func (s *Service) isMixedChain(hops []protocol.Protocol) bool {
+	for _, hop := range hops {
+		if core.HopCore(hop) != s.core.Name() {
+			return true
+		}
+	}
+	return false
+}
+
 // runChainMode runs the proxy in chain mode with optional rotation.
 func (s *Service) runChainMode(ctx context.Context, forceRotate <-chan struct{}) error {
 	isFixedChain := s.config.ChainLinks != "" || s.config.ChainFile != ""
@@ -1376,7 +1419,7 @@ func (s *Service) runChainMode(ctx context.Context, forceRotate <-chan struct{})
 
 // runFixedChainMode parses a fixed chain and runs it without rotation.
 func (s *Service) runFixedChainMode(ctx context.Context) error {
-	hops, err := resolveFixedChain(s.core, s.config.ChainLinks, s.config.ChainFile)
+	hops, err := resolveFixedChain(s.chain.Parser(), s.config.ChainLinks, s.config.ChainFile)
 	if err != nil {
 		return fmt.Errorf("failed to resolve fixed chain: %w", err)
 	}
@@ -1412,7 +1455,7 @@ func (s *Service) runChainNoRotation(ctx context.Context) error {
 		numHops = 2
 	}
 
-	hops, err := selectChainFromPool(s.core, s.config.ConfigLinks, numHops)
+	hops, err := selectChainFromPool(s.chain.Parser(), s.config.ConfigLinks, numHops)
 	if err != nil {
 		return fmt.Errorf("failed to select chain from pool: %w", err)
 	}
@@ -1449,7 +1492,7 @@ func (s *Service) runChainExitRotation(ctx context.Context, forceRotate <-chan s
 	}
 
 	// Select initial chain.
-	hops, err := selectChainFromPool(s.core, s.config.ConfigLinks, numHops)
+	hops, err := selectChainFromPool(s.chain.Parser(), s.config.ConfigLinks, numHops)
 	if err != nil {
 		return fmt.Errorf("failed to select initial chain from pool: %w", err)
 	}
@@ -1550,7 +1593,7 @@ func (s *Service) runChainExitRotation(ctx context.Context, forceRotate <-chan s
 
 		// Pick a fresh exit hop that isn't already in the chain or the one
 		// we're rotating away from.
-		newHops, err := selectExitHopFromPool(s.core, s.config.ConfigLinks, fixedHops, lastExitLink)
+		newHops, err := selectExitHopFromPool(s.chain.Parser(), s.config.ConfigLinks, fixedHops, lastExitLink)
 		if err != nil {
 			s.logf(customlog.Warning, "No new exit hop available: %v. Keeping current chain.\n", err)
 			s.setRotationStatus("stalled")
@@ -1761,7 +1804,7 @@ func (s *Service) findWorkingChain(ctx context.Context, numHops int, timeout tim
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
 		}
-		hops, err := selectChainFromPool(s.core, s.config.ConfigLinks, numHops)
+		hops, err := selectChainFromPool(s.chain.Parser(), s.config.ConfigLinks, numHops)
 		if err != nil {
 			continue
 		}
@@ -1807,6 +1850,9 @@ func (s *Service) logChainHops(hops []protocol.Protocol) {
 			role = "exit"
 		}
 		g := hop.ConvertToGeneralConfig()
+		if hopCore := core.HopCore(hop); hopCore != s.core.Name() {
+			role += ", " + hopCore
+		}
 		if s.logger != nil {
 			s.logger.Printf("Hop %d (%s): %s %s:%s [%s]\n", i+1, role, g.Protocol, g.Address, g.Port, g.Remark)
 		} else {
@@ -1829,6 +1875,10 @@ func (s *Service) createExaminer() (*pkghttp.Examiner, error) {
 		// Keep test-time dials on the same interface the live outbound
 		// uses, otherwise a passing test can hide a runtime --bind failure.
 		BindInterface: s.config.BindInterface,
+		Fragment:      s.config.Fragment,
+		Mux:           s.config.Mux,
+		Profile:       s.config.TestProfile,
+		Samples:       s.config.TestSamples,
 	})
 }
 
@@ -11,7 +11,6 @@ import (
 	"math/rand"
 	"net"
 	"net/http"
-	"net/url"
 	"os"
 	"reflect"
 	"sort"
@@ -23,31 +22,13 @@ import (
 	"github.com/lilendian0x00/xray-knife/v10/database"
 	"github.com/lilendian0x00/xray-knife/v10/pkg/core"
 	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+	pkghttp "github.com/lilendian0x00/xray-knife/v10/pkg/http"
 	"github.com/lilendian0x00/xray-knife/v10/pkg/netbind"
 	"github.com/lilendian0x00/xray-knife/v10/utils"
 	utls "github.com/refraction-networking/utls"
 	"golang.org/x/net/http2"
 )
 
-// zeroReader is an io.Reader that endlessly produces zero bytes.
-type zeroReader struct{}
-
-func (z zeroReader) Read(p []byte) (n int, err error) {
-	return len(p), nil
-}
-
-// countingReader wraps an io.Reader and counts the total bytes read.
-type countingReader struct {
-	r io.Reader
-	n int64
-}
-
-func (c *countingReader) Read(p []byte) (n int, err error) {
-	n, err = c.r.Read(p)
-	c.n += int64(n)
-	return
-}
-
 // ScannerConfig holds all configuration for a scan.
 type ScannerConfig struct {
 	Subnets              []string `json:"subnets"`
@@ -76,8 +57,23 @@ type ScannerConfig struct {
 	Port int `json:"port"`
 	// BindInterface pins outbound dials (both raw and core-based) to a
 	// specific OS interface. Empty disables binding.
-	BindInterface       string `json:"bindInterface,omitempty"`
-	OnIPScannedCallback func() `json:"-"` // Instance-scoped callback for progress reporting
+	BindInterface string `json:"bindInterface,omitempty"`
+	// Fragment enables TLS fragmentation on the ConfigLink core.
+	Fragment *protocol.FragmentOptions `json:"fragment,omitempty"`
+	// CorePolicy picks xray or sing-box for the ConfigLink. nil uses the
+	// default rules.
+	CorePolicy          *core.CorePolicy `json:"corePolicy,omitempty"`
+	OnIPScannedCallback func()           `json:"-"` // Instance-scoped callback for progress reporting
+	// SpeedtestBackend is where speed tests download from and upload to.
+	// Without a ConfigLink the scanned IP is dialed, so it has to be on
+	// the CDN being scanned.
+	SpeedtestBackend pkghttp.SpeedtestBackend `json:"speedtestBackend,omitempty"`
+	// SpeedtestDuration, when set, transfers for that many seconds in
+	// each direction instead of DownloadMB/UploadMB, on SpeedtestStreams
+	// connections. SpeedtestRampUp (ms) is left out of the measurement.
+	SpeedtestDuration int `json:"speedtestDuration,omitempty"`
+	SpeedtestStreams  int `json:"speedtestStreams,omitempty"`
+	SpeedtestRampUp   int `json:"speedtestRampUp,omitempty"`
 }
 
 // scanPort returns the configured port, falling back to 443.
@@ -88,16 +84,34 @@ func (c *ScannerConfig) scanPort() int {
 	return c.Port
 }
 
+// throughput returns the speed test transfer options.
+func (c *ScannerConfig) throughput() pkghttp.ThroughputOptions {
+	return pkghttp.ThroughputOptions{
+		Duration: time.Duration(c.SpeedtestDuration) * time.Second,
+		Streams:  c.SpeedtestStreams,
+		RampUp:   time.Duration(c.SpeedtestRampUp) * time.Millisecond,
+	}
+}
+
+// speedtestTimeout returns the time one IP's speed test may take, enough
+// for both directions of a duration-based one.
+func (c *ScannerConfig) speedtestTimeout() time.Duration {
+	timeout := time.Duration(c.SpeedtestTimeout) * time.Second
+	if length := c.throughput().Length(); length > 0 {
+		timeout = max(timeout, 2*length+10*time.Second)
+	}
+	return timeout
+}
+
 // ScannerService is the main engine for scanning.
 type ScannerService struct {
-	config          ScannerConfig
-	logger          *log.Logger
-	xrayCore        core.Core
-	singboxCore     core.Core
-	selectedCoreMap map[string]core.Core
-	initialResults  []*ScanResult
-	scannedIPs      map[string]bool
-	binder          *netbind.Binder // nil when not configured
+	config         ScannerConfig
+	logger         *log.Logger
+	autoCore       *core.AutomaticCore // nil without a ConfigLink
+	speedTester    pkghttp.SpeedTester
+	initialResults []*ScanResult
+	scannedIPs     map[string]bool
+	binder         *netbind.Binder // nil when not configured
 }
 
 // notifyIPScanned calls the instance callback if set, otherwise falls back to the global.
@@ -118,6 +132,7 @@ type ScanResult struct {
 	UpSpeed   float64       `csv:"upload_mbps" json:"upload_mbps"`
 	Error     error         `csv:"-" json:"-"`
 	ErrorStr  string        `csv:"error,omitempty" json:"error,omitempty"`
+	Core      string        `csv:"core,omitempty" json:"core,omitempty"` // core used for the ConfigLink
 	mu        sync.Mutex    `csv:"-" json:"-"`
 }
 
@@ -139,11 +154,16 @@ func NewScannerService(config ScannerConfig, logger *log.Logger) (*ScannerServic
 	if err != nil {
 		return nil, fmt.Errorf("cfscanner: %w", err)
 	}
+	speedTester, err := pkghttp.NewSpeedTester(config.SpeedtestBackend)
+	if err != nil {
+		return nil, fmt.Errorf("cfscanner: %w", err)
+	}
 	s := &ScannerService{
-		config:     config,
-		logger:     logger,
-		scannedIPs: make(map[string]bool),
-		binder:     binder,
+		config:      config,
+		logger:      logger,
+		scannedIPs:  make(map[string]bool),
+		binder:      binder,
+		speedTester: speedTester,
 	}
 
 	if s.config.Resume {
@@ -185,19 +205,13 @@ func NewScannerService(config ScannerConfig, logger *log.Logger) (*ScannerServic
 	}
 
 	if s.config.ConfigLink != "" {
-		coreOpts := core.FactoryOptions{
+		s.autoCore = core.NewAutomaticCoreWith(core.FactoryOptions{
 			InsecureTLS:   s.config.InsecureTLS,
 			Verbose:       s.config.Verbose,
 			BindInterface: s.config.BindInterface,
-		}
-		s.xrayCore = core.CoreFactoryWith(core.XrayCoreType, coreOpts)
-		s.singboxCore = core.CoreFactoryWith(core.SingboxCoreType, coreOpts)
-		s.selectedCoreMap = map[string]core.Core{
-			protocol.VmessIdentifier: s.xrayCore, protocol.VlessIdentifier: s.xrayCore,
-			protocol.ShadowsocksIdentifier: s.xrayCore, protocol.TrojanIdentifier: s.xrayCore,
-			protocol.SocksIdentifier: s.xrayCore, protocol.WireguardIdentifier: s.xrayCore,
-			protocol.Hysteria2Identifier: s.singboxCore, "hy2": s.singboxCore,
-		}
+			Fragment:      s.config.Fragment,
+			CorePolicy:    s.config.CorePolicy,
+		})
 	}
 
 	return s, nil
@@ -468,7 +482,7 @@ func (s *ScannerService) runSpeedTest(ctx context.Context, allResults []*ScanRes
 		numToTest = len(successfulLatencyResults)
 	}
 	topResults := successfulLatencyResults[:numToTest]
-	s.logger.Printf("Phase 2: Performing speed tests on the top %d IPs (with %d concurrent tests, %ds timeout each)...", len(topResults), s.config.SpeedtestConcurrency, s.config.SpeedtestTimeout)
+	s.logger.Printf("Phase 2: Performing speed tests on the top %d IPs (with %d concurrent tests, %v timeout each)...", len(topResults), s.config.SpeedtestConcurrency, s.config.speedtestTimeout())
 
 	speedTestPool := pond.NewPool(s.config.SpeedtestConcurrency)
 	defer speedTestPool.Stop()
@@ -483,7 +497,7 @@ func (s *ScannerService) runSpeedTest(ctx context.Context, allResults []*ScanRes
 		}
 		resToTest := result
 		group.Submit(func() {
-			timeoutCtx, cancel := context.WithTimeout(group.Context(), time.Duration(s.config.SpeedtestTimeout)*time.Second)
+			timeoutCtx, cancel := context.WithTimeout(group.Context(), s.config.speedtestTimeout())
 			defer cancel()
 			downSpeed, upSpeed, err := s.measureSpeed(timeoutCtx, resToTest.IP)
 			resToTest.mu.Lock()
@@ -552,6 +566,7 @@ func (s *ScannerService) scanIPForLatency(ctx context.Context, ip string) *ScanR
 			return result
 		}
 		defer instance.Close()
+		result.Core = core.UsedCore(s.autoCore, instance)
 	} else {
 		transport := NewBypassJA3Transport(utls.HelloChrome_Auto)
 		transport.DialContext = s.createDialerWithRetry(ip, s.config.RetryCount)
@@ -610,97 +625,36 @@ func (s *ScannerService) measureSpeed(ctx context.Context, ip string) (downSpeed
 		}
 	}
 
-	// Download
-	downURL := fmt.Sprintf("https://%s/__down?bytes=%d", cloudflareSpeedTestURL, downloadBytesTotal)
-	reqDown, err := http.NewRequestWithContext(ctx, "GET", downURL, nil)
-	if err != nil {
-		return 0, 0, fmt.Errorf("failed to create download request: %w", err)
-	}
-	reqDown.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36")
-
-	startDown := time.Now()
-	respDown, err := client.Do(reqDown)
+	throughput := s.config.throughput()
+	downSpeed, err = pkghttp.MeasureDownload(ctx, client, s.speedTester, downloadBytesTotal, throughput)
 	if err != nil {
-		return 0, 0, fmt.Errorf("download test failed: %w", err)
-	}
-	defer respDown.Body.Close()
-	if respDown.StatusCode < 200 || respDown.StatusCode >= 300 {
-		return 0, 0, fmt.Errorf("download status: %s", respDown.Status)
-	}
-	written, err := io.Copy(io.Discard, respDown.Body)
-	if err != nil {
-		return 0, 0, fmt.Errorf("download stream copy: %w", err)
-	}
-	downDuration := time.Since(startDown).Seconds()
-	if downDuration > 0 {
-		downSpeed = (float64(written) * 8) / (downDuration * 1e6)
+		return 0, 0, err
 	}
 
 	if ctx.Err() != nil {
 		return downSpeed, 0, context.Canceled
 	}
 
-	// Upload
-	upURL := fmt.Sprintf("https://%s/__up", cloudflareSpeedTestURL)
-
-	// Use a memory-efficient counting reader instead of a massive byte slice
-	upCounter := &countingReader{r: io.LimitReader(zeroReader{}, uploadBytesTotal)}
-	reqUp, err := http.NewRequestWithContext(ctx, "POST", upURL, upCounter)
-	if err != nil {
-		return downSpeed, 0, fmt.Errorf("failed to create upload request: %w", err)
-	}
-
-	// Explicitly set ContentLength as it's not automatically inferred from io.LimitReader
-	reqUp.ContentLength = uploadBytesTotal
-	reqUp.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 Safari/537.36")
-	reqUp.Header.Set("Content-Type", "application/octet-stream")
-
-	startUp := time.Now()
-	respUp, err := client.Do(reqUp)
-	if err != nil {
-		return downSpeed, 0, fmt.Errorf("upload test failed: %w", err)
-	}
-	defer respUp.Body.Close()
-	if _, drainErr := io.Copy(io.Discard, respUp.Body); drainErr != nil {
-		return downSpeed, 0, fmt.Errorf("upload response drain: %w", drainErr)
+	upSpeed, err = pkghttp.MeasureUpload(ctx, client, s.speedTester, uploadBytesTotal, throughput)
+	if errors.Is(err, pkghttp.ErrNoUploadEndpoint) {
+		return downSpeed, 0, nil
 	}
-	if respUp.StatusCode < 200 || respUp.StatusCode >= 300 {
-		return downSpeed, 0, fmt.Errorf("upload status: %s", respUp.Status)
-	}
-	upDuration := time.Since(startUp).Seconds()
-	if upDuration > 0 {
-		upSpeed = (float64(upCounter.n) * 8) / (upDuration * 1e6)
-	}
-	return downSpeed, upSpeed, nil
+	return downSpeed, upSpeed, err
 }
 
 func (s *ScannerService) createClientFromConfig(ip string, timeout time.Duration) (*http.Client, protocol.Instance, error) {
-	uri, err := url.Parse(s.config.ConfigLink)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse config link for core selection: %w", err)
-	}
-	selectedCore, ok := s.selectedCoreMap[uri.Scheme]
-	if !ok {
-		return nil, nil, fmt.Errorf("unsupported protocol scheme for auto core: %s", uri.Scheme)
-	}
-	proto, errProto := selectedCore.CreateProtocol(s.config.ConfigLink)
-	if errProto != nil {
-		return nil, nil, fmt.Errorf("failed to create protocol: %w", errProto)
-	}
-	if err = proto.Parse(); err != nil {
-		return nil, nil, fmt.Errorf("failed to parse protocol: %w", err)
-	}
-	if err = setAddress(proto, ip); err != nil {
-		return nil, nil, fmt.Errorf("failed to set IP on protocol: %w", err)
-	}
-	return selectedCore.MakeHttpClient(context.Background(), proto, timeout)
+	return s.autoCore.MakeHttpClientForLink(context.Background(), s.config.ConfigLink, timeout, func(proto protocol.Protocol) error {
+		if err := setAddress(proto, ip); err != nil {
+			return fmt.Errorf("failed to set IP on protocol: %w", err)
+		}
+		return nil
+	})
 }
 
 const (
-	saveBatchSize          = 50
-	saveInterval           = 3 * time.Second
-	cloudflareTraceURL     = "https://cloudflare.com/cdn-cgi/trace"
-	cloudflareSpeedTestURL = "speed.cloudflare.com"
+	saveBatchSize      = 50
+	saveInterval       = 3 * time.Second
+	cloudflareTraceURL = "https://cloudflare.com/cdn-cgi/trace"
 )
 
 func inc(ip net.IP) {
@@ -0,0 +1,155 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+)
+
+// CloudflareRanges are the published Cloudflare edge ranges, used when
+// https://www.cloudflare.com/ips-v4 and ips-v6 can't be fetched.
+var CloudflareRanges = []string{
+	// IPv4 Fallback
+	"173.245.48.0/20",
+	"103.21.244.0/22",
+	"103.22.200.0/22",
+	"103.31.4.0/22",
+	"141.101.64.0/18",
+	"108.162.192.0/18",
+	"190.93.240.0/20",
+	"188.114.96.0/20",
+	"197.234.240.0/22",
+	"198.41.128.0/17",
+	"162.158.0.0/15",
+	"104.16.0.0/13",
+	"104.24.0.0/14",
+	"172.64.0.0/13",
+	"131.0.72.0/22",
+	// IPv6 Fallback
+	"2606:4700::/32",
+	"2803:f800::/32",
+	"2400:cb00::/32",
+	"2c0f:f248::/32",
+	"2a06:98c0::/29",
+}
+
+var cloudflareNets = func() []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(CloudflareRanges))
+	for _, cidr := range CloudflareRanges {
+		if _, n, err := net.ParseCIDR(cidr); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}()
+
+// IsCloudflareIP reports whether ip lies in CloudflareRanges.
+func IsCloudflareIP(ip net.IP) bool {
+	for _, n := range cloudflareNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// cdnTransports are the transports Cloudflare proxies.
+var cdnTransports = map[string]bool{
+	"ws":          true,
+	"grpc":        true,
+	"xhttp":       true,
+	"splithttp":   true,
+	"httpupgrade": true,
+}
+
+// CDNFront returns the domain a CDN-fronted config reaches its server
+// through: the transport host, the SNI, or the address when it is a
+// domain. ok is false for configs not using a transport Cloudflare proxies
+// or using REALITY, which a CDN can't relay.
+func CDNFront(g protocol.GeneralConfig) (front string, ok bool) {
+	if !cdnTransports[strings.ToLower(g.Transport())] || g.TLS == "reality" {
+		return "", false
+	}
+	for _, name := range []string{g.Host, g.SNI, g.Address} {
+		if name = strings.TrimSpace(name); name != "" && net.ParseIP(name) == nil {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// IsBehindCloudflare reports whether the config's server is reached through
+// Cloudflare: its address, or what its front domain resolves to, is a
+// Cloudflare edge IP. With a nil resolver any CDN-fronted config counts.
+func IsBehindCloudflare(ctx context.Context, resolver *net.Resolver, g protocol.GeneralConfig) bool {
+	front, ok := CDNFront(g)
+	if !ok {
+		return false
+	}
+	if ip := net.ParseIP(strings.Trim(g.Address, "[]")); ip != nil {
+		return IsCloudflareIP(ip)
+	}
+	if resolver == nil {
+		return true
+	}
+
+	host := g.Address
+	if host == "" {
+		host = front
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if IsCloudflareIP(a.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// Variant is a config pointed at another edge IP.
+type Variant struct {
+	Link     string
+	SourceIP string // the edge IP the variant dials
+	Origin   string // link of the config the variant was made from
+}
+
+// MakeVariants returns one variant of the CDN-fronted config p per IP in
+// ips. Only the address changes; the front domain is copied into an empty
+// host and SNI so the CDN still routes to the same server. Remarks get the
+// IP appended.
+func MakeVariants(c core.Core, p protocol.Protocol, ips []string) ([]Variant, error) {
+	g := p.ConvertToGeneralConfig()
+	front, ok := CDNFront(g)
+	if !ok {
+		return nil, fmt.Errorf("config is not fronted by a CDN")
+	}
+
+	edits := protocol.Edits{Remark: "{remark} @{ip}"}
+	if g.Host == "" {
+		edits.Host = front
+	}
+	if g.SNI == "" && g.TLS != "" && g.TLS != "none" {
+		edits.SNI = front
+	}
+	if g.Remark == "" {
+		edits.Remark = front + " @{ip}"
+	}
+
+	variants := make([]Variant, 0, len(ips))
+	for _, ip := range ips {
+		edits.Address = ip
+		edited, err := core.EditProtocol(c, p, edits, map[string]string{"ip": ip})
+		if err != nil {
+			return nil, fmt.Errorf("variant for %s: %w", ip, err)
+		}
+		variants = append(variants, Variant{Link: edited.GetLink(), SourceIP: ip, Origin: p.GetLink()})
+	}
+	return variants, nil
+}
@@ -0,0 +1,57 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+)
+
+func TestIsCloudflareIP(t *testing.T) {
+	for ip, want := range map[string]bool{
+		"104.16.1.1":      true,
+		"172.67.10.10":    true,
+		"2606:4700::1":    true,
+		"1.1.1.1":         false,
+		"8.8.8.8":         false,
+		"2001:4860::8888": false,
+	} {
+		if got := IsCloudflareIP(net.ParseIP(ip)); got != want {
+			t.Errorf("IsCloudflareIP(%s) = %v, want %v", ip, got, want)
+		}
+	}
+}
+
+func TestCDNFront(t *testing.T) {
+	tests := []struct {
+		name  string
+		g     protocol.GeneralConfig
+		front string
+		ok    bool
+	}{
+		{"vless ws host", protocol.GeneralConfig{Address: "104.16.1.1", Host: "a.com", SNI: "b.com", Type: "ws", TLS: "tls"}, "a.com", true},
+		{"vmess ws sni", protocol.GeneralConfig{Address: "104.16.1.1", SNI: "b.com", Network: "ws", Type: "none", TLS: "tls"}, "b.com", true},
+		{"grpc domain address", protocol.GeneralConfig{Address: "c.com", Type: "grpc", TLS: "tls"}, "c.com", true},
+		{"tcp", protocol.GeneralConfig{Address: "c.com", Type: "tcp", TLS: "tls"}, "", false},
+		{"reality", protocol.GeneralConfig{Address: "c.com", Type: "xhttp", TLS: "reality"}, "", false},
+		{"no domain", protocol.GeneralConfig{Address: "104.16.1.1", Type: "ws"}, "", false},
+	}
+	for _, tt := range tests {
+		front, ok := CDNFront(tt.g)
+		if front != tt.front || ok != tt.ok {
+			t.Errorf("%s: CDNFront() = %q, %v; want %q, %v", tt.name, front, ok, tt.front, tt.ok)
+		}
+	}
+}
+
+func TestIsBehindCloudflareIPAddress(t *testing.T) {
+	g := protocol.GeneralConfig{Address: "8.8.8.8", Host: "a.com", Type: "ws"}
+	if IsBehindCloudflare(context.Background(), nil, g) {
+		t.Error("config dialing a non-Cloudflare IP counted as behind Cloudflare")
+	}
+	g.Address = "104.16.1.1"
+	if !IsBehindCloudflare(context.Background(), nil, g) {
+		t.Error("config dialing a Cloudflare IP not counted as behind Cloudflare")
+	}
+}
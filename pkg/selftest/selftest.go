@@ -0,0 +1,454 @@
+// Package selftest checks end to end that this build speaks every protocol
+// it serves: it starts local servers with the inbound builders of both
+// cores and connects to them through the client path of each core.
+package selftest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/singbox"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/xray"
+	"github.com/lilendian0x00/xray-knife/v10/pkg/gen"
+)
+
+// Statuses of a client result.
+const (
+	StatusPassed      = "passed"
+	StatusFailed      = "failed"
+	StatusUnsupported = "unsupported" // the client core doesn't speak the case's protocol or transport
+)
+
+// Case is a server to start and connect to.
+type Case struct {
+	Core      string // server core, core.XrayCoreName or core.SingboxCoreName
+	Protocol  string // vless, vmess, trojan, ss, socks or hysteria2
+	Transport string // tcp, ws, grpc, httpupgrade or xhttp; the cipher for ss
+	TLS       bool
+}
+
+// runsOn reports whether clientCore has a client for the case.
+func (c Case) runsOn(clientCore string) bool {
+	switch clientCore {
+	case core.XrayCoreName:
+		return c.Protocol != protocol.Hysteria2Identifier
+	case core.SingboxCoreName:
+		return c.Transport != "xhttp"
+	}
+	return true
+}
+
+func (c Case) String() string {
+	s := c.Protocol + "/" + c.Transport
+	if c.TLS {
+		s += "/tls"
+	}
+	return s
+}
+
+// Cases returns the protocol and transport combinations the inbound
+// builders of each core serve.
+func Cases() []Case {
+	var cases []Case
+	add := func(coreName, proto string, transports []string, tls ...bool) {
+		for _, t := range transports {
+			for _, withTLS := range tls {
+				cases = append(cases, Case{Core: coreName, Protocol: proto, Transport: t, TLS: withTLS})
+			}
+		}
+	}
+
+	v2rayTransports := []string{"tcp", "ws", "grpc", "httpupgrade", "xhttp"}
+	ssMethods := []string{"aes-128-gcm", "chacha20-ietf-poly1305", "2022-blake3-aes-128-gcm"}
+	add(core.XrayCoreName, protocol.VlessIdentifier, v2rayTransports, false, true)
+	add(core.XrayCoreName, protocol.VmessIdentifier, v2rayTransports, false, true)
+	add(core.XrayCoreName, protocol.TrojanIdentifier, v2rayTransports, false, true)
+	add(core.XrayCoreName, protocol.ShadowsocksIdentifier, ssMethods, false)
+	add(core.XrayCoreName, protocol.SocksIdentifier, []string{"tcp"}, false)
+
+	// sing-box has no xhttp server
+	singboxTransports := v2rayTransports[:4]
+	add(core.SingboxCoreName, protocol.VlessIdentifier, singboxTransports, false, true)
+	add(core.SingboxCoreName, protocol.VmessIdentifier, singboxTransports, false, true)
+	add(core.SingboxCoreName, protocol.TrojanIdentifier, singboxTransports, false, true)
+	add(core.SingboxCoreName, protocol.ShadowsocksIdentifier, ssMethods, false)
+	add(core.SingboxCoreName, protocol.SocksIdentifier, []string{"tcp"}, false)
+	add(core.SingboxCoreName, protocol.Hysteria2Identifier, []string{"udp"}, true)
+	return cases
+}
+
+// Options configures Run.
+type Options struct {
+	// Clients are the cores to connect with, both when empty.
+	Clients []string
+	// Timeout bounds each request, 5s when zero.
+	Timeout time.Duration
+	Verbose bool
+}
+
+// ClientResult is the outcome of connecting to a case's server with one
+// client core.
+type ClientResult struct {
+	Core   string
+	Status string
+	Delay  time.Duration
+	Err    error
+}
+
+// Result is the outcome of a case.
+type Result struct {
+	Case Case
+	Link string // the link the clients connected with
+	// ServerErr is set when the server didn't start; there are no client
+	// results then.
+	ServerErr error
+	Clients   []ClientResult
+}
+
+// Passed reports whether the server started and no client failed.
+// Unsupported clients don't count as failures.
+func (r Result) Passed() bool {
+	if r.ServerErr != nil {
+		return false
+	}
+	for _, c := range r.Clients {
+		if c.Status == StatusFailed {
+			return false
+		}
+	}
+	return true
+}
+
+// Run starts the server of each case on 127.0.0.1 in turn and fetches a
+// local HTTP target through it with every client core. onResult, when not
+// nil, is called as each case finishes.
+func Run(ctx context.Context, cases []Case, opts Options, onResult func(Result)) ([]Result, error) {
+	if len(opts.Clients) == 0 {
+		opts.Clients = []string{core.XrayCoreName, core.SingboxCoreName}
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 5 * time.Second
+	}
+
+	certDir, err := os.MkdirTemp("", "xray-knife-selftest")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate directory: %w", err)
+	}
+	defer os.RemoveAll(certDir)
+	certPEM, keyPEM, err := gen.SelfSignedCert("localhost")
+	if err != nil {
+		return nil, err
+	}
+	certPin, err := gen.CertPin(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	certFile, keyFile := filepath.Join(certDir, "server.crt"), filepath.Join(certDir, "server.key")
+	if err := os.WriteFile(certFile, certPEM, 0600); err != nil {
+		return nil, fmt.Errorf("failed to save certificate: %w", err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return nil, fmt.Errorf("failed to save certificate key: %w", err)
+	}
+
+	target, err := newTarget()
+	if err != nil {
+		return nil, err
+	}
+	defer target.Close()
+
+	r := &runner{opts: opts, certFile: certFile, keyFile: keyFile, certPin: certPin, target: target}
+	results := make([]Result, 0, len(cases))
+	for _, c := range cases {
+		if ctx.Err() != nil {
+			return results, ctx.Err()
+		}
+		res := r.run(ctx, c)
+		results = append(results, res)
+		if onResult != nil {
+			onResult(res)
+		}
+	}
+	return results, nil
+}
+
+type runner struct {
+	opts              Options
+	certFile, keyFile string
+	certPin           string // pin of the certificate, which TLS clients check
+	target            *target
+}
+
+func (r *runner) run(ctx context.Context, c Case) Result {
+	res := Result{Case: c}
+	port, err := freePort(c.Protocol == protocol.Hysteria2Identifier)
+	if err != nil {
+		res.ServerErr = err
+		return res
+	}
+	if res.Link, err = c.link(port, r.certPin); err != nil {
+		res.ServerErr = err
+		return res
+	}
+
+	server, err := r.startServer(ctx, c, res.Link)
+	if err != nil {
+		res.ServerErr = err
+		return res
+	}
+	defer server.Close()
+
+	for _, clientCore := range r.opts.Clients {
+		res.Clients = append(res.Clients, r.connect(ctx, clientCore, c, res.Link))
+	}
+	return res
+}
+
+func (r *runner) startServer(ctx context.Context, c Case, link string) (protocol.Instance, error) {
+	p, err := parseWith(c.Core, link, r.opts.Verbose)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server link: %w", err)
+	}
+	if c.TLS {
+		setCert(p, r.certFile, r.keyFile)
+	}
+	instance, err := core.MakeServerInstance(ctx, c.Core, p, core.FactoryOptions{Verbose: r.opts.Verbose})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build server: %w", err)
+	}
+	if err := instance.Start(); err != nil {
+		instance.Close()
+		return nil, fmt.Errorf("failed to start server: %w", err)
+	}
+	return instance, nil
+}
+
+// connect fetches the target through the server of c with clientCore.
+func (r *runner) connect(ctx context.Context, clientCore string, c Case, link string) ClientResult {
+	res := ClientResult{Core: clientCore, Status: StatusUnsupported}
+	if !c.runsOn(clientCore) {
+		res.Err = fmt.Errorf("no %s client in %s", c, clientCore)
+		return res
+	}
+
+	res.Status = StatusFailed
+	p, err := parseWith(clientCore, link, r.opts.Verbose)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	// xray checks the certificate pin of the link. sing-box can't check
+	// one and has to skip verification of the self-signed certificate.
+	insecure := clientCore == core.SingboxCoreName
+	client, instance, err := core.CoreFactory(coreType(clientCore), insecure, r.opts.Verbose).MakeHttpClient(ctx, p, r.opts.Timeout)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer instance.Close()
+
+	reqCtx, cancel := context.WithTimeout(ctx, r.opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, r.target.url, nil)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	if string(body) != r.target.token {
+		res.Err = fmt.Errorf("unexpected response %q", body)
+		return res
+	}
+	res.Status = StatusPassed
+	res.Delay = time.Since(start)
+	return res
+}
+
+// link returns the share link of the case's server on port. TLS links pin
+// the certificate certPin.
+func (c Case) link(port int, certPin string) (string, error) {
+	host := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+	// Hex keeps the password out of the way of the link syntax
+	password, err := gen.RandomHex(16)
+	if err != nil {
+		return "", err
+	}
+	security := "none"
+	if c.TLS {
+		security = "tls"
+	}
+
+	q := url.Values{}
+	q.Set("type", c.Transport)
+	q.Set("security", security)
+	if c.TLS {
+		q.Set("sni", "localhost")
+		q.Set(protocol.PinnedCertParam, certPin)
+	}
+	switch c.Transport {
+	case "ws", "httpupgrade", "xhttp":
+		q.Set("path", "/selftest")
+		q.Set("host", "localhost")
+	case "grpc":
+		q.Set("serviceName", "selftest")
+	}
+
+	switch c.Protocol {
+	case protocol.VlessIdentifier:
+		q.Set("encryption", "none")
+		return "vless://" + uuid.NewString() + "@" + host + "?" + q.Encode() + "#selftest", nil
+	case protocol.TrojanIdentifier:
+		return "trojan://" + password + "@" + host + "?" + q.Encode() + "#selftest", nil
+	case protocol.VmessIdentifier:
+		path := "/selftest"
+		if c.Transport == "grpc" {
+			path = "selftest"
+		}
+		v := map[string]string{
+			"v": "2", "ps": "selftest", "add": "127.0.0.1", "port": strconv.Itoa(port),
+			"id": uuid.NewString(), "aid": "0", "scy": "auto", "net": c.Transport, "type": "none",
+			"host": "localhost", "path": path, "tls": "", "sni": "",
+		}
+		if c.Transport == "tcp" {
+			v["host"], v["path"] = "", ""
+		}
+		if c.TLS {
+			v["tls"], v["sni"], v[protocol.PinnedCertParam] = "tls", "localhost", certPin
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return "vmess://" + base64.StdEncoding.EncodeToString(b), nil
+	case protocol.ShadowsocksIdentifier:
+		if _, ok := gen.SS2022Methods[c.Transport]; ok {
+			if password, err = gen.RandomKey(gen.SS2022Methods[c.Transport]); err != nil {
+				return "", err
+			}
+		}
+		creds := base64.URLEncoding.EncodeToString([]byte(c.Transport + ":" + password))
+		return "ss://" + creds + "@" + host + "#selftest", nil
+	case protocol.SocksIdentifier:
+		creds := base64.URLEncoding.EncodeToString([]byte("selftest:" + password))
+		return "socks://" + creds + "@" + host + "#selftest", nil
+	case protocol.Hysteria2Identifier:
+		return "hysteria2://" + password + "@" + host + "?sni=localhost&insecure=1#selftest", nil
+	}
+	return "", fmt.Errorf("unknown protocol %q", c.Protocol)
+}
+
+// setCert makes the TLS inbound p serve the certificate.
+func setCert(p protocol.Protocol, certFile, keyFile string) {
+	switch in := p.(type) {
+	case *xray.Vless:
+		in.CertFile, in.KeyFile = certFile, keyFile
+	case *xray.Vmess:
+		in.CertFile, in.KeyFile = certFile, keyFile
+	case *xray.Trojan:
+		in.CertFile, in.KeyFile = certFile, keyFile
+	case *singbox.Vless:
+		in.CertFile, in.KeyFile = certFile, keyFile
+	case *singbox.Vmess:
+		in.CertFile, in.KeyFile = certFile, keyFile
+	case *singbox.Trojan:
+		in.CertFile, in.KeyFile = certFile, keyFile
+	case *singbox.Hysteria2:
+		in.CertFile, in.KeyFile = certFile, keyFile
+	}
+}
+
+func parseWith(coreName, link string, verbose bool) (protocol.Protocol, error) {
+	p, err := core.CoreFactory(coreType(coreName), true, verbose).CreateProtocol(link)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Parse(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func coreType(coreName string) core.CoreType {
+	if coreName == core.SingboxCoreName {
+		return core.SingboxCoreType
+	}
+	return core.XrayCoreType
+}
+
+// freePort returns a port free on 127.0.0.1, for UDP or TCP.
+func freePort(udp bool) (int, error) {
+	if udp {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+		return conn.LocalAddr().(*net.UDPAddr).Port, nil
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+// target is the local HTTP server the clients fetch through the servers.
+type target struct {
+	url    string
+	token  string
+	server *http.Server
+}
+
+func newTarget() (*target, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start HTTP target: %w", err)
+	}
+	token, err := gen.RandomHex(16)
+	if err != nil {
+		ln.Close()
+		return nil, err
+	}
+	t := &target{
+		url:   "http://" + ln.Addr().String() + "/selftest",
+		token: token,
+	}
+	t.server = &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = io.WriteString(w, t.token)
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go t.server.Serve(ln)
+	return t, nil
+}
+
+func (t *target) Close() error {
+	return t.server.Close()
+}
@@ -0,0 +1,54 @@
+package selftest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lilendian0x00/xray-knife/v10/pkg/core/protocol"
+)
+
+func TestCaseLinksParseOnServerCore(t *testing.T) {
+	for _, c := range Cases() {
+		link, err := c.link(443, protocol.CertSHA256([]byte("selftest")))
+		if err != nil {
+			t.Fatalf("%s (%s): %v", c, c.Core, err)
+		}
+		if _, err := parseWith(c.Core, link, false); err != nil {
+			t.Errorf("%s (%s): link %s does not parse: %v", c, c.Core, link, err)
+		}
+	}
+}
+
+func TestLoopback(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts a local server per protocol")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	results, err := Run(ctx, Cases(), Options{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range results {
+		name := res.Case.String() + " on " + res.Case.Core
+		if res.ServerErr != nil {
+			t.Errorf("%s: %v", name, res.ServerErr)
+			continue
+		}
+		supported := false
+		for _, c := range res.Clients {
+			switch c.Status {
+			case StatusPassed:
+				supported = true
+			case StatusFailed:
+				supported = true
+				t.Errorf("%s: %s client failed: %v", name, c.Core, c.Err)
+			}
+		}
+		if !supported {
+			t.Errorf("%s: no client core supports it", name)
+		}
+	}
+}
@@ -97,3 +97,11 @@ func GeneratePassword(length int) (string, error) {
 
 	return string(password), nil
 }
+
+// Truncate shortens s to n bytes, ending it with "..." when cut.
+func Truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n-3] + "..."
+}
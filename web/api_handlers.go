@@ -64,6 +64,7 @@ func (h *APIHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/http/test/stop", h.handleHttpTestStop)
 	mux.HandleFunc("/api/v1/http/test/history", h.handleHttpTestHistory)
 	mux.HandleFunc("/api/v1/http/test/clear_history", h.handleHttpTestClearHistory)
+	mux.HandleFunc("/api/v1/http/profiles", h.handleHttpProfiles)
 	mux.HandleFunc("/api/v1/scanner/cf/start", h.handleCfScannerStart)
 	mux.HandleFunc("/api/v1/scanner/cf/stop", h.handleCfScannerStop)
 	mux.HandleFunc("/api/v1/scanner/cf/status", h.handleCfScannerStatus)
@@ -84,6 +85,12 @@ func (h *APIHandler) handleProxyStart(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
 		return
 	}
+	if cfg.TestProfile != nil {
+		if _, err := pkghttp.ResolveProfile(cfg.TestProfile); err != nil {
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 	if err := h.manager.StartProxy(cfg); err != nil {
 		writeJSONError(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -157,6 +164,16 @@ func (h *APIHandler) handleHttpTest(w http.ResponseWriter, r *http.Request) {
 		writeJSONError(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
 		return
 	}
+	if requestBody.Profile != nil {
+		if _, err := pkghttp.ResolveProfile(requestBody.Profile); err != nil {
+			writeJSONError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if _, err := pkghttp.NewSpeedTester(requestBody.SpeedtestBackend); err != nil {
+		writeJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	req := pkghttp.HttpTestRequest{
 		Links:       requestBody.Links,
@@ -219,6 +236,16 @@ func (h *APIHandler) handleHttpTestClearHistory(w http.ResponseWriter, r *http.R
 	writeJSONResponse(w, http.StatusOK, map[string]string{"status": "History cleared"})
 }
 
+// handleHttpProfiles lists the built-in test profiles. A test request
+// selects one with {"profile": {"name": "..."}} or sends its own targets.
+func (h *APIHandler) handleHttpProfiles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+	writeJSONResponse(w, http.StatusOK, pkghttp.BuiltinProfiles())
+}
+
 // --- CF Scanner Handlers ---
 
 func (h *APIHandler) handleCfScannerStart(w http.ResponseWriter, r *http.Request) {
@@ -293,31 +320,6 @@ var cloudflareRangesLink = []string{
 	"https://www.cloudflare.com/ips-v6",
 }
 
-var cloudflareRanges = []string{
-	// IPv4 Fallback
-	"173.245.48.0/20",
-	"103.21.244.0/22",
-	"103.22.200.0/22",
-	"103.31.4.0/22",
-	"141.101.64.0/18",
-	"108.162.192.0/18",
-	"190.93.240.0/20",
-	"188.114.96.0/20",
-	"197.234.240.0/22",
-	"198.41.128.0/17",
-	"162.158.0.0/15",
-	"104.16.0.0/13",
-	"104.24.0.0/14",
-	"172.64.0.0/13",
-	"131.0.72.0/22",
-	// IPv6 Fallback
-	"2606:4700::/32",
-	"2803:f800::/32",
-	"2400:cb00::/32",
-	"2c0f:f248::/32",
-	"2a06:98c0::/29",
-}
-
 // cfRangesCache caches fetched Cloudflare IP ranges to avoid blocking API requests.
 var cfRangesCache struct {
 	mu        sync.Mutex
@@ -386,7 +388,7 @@ func fetchCloudflareRanges(logger *log.Logger) []string {
 
 	if firstError != nil {
 		logger.Printf("Failed to fetch live Cloudflare IP ranges, using fallback list. Error: %v", firstError)
-		return cloudflareRanges
+		return scanner.CloudflareRanges
 	}
 
 	// Update cache
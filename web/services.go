@@ -376,6 +376,8 @@ func (ht *HttpTestRunner) consumeResults(ctx context.Context, resultsChan <-chan
 					ConfigLink: res.ConfigLink,
 					Status:     res.Status,
 					Reason:     sql.NullString{String: res.Reason, Valid: res.Reason != ""},
+					Core:       sql.NullString{String: res.Core, Valid: res.Core != ""},
+					UDP:        sql.NullString{String: res.UDP, Valid: res.UDP != ""},
 					DelayMs:    -1,
 				}
 				if res.Status == "passed" || res.Status == "semi-passed" {
@@ -386,6 +388,12 @@ func (ht *HttpTestRunner) consumeResults(ctx context.Context, resultsChan <-chan
 					dbRes.IPLocation = sql.NullString{String: res.IpAddrLoc, Valid: res.IpAddrLoc != "" && res.IpAddrLoc != "null"}
 					dbRes.TTFBMs = res.TTFB
 					dbRes.ConnectTimeMs = res.ConnectTime
+					dbRes.Samples = res.Samples
+					dbRes.DelayMinMs = res.DelayMin
+					dbRes.DelayP50Ms = res.DelayP50
+					dbRes.DelayP95Ms = res.DelayP95
+					dbRes.JitterMs = res.Jitter
+					dbRes.LossPct = float64(res.Loss)
 				}
 				dbResults = append(dbResults, dbRes)
 			}